@@ -113,20 +113,55 @@ func sendRequest(ctx context.Context, ac *apiClient, path string, method string,
 }
 
 func downloadFile(ctx context.Context, ac *apiClient, path string, httpOptions *HTTPOptions) ([]byte, error) {
-	// The client and request timeout are not used for downloadFile.
-	// TODO(b/427540996): implement timeout.
-	req, _, err := buildRequest(ctx, ac, path, nil, http.MethodGet, httpOptions)
-	if err != nil {
+	var buf bytes.Buffer
+	if _, err := downloadFileTo(ctx, ac, path, httpOptions, &buf, 0, nil); err != nil {
 		return nil, err
 	}
+	return buf.Bytes(), nil
+}
+
+// downloadFileTo streams the file at path to w, starting at offset, and
+// returns the number of bytes written. A non-zero offset is requested with an
+// HTTP Range; if the server ignores it, the first offset bytes are discarded.
+// onProgress, if not nil, is called as the data is received.
+func downloadFileTo(ctx context.Context, ac *apiClient, path string, httpOptions *HTTPOptions, w io.Writer, offset int64, onProgress func(TransferProgress)) (int64, error) {
+	req, httpOptions, err := buildRequest(ctx, ac, path, nil, http.MethodGet, httpOptions)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	// The timeout bounds the whole download, including reading the body.
+	if timeout := httpOptions.Timeout; timeout != nil && *timeout > 0 && isTimeoutBeforeDeadline(ctx, *timeout) {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
 	req = req.WithContext(ctx)
 
 	resp, err := doRequest(ac, req)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if !httpStatusOk(resp) {
+		return 0, newAPIError(resp)
 	}
 
-	return io.ReadAll(resp.Body)
+	total := resp.ContentLength
+	if offset > 0 {
+		if resp.StatusCode == http.StatusPartialContent {
+			if total >= 0 {
+				total += offset
+			}
+		} else if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			// The server ignored the range and sent the whole file.
+			return 0, fmt.Errorf("failed to skip the first %d bytes of the download: %w", offset, err)
+		}
+	}
+	return io.Copy(w, &progressReader{r: resp.Body, onProgress: onProgress, bytes: offset, total: total})
 }
 
 // InternalMapToStruct is an internal function used for converting a map[string]any to a struct.
@@ -626,13 +661,43 @@ func scan(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	return 0, nil, nil
 }
 
-func (ac *apiClient) upload(ctx context.Context, r io.Reader, uploadURL string, httpOptions *HTTPOptions) (map[string]any, error) {
-	var offset int64 = 0
+func (ac *apiClient) upload(ctx context.Context, r io.Reader, uploadURL string, httpOptions *HTTPOptions, options *uploadOptions) (map[string]any, error) {
+	return ac.uploadFrom(ctx, r, uploadURL, 0, uploadSize(r, httpOptions), httpOptions, options)
+}
+
+// uploadFrom uploads the data of r to the upload session at uploadURL, starting
+// at offset. total is the size of the whole upload, or -1 if it is unknown.
+// Errors are *UploadError with the session to resume the upload from.
+//
+// If options is nil, the default chunk size is used and the upload is only
+// bounded by ctx and the timeout of httpOptions.
+func (ac *apiClient) uploadFrom(ctx context.Context, r io.Reader, uploadURL string, offset, total int64, httpOptions *HTTPOptions, options *uploadOptions) (map[string]any, error) {
 	var resp *http.Response
 	var respBody map[string]any
 	var uploadCommand = "upload"
+	uploadError := func(err error) error {
+		return &UploadError{Session: UploadSession{URL: uploadURL, Offset: offset}, Err: err}
+	}
 
-	buffer := make([]byte, maxChunkSize)
+	if options == nil {
+		var err error
+		if options, err = newUploadOptions(0, 0, 0, nil); err != nil {
+			return nil, uploadError(err)
+		}
+	}
+	if options.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.timeout)
+		defer cancel()
+	}
+	chunkTimeout := options.chunkTimeout
+	if chunkTimeout == 0 {
+		if patched, err := patchHTTPOptions(ac.clientConfig.HTTPOptions, *httpOptions); err == nil && patched.Timeout != nil {
+			chunkTimeout = *patched.Timeout
+		}
+	}
+
+	buffer := make([]byte, options.chunkSize)
 	for {
 		bytesRead, err := io.ReadFull(r, buffer)
 		// Check both EOF and UnexpectedEOF errors.
@@ -641,121 +706,172 @@ func (ac *apiClient) upload(ctx context.Context, r io.Reader, uploadURL string,
 		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			uploadCommand += ", finalize"
 		} else if err != nil {
-			return nil, fmt.Errorf("Failed to read bytes from file at offset %d: %w. Bytes actually read: %d", offset, err, bytesRead)
+			return nil, uploadError(fmt.Errorf("Failed to read bytes from file at offset %d: %w. Bytes actually read: %d", offset, err, bytesRead))
 		}
+		// cancelChunk releases the timeout of the last chunk request once its
+		// response was read.
+		cancelChunk := func() {}
 		for attempt := 0; attempt < maxRetryCount; attempt++ {
-			patchedHTTPOptions, err := patchHTTPOptions(ac.clientConfig.HTTPOptions, *httpOptions)
-			if err != nil {
-				return nil, err
+			chunkCtx := ctx
+			if chunkTimeout > 0 {
+				chunkCtx, cancelChunk = context.WithTimeout(ctx, chunkTimeout)
 			}
-
-			finalUploadURL := uploadURL
-			if patchedHTTPOptions.BaseURL != "" {
-				parsedBase, errBase := url.Parse(patchedHTTPOptions.BaseURL)
-				parsedUpload, errUpload := url.Parse(uploadURL)
-				if errBase == nil && errUpload == nil {
-					parsedUpload.Scheme = parsedBase.Scheme
-					parsedUpload.Host = parsedBase.Host
-					finalUploadURL = parsedUpload.String()
-				}
-			}
-
-			// TODO(b/427540996): Support timeout.
-			req, err := http.NewRequestWithContext(ctx, http.MethodPost, finalUploadURL, bytes.NewReader(buffer[:bytesRead]))
+			req, err := ac.newUploadRequest(chunkCtx, uploadURL, uploadCommand, httpOptions, bytes.NewReader(buffer[:bytesRead]))
 			if err != nil {
-				return nil, fmt.Errorf("Failed to create upload request for chunk at offset %d: %w", offset, err)
+				cancelChunk()
+				return nil, uploadError(fmt.Errorf("Failed to create upload request for chunk at offset %d: %w", offset, err))
 			}
 
-			req.Header = patchedHTTPOptions.Headers
-			req.Header.Set("Content-Type", "application/json")
-			if ac.clientConfig.APIKey != "" {
-				req.Header.Set("x-goog-api-key", ac.clientConfig.APIKey)
-			}
-			// TODO(b/427540996): Add timeout logging.
-
-			req.Header.Set("X-Goog-Upload-Command", uploadCommand)
 			req.Header.Set("X-Goog-Upload-Offset", strconv.FormatInt(offset, 10))
 			req.Header.Set("Content-Length", strconv.FormatInt(int64(bytesRead), 10))
 			resp, err = doRequest(ac, req)
 			if err != nil {
-				return nil, fmt.Errorf("upload request failed for chunk at offset %d: %w", offset, err)
+				cancelChunk()
+				if chunkCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+					return nil, uploadError(fmt.Errorf("upload request for chunk at offset %d timed out after %v: %w", offset, chunkTimeout, err))
+				}
+				return nil, uploadError(fmt.Errorf("upload request failed for chunk at offset %d: %w", offset, err))
 			}
-			if resp.Header.Get("X-Goog-Upload-Status") != "" {
+			if resp.Header.Get("X-Goog-Upload-Status") != "" || attempt == maxRetryCount-1 {
 				break
 			}
 			resp.Body.Close()
+			cancelChunk()
 
 			select {
 			case <-ctx.Done():
-				return nil, fmt.Errorf("upload aborted while waiting to retry (attempt %d, offset %d): %w", attempt+1, offset, ctx.Err())
-			case <-time.After(initialRetryDelay * time.Duration(delayMultiplier^attempt)):
+				return nil, uploadError(fmt.Errorf("upload aborted while waiting to retry (attempt %d, offset %d): %w", attempt+1, offset, ctx.Err()))
+			case <-time.After(retryDelay(attempt)):
 				// Sleep completed, continue to the next attempt.
 			}
 		}
-		defer resp.Body.Close()
 
 		respBody, err = deserializeUnaryResponse(resp)
+		resp.Body.Close()
+		cancelChunk()
 		if err != nil {
-			return nil, fmt.Errorf("response body is invalid for chunk at offset %d: %w", offset, err)
+			return nil, uploadError(fmt.Errorf("response body is invalid for chunk at offset %d: %w", offset, err))
 		}
 
-		offset += int64(bytesRead)
-
 		uploadStatus := resp.Header.Get("X-Goog-Upload-Status")
+		if uploadStatus == "active" || uploadStatus == "final" {
+			offset += int64(bytesRead)
+			options.reportProgress(offset, total)
+		}
 
 		if uploadStatus != "final" && strings.Contains(uploadCommand, "finalize") {
-			return nil, fmt.Errorf("send finalize command but doesn't receive final status. Offset %d, Bytes read: %d, Upload status: %s", offset, bytesRead, uploadStatus)
+			return nil, uploadError(fmt.Errorf("send finalize command but doesn't receive final status. Offset %d, Bytes read: %d, Upload status: %s", offset, bytesRead, uploadStatus))
 		}
 		if uploadStatus != "active" {
 			// Upload is complete ('final') or interrupted ('cancelled', etc.)
 			break
 		}
+		if options.onCommit != nil {
+			options.onCommit(offset)
+		}
 	}
 
 	if resp == nil {
-		return nil, fmt.Errorf("Upload request failed. No response received")
+		return nil, uploadError(fmt.Errorf("Upload request failed. No response received"))
 	}
 
 	finalUploadStatus := resp.Header.Get("X-Goog-Upload-Status")
 	if finalUploadStatus != "final" {
-		return nil, fmt.Errorf("Failed to upload file: Upload status is not finalized")
+		return nil, uploadError(fmt.Errorf("Failed to upload file: Upload status is not finalized"))
 	}
 
 	return respBody, nil
 }
 
-func (ac *apiClient) uploadFile(ctx context.Context, r io.Reader, uploadURL string, httpOptions *HTTPOptions) (*File, error) {
-	respBody, err := ac.upload(ctx, r, uploadURL, httpOptions)
+// queryUpload asks the server for the state of the upload session at
+// uploadURL. It returns the upload status, the number of bytes committed by the
+// server, and the response body, which holds the uploaded file once the status
+// is "final".
+func (ac *apiClient) queryUpload(ctx context.Context, uploadURL string, httpOptions *HTTPOptions) (string, int64, map[string]any, error) {
+	req, err := ac.newUploadRequest(ctx, uploadURL, "query", httpOptions, nil)
 	if err != nil {
-		return nil, err // Propagate any errors from the upload process
+		return "", 0, nil, fmt.Errorf("failed to create upload query request: %w", err)
+	}
+	resp, err := doRequest(ac, req)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("upload query request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := deserializeUnaryResponse(resp)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	status := resp.Header.Get("X-Goog-Upload-Status")
+	if status == "" {
+		return "", 0, nil, fmt.Errorf("upload query response has no upload status")
+	}
+	var offset int64
+	if received := resp.Header.Get("X-Goog-Upload-Size-Received"); received != "" {
+		offset, err = strconv.ParseInt(received, 10, 64)
+		if err != nil || offset < 0 {
+			return "", 0, nil, fmt.Errorf("upload query response has an invalid size received: %q", received)
+		}
+	}
+	return status, offset, respBody, nil
+}
+
+// newUploadRequest creates a request of the resumable upload protocol with the
+// given command. When httpOptions sets a base URL, it replaces the scheme and
+// host of uploadURL.
+func (ac *apiClient) newUploadRequest(ctx context.Context, uploadURL, command string, httpOptions *HTTPOptions, body io.Reader) (*http.Request, error) {
+	patchedHTTPOptions, err := patchHTTPOptions(ac.clientConfig.HTTPOptions, *httpOptions)
+	if err != nil {
+		return nil, err
 	}
-	if respBody == nil {
-		return nil, fmt.Errorf("upload completed but response body was empty")
+
+	finalUploadURL := uploadURL
+	if patchedHTTPOptions.BaseURL != "" {
+		parsedBase, errBase := url.Parse(patchedHTTPOptions.BaseURL)
+		parsedUpload, errUpload := url.Parse(uploadURL)
+		if errBase == nil && errUpload == nil {
+			parsedUpload.Scheme = parsedBase.Scheme
+			parsedUpload.Host = parsedBase.Host
+			finalUploadURL = parsedUpload.String()
+		}
 	}
 
-	var response = new(File)
-	err = mapToStruct(respBody["file"].(map[string]any), &response)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, finalUploadURL, body)
 	if err != nil {
 		return nil, err
 	}
-	return response, nil
+	req.Header = patchedHTTPOptions.Headers
+	req.Header.Set("Content-Type", "application/json")
+	if ac.clientConfig.APIKey != "" {
+		req.Header.Set("x-goog-api-key", ac.clientConfig.APIKey)
+	}
+	req.Header.Set("X-Goog-Upload-Command", command)
+	return req, nil
 }
 
-func (ac *apiClient) uploadToFileSearchStore(ctx context.Context, r io.Reader, uploadURL string, httpOptions *HTTPOptions) (*UploadToFileSearchStoreOperation, error) {
-	respBody, err := ac.upload(ctx, r, uploadURL, httpOptions)
+// retryDelay returns the delay before retrying after the given attempt,
+// counted from 0. The delay grows exponentially from initialRetryDelay.
+func retryDelay(attempt int) time.Duration {
+	delay := initialRetryDelay
+	for range attempt {
+		delay *= delayMultiplier
+	}
+	return delay
+}
+
+func (ac *apiClient) uploadFile(ctx context.Context, r io.Reader, uploadURL string, httpOptions *HTTPOptions) (*File, error) {
+	respBody, err := ac.upload(ctx, r, uploadURL, httpOptions, nil)
 	if err != nil {
 		return nil, err // Propagate any errors from the upload process
 	}
-	if respBody == nil {
-		return nil, fmt.Errorf("upload completed but response body was empty")
-	}
+	return uploadedFile(respBody)
+}
 
-	var response = new(UploadToFileSearchStoreOperation)
-	err = mapToStruct(respBody, &response)
+func (ac *apiClient) uploadToFileSearchStore(ctx context.Context, r io.Reader, uploadURL string, httpOptions *HTTPOptions) (*UploadToFileSearchStoreOperation, error) {
+	respBody, err := ac.upload(ctx, r, uploadURL, httpOptions, nil)
 	if err != nil {
-		return nil, err
+		return nil, err // Propagate any errors from the upload process
 	}
-	return response, nil
+	return uploadedOperation(respBody)
 }
 
 func (ac *apiClient) ClientConfig() ClientConfig {
@@ -0,0 +1,360 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audio provides helpers to stream PCM audio into Live sessions and
+// to play back the audio generated by the model.
+//
+// All audio handled by this package is 16-bit signed little-endian PCM, with
+// the samples of multiple channels interleaved.
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"google.golang.org/genai"
+	"google.golang.org/genai/internal/media"
+)
+
+const (
+	// InputSampleRate is the sample rate of the audio sent by [Stream].
+	InputSampleRate = 16000
+	// OutputSampleRate is the sample rate of the audio generated by Live models.
+	OutputSampleRate = 24000
+
+	defaultChunkDuration = 100 * time.Millisecond
+)
+
+// Format describes 16-bit signed little-endian PCM audio.
+type Format struct {
+	// SampleRate is the number of samples per second for each channel.
+	SampleRate int
+	// Channels is the number of interleaved channels.
+	Channels int
+}
+
+func (f Format) validate() error {
+	if f.SampleRate <= 0 {
+		return fmt.Errorf("audio sample rate must be positive, got %d", f.SampleRate)
+	}
+	if f.Channels <= 0 {
+		return fmt.Errorf("audio channel count must be positive, got %d", f.Channels)
+	}
+	return nil
+}
+
+// frameSize returns the size in bytes of one sample for all channels.
+func (f Format) frameSize() int {
+	return 2 * f.Channels
+}
+
+// duration returns the duration of n bytes of audio.
+func (f Format) duration(n int) time.Duration {
+	return time.Duration(n/f.frameSize()) * time.Second / time.Duration(f.SampleRate)
+}
+
+// MIMEType returns the MIME type of mono audio with the format's sample rate,
+// for example "audio/pcm;rate=16000".
+func (f Format) MIMEType() string {
+	return fmt.Sprintf("audio/pcm;rate=%d", f.SampleRate)
+}
+
+// RealtimeSender sends realtime input in a Live session. It is implemented by
+// [genai.Session] and [genai.ResumableSession].
+type RealtimeSender interface {
+	SendRealtimeInput(input genai.LiveRealtimeInput) error
+}
+
+// StreamConfig configures [Stream].
+type StreamConfig struct {
+	// Required. Format of the audio read from the reader.
+	Format Format
+	// Optional. ChunkDuration is the duration of the audio sent in each
+	// message. If zero, 100 milliseconds is used.
+	ChunkDuration time.Duration
+	// Optional. DisablePacing sends the audio as fast as it can be read instead
+	// of at real-time pace. Use it for readers that already produce audio in
+	// real time, such as a microphone.
+	DisablePacing bool
+	// Optional. SendAudioStreamEnd sends [genai.LiveRealtimeInput.AudioStreamEnd]
+	// when the reader is exhausted.
+	SendAudioStreamEnd bool
+	// Optional. VAD enables client-side voice activity detection. If set, only
+	// the audio of detected activity is sent, surrounded by
+	// [genai.ActivityStart] and [genai.ActivityEnd] messages.
+	VAD *VADConfig
+}
+
+// Stream reads audio from r, converts it to 16kHz mono and sends it to sender
+// in chunks of [StreamConfig.ChunkDuration], until r returns [io.EOF] or ctx
+// is done. Unless pacing is disabled, chunks are sent no faster than real
+// time so that the session receives the audio as if it was recorded live.
+//
+// Stream returns nil when r is exhausted.
+func Stream(ctx context.Context, sender RealtimeSender, r io.Reader, config *StreamConfig) error {
+	if config == nil {
+		return fmt.Errorf("audio stream config is required")
+	}
+	if err := config.Format.validate(); err != nil {
+		return err
+	}
+	chunkDuration := config.ChunkDuration
+	if chunkDuration <= 0 {
+		chunkDuration = defaultChunkDuration
+	}
+	frames := int(int64(config.Format.SampleRate) * int64(chunkDuration) / int64(time.Second))
+	if frames <= 0 {
+		frames = 1
+	}
+	chunk := make([]byte, frames*config.Format.frameSize())
+	output := Format{SampleRate: InputSampleRate, Channels: 1}
+	converter := newConverter(config.Format, output.SampleRate)
+	var detector *vad
+	if config.VAD != nil {
+		var err error
+		detector, err = newVAD(config.VAD, output.SampleRate)
+		if err != nil {
+			return err
+		}
+	}
+	send := func(events []vadEvent) error {
+		for _, event := range events {
+			var input genai.LiveRealtimeInput
+			switch event.kind {
+			case vadActivityStart:
+				input.ActivityStart = &genai.ActivityStart{}
+			case vadActivityEnd:
+				input.ActivityEnd = &genai.ActivityEnd{}
+			default:
+				input.Audio = &genai.Blob{MIMEType: output.MIMEType(), Data: encodeSamples(event.samples)}
+			}
+			if err := sender.SendRealtimeInput(input); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := time.Now()
+	// elapsed is the duration of the audio read so far.
+	var elapsed time.Duration
+	for {
+		n, err := io.ReadFull(r, chunk)
+		// Drop a trailing partial frame.
+		n -= n % config.Format.frameSize()
+		if n > 0 {
+			samples := converter.convert(chunk[:n])
+			if !config.DisablePacing {
+				if err := media.SleepUntil(ctx, start.Add(elapsed)); err != nil {
+					return err
+				}
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			events := []vadEvent{{kind: vadAudio, samples: samples}}
+			if detector != nil {
+				events = detector.process(samples)
+			}
+			if err := send(events); err != nil {
+				return err
+			}
+			elapsed += time.Duration(len(samples)) * time.Second / time.Duration(output.SampleRate)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if detector != nil {
+		if err := send(detector.flush()); err != nil {
+			return err
+		}
+	}
+	if config.SendAudioStreamEnd {
+		return sender.SendRealtimeInput(genai.LiveRealtimeInput{AudioStreamEnd: true})
+	}
+	return nil
+}
+
+// converter downmixes PCM to mono and resamples it. It keeps state across
+// calls so that consecutive chunks are converted seamlessly.
+type converter struct {
+	channels  int
+	resampler *resampler
+}
+
+func newConverter(input Format, outputRate int) *converter {
+	return &converter{
+		channels:  input.Channels,
+		resampler: newResampler(input.SampleRate, outputRate),
+	}
+}
+
+// convert converts whole frames of PCM into mono samples at the output rate.
+func (c *converter) convert(pcm []byte) []float64 {
+	return c.resampler.process(downmix(decodeSamples(pcm), c.channels))
+}
+
+// decodeSamples decodes 16-bit little-endian samples into the range [-1, 1).
+func decodeSamples(pcm []byte) []float64 {
+	samples := make([]float64, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(pcm[2*i:]))) / 32768
+	}
+	return samples
+}
+
+// encodeSamples encodes samples in the range [-1, 1) into 16-bit
+// little-endian PCM, rounding them and clipping values out of range.
+func encodeSamples(samples []float64) []byte {
+	pcm := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		v := math.Round(s * 32768)
+		if v > 32767 {
+			v = 32767
+		} else if v < -32768 {
+			v = -32768
+		}
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(int16(v)))
+	}
+	return pcm
+}
+
+// downmix averages interleaved channels into a single channel.
+func downmix(samples []float64, channels int) []float64 {
+	if channels == 1 {
+		return samples
+	}
+	mono := make([]float64, len(samples)/channels)
+	for i := range mono {
+		var sum float64
+		for _, s := range samples[i*channels : (i+1)*channels] {
+			sum += s
+		}
+		mono[i] = sum / float64(channels)
+	}
+	return mono
+}
+
+// resampler converts mono audio between sample rates with linear
+// interpolation. When downsampling, the audio is first low-pass filtered below
+// the output Nyquist frequency, so that higher frequencies don't alias.
+type resampler struct {
+	// step is the distance between output samples, in input samples.
+	step float64
+	// pos is the position of the next output sample, relative to last.
+	pos float64
+	// last is the last input sample of the previous call.
+	last   float64
+	primed bool
+	// lowpass is the anti-aliasing filter, or nil when upsampling.
+	lowpass *lowpass
+}
+
+func newResampler(inputRate, outputRate int) *resampler {
+	r := &resampler{step: float64(inputRate) / float64(outputRate)}
+	if r.step > 1 {
+		// The cutoff leaves room for the transition band of the filter below
+		// the output Nyquist frequency, which is 0.5/step cycles per sample.
+		r.lowpass = newLowpass(0.45/r.step, int(math.Ceil(10*r.step)))
+	}
+	return r
+}
+
+func (r *resampler) process(in []float64) []float64 {
+	if r.step == 1 || len(in) == 0 {
+		return in
+	}
+	if r.lowpass != nil {
+		in = r.lowpass.process(in)
+	}
+	v := in
+	if r.primed {
+		v = append([]float64{r.last}, in...)
+	}
+	var out []float64
+	for r.pos < float64(len(v)-1) {
+		i := int(r.pos)
+		frac := r.pos - float64(i)
+		out = append(out, v[i]*(1-frac)+v[i+1]*frac)
+		r.pos += r.step
+	}
+	r.pos -= float64(len(v) - 1)
+	r.last = v[len(v)-1]
+	r.primed = true
+	return out
+}
+
+// lowpass is a windowed-sinc low-pass FIR filter. It keeps the last input
+// samples across calls so that consecutive chunks are filtered seamlessly.
+type lowpass struct {
+	taps []float64
+	// history holds the last len(taps)-1 input samples.
+	history []float64
+}
+
+// newLowpass returns a filter with the given cutoff frequency, in cycles per
+// sample, and 2*halfWidth+1 taps.
+func newLowpass(cutoff float64, halfWidth int) *lowpass {
+	n := 2*halfWidth + 1
+	taps := make([]float64, n)
+	var sum float64
+	for i := range taps {
+		x := float64(i - halfWidth)
+		sinc := 1.0
+		if x != 0 {
+			sinc = math.Sin(2*math.Pi*cutoff*x) / (2 * math.Pi * cutoff * x)
+		}
+		// Blackman window.
+		w := 0.42 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1)) + 0.08*math.Cos(4*math.Pi*float64(i)/float64(n-1))
+		taps[i] = sinc * w
+		sum += taps[i]
+	}
+	for i := range taps {
+		taps[i] /= sum
+	}
+	return &lowpass{taps: taps}
+}
+
+// process returns the filtered samples of in, delayed by half the length of
+// the filter.
+func (f *lowpass) process(in []float64) []float64 {
+	if f.history == nil {
+		// The first sample is repeated before the audio to avoid a transient.
+		f.history = make([]float64, len(f.taps)-1)
+		for i := range f.history {
+			f.history[i] = in[0]
+		}
+	}
+	v := make([]float64, 0, len(f.history)+len(in))
+	v = append(append(v, f.history...), in...)
+	out := make([]float64, len(in))
+	for i := range out {
+		var sum float64
+		for j, tap := range f.taps {
+			sum += tap * v[i+j]
+		}
+		out[i] = sum
+	}
+	copy(f.history, v[len(in):])
+	return out
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+)
+
+type fakeSender struct {
+	mu     sync.Mutex
+	inputs []genai.LiveRealtimeInput
+	times  []time.Time
+}
+
+func (s *fakeSender) SendRealtimeInput(input genai.LiveRealtimeInput) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inputs = append(s.inputs, input)
+	s.times = append(s.times, time.Now())
+	return nil
+}
+
+// pcm encodes samples as 16-bit little-endian PCM.
+func pcm(samples ...int16) []byte {
+	b := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(s))
+	}
+	return b
+}
+
+// constant returns n frames of PCM where every sample has value v.
+func constant(v int16, n int) []byte {
+	samples := make([]int16, n)
+	for i := range samples {
+		samples[i] = v
+	}
+	return pcm(samples...)
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		desc   string
+		format Format
+		input  []byte
+		// wantChunks is the number of samples in each sent chunk.
+		wantChunks []int
+		wantValue  int16
+	}{
+		{
+			desc:       "16kHz mono",
+			format:     Format{SampleRate: 16000, Channels: 1},
+			input:      constant(1000, 4000),
+			wantChunks: []int{1600, 1600, 800},
+			wantValue:  1000,
+		},
+		{
+			desc:   "48kHz stereo",
+			format: Format{SampleRate: 48000, Channels: 2},
+			// Left and right channels are averaged.
+			input:      bytes.Repeat(pcm(1000, 3000), 9600),
+			wantChunks: []int{1600, 1600},
+			wantValue:  2000,
+		},
+		{
+			desc:   "8kHz mono",
+			format: Format{SampleRate: 8000, Channels: 1},
+			input:  constant(-500, 800),
+			// Interpolation stops at the last input sample.
+			wantChunks: []int{1598},
+			wantValue:  -500,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			sender := &fakeSender{}
+			err := Stream(context.Background(), sender, bytes.NewReader(tt.input), &StreamConfig{
+				Format:             tt.format,
+				DisablePacing:      true,
+				SendAudioStreamEnd: true,
+			})
+			if err != nil {
+				t.Fatalf("Stream() failed: %v", err)
+			}
+			if len(sender.inputs) != len(tt.wantChunks)+1 {
+				t.Fatalf("Stream() sent %d messages, want %d", len(sender.inputs), len(tt.wantChunks)+1)
+			}
+			for i, want := range tt.wantChunks {
+				audio := sender.inputs[i].Audio
+				if audio == nil {
+					t.Fatalf("message %d has no audio", i)
+				}
+				if audio.MIMEType != "audio/pcm;rate=16000" {
+					t.Errorf("message %d MIME type = %q, want %q", i, audio.MIMEType, "audio/pcm;rate=16000")
+				}
+				if got := len(audio.Data) / 2; got != want {
+					t.Errorf("message %d has %d samples, want %d", i, got, want)
+				}
+				for j := 0; j < len(audio.Data); j += 2 {
+					if got := int16(binary.LittleEndian.Uint16(audio.Data[j:])); got != tt.wantValue {
+						t.Fatalf("message %d sample %d = %d, want %d", i, j/2, got, tt.wantValue)
+					}
+				}
+			}
+			if !sender.inputs[len(sender.inputs)-1].AudioStreamEnd {
+				t.Errorf("last message = %+v, want AudioStreamEnd", sender.inputs[len(sender.inputs)-1])
+			}
+		})
+	}
+}
+
+func TestStreamPacing(t *testing.T) {
+	sender := &fakeSender{}
+	start := time.Now()
+	err := Stream(context.Background(), sender, bytes.NewReader(constant(0, 1600)), &StreamConfig{
+		Format:        Format{SampleRate: 16000, Channels: 1},
+		ChunkDuration: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Stream() failed: %v", err)
+	}
+	if len(sender.inputs) != 5 {
+		t.Fatalf("Stream() sent %d messages, want 5", len(sender.inputs))
+	}
+	// The last chunk starts 80ms into the audio.
+	if elapsed := sender.times[4].Sub(start); elapsed < 80*time.Millisecond {
+		t.Errorf("last chunk sent after %v, want at least 80ms", elapsed)
+	}
+}
+
+func TestStreamContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := Stream(ctx, &fakeSender{}, bytes.NewReader(constant(0, 16000)), &StreamConfig{
+		Format: Format{SampleRate: 16000, Channels: 1},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stream() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestStreamInvalidConfig(t *testing.T) {
+	for _, config := range []*StreamConfig{nil, {}, {Format: Format{SampleRate: 16000}}} {
+		if err := Stream(context.Background(), &fakeSender{}, bytes.NewReader(nil), config); err == nil {
+			t.Errorf("Stream(%+v) succeeded, want error", config)
+		}
+	}
+}
+
+func TestResamplerContinuity(t *testing.T) {
+	// A ramp resampled in chunks must match the ramp resampled at once.
+	in := make([]float64, 1000)
+	for i := range in {
+		in[i] = float64(i) / 1000
+	}
+	whole := newResampler(44100, 16000).process(in)
+
+	r := newResampler(44100, 16000)
+	var chunked []float64
+	for i := 0; i < len(in); i += 37 {
+		chunked = append(chunked, r.process(in[i:min(i+37, len(in))])...)
+	}
+	approx := cmp.Comparer(func(a, b float64) bool { return math.Abs(a-b) < 1e-9 })
+	if diff := cmp.Diff(whole, chunked, approx); diff != "" {
+		t.Errorf("chunked resampling mismatch (-whole +chunked):\n%s", diff)
+	}
+}
+
+func TestResamplerAntiAliasing(t *testing.T) {
+	// rms returns the RMS level of a tone resampled from 48kHz to 16kHz,
+	// ignoring the start of the output where the filter settles.
+	rms := func(frequency float64) float64 {
+		in := make([]float64, 48000)
+		for i := range in {
+			in[i] = 0.5 * math.Sin(2*math.Pi*frequency*float64(i)/48000)
+		}
+		out := newResampler(48000, 16000).process(in)[100:]
+		var sum float64
+		for _, s := range out {
+			sum += s * s
+		}
+		return math.Sqrt(sum / float64(len(out)))
+	}
+	// A tone below the output Nyquist frequency passes through.
+	if got, want := rms(1000), 0.5/math.Sqrt2; math.Abs(got-want) > 0.01 {
+		t.Errorf("RMS of a 1kHz tone = %.4f, want %.4f", got, want)
+	}
+	// A tone above it is filtered out instead of folding back to 4kHz.
+	if got := rms(12000); got > 0.001 {
+		t.Errorf("RMS of a 12kHz tone = %.4f, want it attenuated below 0.001", got)
+	}
+}
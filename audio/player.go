@@ -0,0 +1,188 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+	"google.golang.org/genai/internal/media"
+)
+
+const defaultJitterBuffer = 200 * time.Millisecond
+
+// PlayerConfig configures a [Player].
+type PlayerConfig struct {
+	// Optional. SampleRate of the audio read from the player. Model audio with
+	// a different rate is resampled. If zero, [OutputSampleRate] is used.
+	SampleRate int
+	// Optional. JitterBuffer is the amount of audio buffered before Read
+	// starts returning audio of a turn, and again after Read has drained the
+	// buffer in the middle of a turn. It smooths out uneven delivery of the
+	// model audio. If zero, 200 milliseconds is used. If negative, no audio is
+	// buffered.
+	JitterBuffer time.Duration
+}
+
+// Player assembles the audio generated by the model in a Live session into a
+// continuous stream of mono PCM that can be read from the [Player.Read]
+// method, for example to play it on a speaker or to write it to a
+// [WAVWriter].
+//
+// Pass every received message to [Player.Handle]. When the model is
+// interrupted, the audio that hasn't been read yet is discarded.
+//
+// A Player is safe for concurrent use.
+type Player struct {
+	format    Format
+	threshold int
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  []byte
+	// playing is true while Read returns audio without waiting for the jitter
+	// buffer to fill.
+	playing bool
+	closed  bool
+	// resamplers converts model audio by input sample rate.
+	resamplers map[int]*resampler
+}
+
+// NewPlayer returns a player configured by config, which may be nil.
+func NewPlayer(config *PlayerConfig) *Player {
+	if config == nil {
+		config = &PlayerConfig{}
+	}
+	format := Format{SampleRate: config.SampleRate, Channels: 1}
+	if format.SampleRate <= 0 {
+		format.SampleRate = OutputSampleRate
+	}
+	jitter := config.JitterBuffer
+	if jitter == 0 {
+		jitter = defaultJitterBuffer
+	}
+	threshold := 0
+	if jitter > 0 {
+		threshold = int(int64(format.SampleRate)*int64(jitter)/int64(time.Second)) * format.frameSize()
+	}
+	p := &Player{
+		format:     format,
+		threshold:  threshold,
+		resamplers: make(map[int]*resampler),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Format returns the format of the audio read from the player.
+func (p *Player) Format() Format {
+	return p.format
+}
+
+// Handle adds the audio in message to the player. When the model is
+// interrupted, it discards the buffered audio, and when the model's turn is
+// complete, it lets Read return the rest of the turn without waiting for the
+// jitter buffer. It returns true if message contained audio or one of these
+// events.
+func (p *Player) Handle(message *genai.LiveServerMessage) bool {
+	if message == nil || message.ServerContent == nil {
+		return false
+	}
+	content := message.ServerContent
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	handled := false
+	if content.Interrupted {
+		p.buf = nil
+		p.playing = false
+		handled = true
+	}
+	if content.ModelTurn != nil {
+		for _, part := range content.ModelTurn.Parts {
+			if part == nil || part.InlineData == nil {
+				continue
+			}
+			rate, ok := media.ParsePCMRate(part.InlineData.MIMEType, OutputSampleRate)
+			if !ok {
+				continue
+			}
+			p.buf = append(p.buf, p.resample(rate, part.InlineData.Data)...)
+			handled = true
+		}
+	}
+	if content.TurnComplete || content.GenerationComplete {
+		p.playing = true
+		handled = true
+	}
+	if len(p.buf) >= p.threshold {
+		p.playing = true
+	}
+	p.cond.Broadcast()
+	return handled
+}
+
+// resample converts mono PCM at rate to the player's sample rate.
+func (p *Player) resample(rate int, pcm []byte) []byte {
+	pcm = pcm[:len(pcm)-len(pcm)%2]
+	if rate == p.format.SampleRate {
+		return pcm
+	}
+	r, ok := p.resamplers[rate]
+	if !ok {
+		r = newResampler(rate, p.format.SampleRate)
+		p.resamplers[rate] = r
+	}
+	return encodeSamples(r.process(decodeSamples(pcm)))
+}
+
+// Read reads buffered audio into b. It blocks until the jitter buffer is
+// filled or the model's turn is complete. After [Player.Close], Read returns
+// the remaining audio and then [io.EOF].
+func (p *Player) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for !p.closed && (!p.playing || len(p.buf) == 0) {
+		p.cond.Wait()
+	}
+	if len(p.buf) == 0 {
+		return 0, io.EOF
+	}
+	// Only return whole samples.
+	n := copy(b[:len(b)-len(b)%2], p.buf)
+	p.buf = p.buf[n:]
+	if len(p.buf) == 0 && p.threshold > 0 {
+		// Buffer again after an underrun.
+		p.playing = false
+	}
+	return n, nil
+}
+
+// Buffered returns the duration of the audio that hasn't been read yet.
+func (p *Player) Buffered() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.format.duration(len(p.buf))
+}
+
+// Close makes Read return [io.EOF] once the buffered audio is read.
+func (p *Player) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.cond.Broadcast()
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func audioMessage(mimeType string, data []byte) *genai.LiveServerMessage {
+	return &genai.LiveServerMessage{ServerContent: &genai.LiveServerContent{
+		ModelTurn: &genai.Content{Parts: []*genai.Part{{InlineData: &genai.Blob{MIMEType: mimeType, Data: data}}}},
+	}}
+}
+
+// readAsync reads from r in a goroutine and returns a channel with the result.
+func readAsync(r io.Reader, n int) chan []byte {
+	result := make(chan []byte, 1)
+	go func() {
+		b := make([]byte, n)
+		n, _ := r.Read(b)
+		result <- b[:n]
+	}()
+	return result
+}
+
+func TestPlayerJitterBuffer(t *testing.T) {
+	// 10ms of 24kHz audio is 480 bytes.
+	p := NewPlayer(&PlayerConfig{JitterBuffer: 20 * time.Millisecond})
+	read := readAsync(p, 4096)
+
+	p.Handle(audioMessage("audio/pcm;rate=24000", constant(1, 240)))
+	select {
+	case b := <-read:
+		t.Fatalf("Read() returned %d bytes before the jitter buffer was filled", len(b))
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	p.Handle(audioMessage("audio/pcm;rate=24000", constant(2, 240)))
+	select {
+	case b := <-read:
+		if len(b) != 960 {
+			t.Errorf("Read() returned %d bytes, want 960", len(b))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read() didn't return after the jitter buffer was filled")
+	}
+
+	// The end of the turn flushes a partially filled buffer.
+	p.Handle(audioMessage("audio/pcm;rate=24000", constant(3, 10)))
+	p.Handle(&genai.LiveServerMessage{ServerContent: &genai.LiveServerContent{TurnComplete: true}})
+	if b := <-readAsync(p, 4096); !bytes.Equal(b, constant(3, 10)) {
+		t.Errorf("Read() after turn complete = %v, want %v", b, constant(3, 10))
+	}
+}
+
+func TestPlayerInterrupted(t *testing.T) {
+	p := NewPlayer(&PlayerConfig{JitterBuffer: -1})
+	p.Handle(audioMessage("audio/pcm;rate=24000", constant(1, 100)))
+	if got := p.Buffered(); got == 0 {
+		t.Fatalf("Buffered() = 0, want audio")
+	}
+	if !p.Handle(&genai.LiveServerMessage{ServerContent: &genai.LiveServerContent{Interrupted: true}}) {
+		t.Errorf("Handle() = false for an interruption, want true")
+	}
+	if got := p.Buffered(); got != 0 {
+		t.Errorf("Buffered() after interruption = %v, want 0", got)
+	}
+
+	p.Handle(audioMessage("audio/pcm;rate=24000", constant(2, 10)))
+	p.Close()
+	got, err := io.ReadAll(p)
+	if err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+	if !bytes.Equal(got, constant(2, 10)) {
+		t.Errorf("ReadAll() = %v, want %v", got, constant(2, 10))
+	}
+}
+
+func TestPlayerIgnoresOtherMessages(t *testing.T) {
+	p := NewPlayer(nil)
+	for _, message := range []*genai.LiveServerMessage{
+		nil,
+		{SetupComplete: &genai.LiveServerSetupComplete{}},
+		audioMessage("image/jpeg", []byte("jpeg")),
+	} {
+		if p.Handle(message) {
+			t.Errorf("Handle(%+v) = true, want false", message)
+		}
+	}
+	if got := p.Buffered(); got != 0 {
+		t.Errorf("Buffered() = %v, want 0", got)
+	}
+}
+
+func TestPlayerResamples(t *testing.T) {
+	p := NewPlayer(&PlayerConfig{JitterBuffer: -1})
+	p.Handle(audioMessage("audio/pcm;rate=48000", constant(7, 4801)))
+	p.Close()
+	got, err := io.ReadAll(p)
+	if err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+	if !bytes.Equal(got, constant(7, 2400)) {
+		t.Errorf("ReadAll() returned %d samples, want 2400 samples of value 7", len(got)/2)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	vadFrameDuration = 10 * time.Millisecond
+
+	defaultVADStartThreshold = -40
+	defaultVADThresholdGap   = 5
+	defaultVADMinSpeech      = 30 * time.Millisecond
+	defaultVADPreRoll        = 300 * time.Millisecond
+	defaultVADHangover       = 600 * time.Millisecond
+)
+
+// VADConfig configures the client-side voice activity detection of [Stream].
+//
+// The detector measures the energy of every 10 millisecond frame of audio.
+// Activity starts when the energy stays at or above StartThreshold for
+// MinSpeechDuration, and ends when it stays below EndThreshold for Hangover.
+// Using a lower EndThreshold than StartThreshold keeps quiet syllables from
+// ending the activity.
+//
+// Use it with [genai.AutomaticActivityDetection.Disabled] set, as the server
+// then relies on the client to mark activity.
+type VADConfig struct {
+	// Optional. StartThreshold is the frame energy, in dBFS, at which speech is
+	// detected. If zero, -40 dBFS is used.
+	StartThreshold float64
+	// Optional. EndThreshold is the frame energy, in dBFS, below which a frame
+	// is silent during activity. If zero, 5 dB below StartThreshold is used.
+	EndThreshold float64
+	// Optional. MinSpeechDuration is how long the energy must stay at or above
+	// StartThreshold to start activity. If zero, 30 milliseconds is used.
+	MinSpeechDuration time.Duration
+	// Optional. PreRoll is the duration of audio before the detected start of
+	// speech that is sent after ActivityStart, so that the first syllable isn't
+	// cut. If zero, 300 milliseconds is used. If negative, no audio before the
+	// start of speech is sent.
+	PreRoll time.Duration
+	// Optional. Hangover is how long the audio must stay silent to end
+	// activity. If zero, 600 milliseconds is used.
+	Hangover time.Duration
+}
+
+// vadEventKind is the kind of a vadEvent.
+type vadEventKind int
+
+const (
+	vadAudio vadEventKind = iota
+	vadActivityStart
+	vadActivityEnd
+)
+
+// vadEvent is audio to send or an activity marker.
+type vadEvent struct {
+	kind    vadEventKind
+	samples []float64
+}
+
+// vad is an energy-based voice activity detector for mono audio.
+type vad struct {
+	frameSize      int
+	startLevel     float64
+	endLevel       float64
+	minSpeech      int
+	preRollFrames  int
+	hangoverFrames int
+
+	// pending holds the samples of an incomplete frame.
+	pending []float64
+	active  bool
+	// recent holds the latest frames while not active, to be sent as pre-roll.
+	recent [][]float64
+	// speech is the number of consecutive speech frames while not active.
+	speech int
+	// silence is the number of consecutive silent frames while active.
+	silence int
+}
+
+func newVAD(config *VADConfig, sampleRate int) (*vad, error) {
+	start := config.StartThreshold
+	if start == 0 {
+		start = defaultVADStartThreshold
+	}
+	end := config.EndThreshold
+	if end == 0 {
+		end = start - defaultVADThresholdGap
+	}
+	if start > 0 || end > 0 {
+		return nil, fmt.Errorf("VAD thresholds must be negative dBFS values, got start %v and end %v", start, end)
+	}
+	if end > start {
+		return nil, fmt.Errorf("VAD end threshold %v must not be higher than start threshold %v", end, start)
+	}
+	frames := func(d, defaultValue time.Duration) int {
+		if d == 0 {
+			d = defaultValue
+		}
+		if d < 0 {
+			return 0
+		}
+		return int((d + vadFrameDuration - 1) / vadFrameDuration)
+	}
+	v := &vad{
+		frameSize:      int(int64(sampleRate) * int64(vadFrameDuration) / int64(time.Second)),
+		startLevel:     math.Pow(10, start/20),
+		endLevel:       math.Pow(10, end/20),
+		minSpeech:      max(frames(config.MinSpeechDuration, defaultVADMinSpeech), 1),
+		preRollFrames:  frames(config.PreRoll, defaultVADPreRoll),
+		hangoverFrames: max(frames(config.Hangover, defaultVADHangover), 1),
+	}
+	return v, nil
+}
+
+// process runs detection on samples and returns the audio to send and the
+// activity markers, in order. Audio outside of activity isn't returned.
+func (v *vad) process(samples []float64) []vadEvent {
+	var events []vadEvent
+	emit := func(kind vadEventKind, samples []float64) {
+		if kind == vadAudio && len(events) > 0 && events[len(events)-1].kind == vadAudio {
+			last := &events[len(events)-1]
+			last.samples = append(last.samples, samples...)
+			return
+		}
+		events = append(events, vadEvent{kind: kind, samples: samples})
+	}
+
+	v.pending = append(v.pending, samples...)
+	for len(v.pending) >= v.frameSize {
+		frame := v.pending[:v.frameSize:v.frameSize]
+		v.pending = v.pending[v.frameSize:]
+		level := rms(frame)
+
+		if v.active {
+			emit(vadAudio, frame)
+			if level < v.endLevel {
+				v.silence++
+			} else {
+				v.silence = 0
+			}
+			if v.silence >= v.hangoverFrames {
+				emit(vadActivityEnd, nil)
+				v.active = false
+				v.silence = 0
+			}
+			continue
+		}
+
+		v.recent = append(v.recent, frame)
+		if keep := v.preRollFrames + v.minSpeech; len(v.recent) > keep {
+			v.recent = v.recent[len(v.recent)-keep:]
+		}
+		if level >= v.startLevel {
+			v.speech++
+		} else {
+			v.speech = 0
+		}
+		if v.speech >= v.minSpeech {
+			emit(vadActivityStart, nil)
+			// Send the speech frames and the pre-roll before them.
+			for _, f := range v.recent {
+				emit(vadAudio, f)
+			}
+			v.recent = nil
+			v.active = true
+			v.speech = 0
+		}
+	}
+	return events
+}
+
+// flush ends the activity in progress, if any, at the end of the stream.
+func (v *vad) flush() []vadEvent {
+	if !v.active {
+		return nil
+	}
+	var events []vadEvent
+	if len(v.pending) > 0 {
+		events = append(events, vadEvent{kind: vadAudio, samples: v.pending})
+	}
+	v.pending = nil
+	v.active = false
+	return append(events, vadEvent{kind: vadActivityEnd})
+}
+
+// rms returns the root mean square of samples.
+func rms(samples []float64) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += s * s
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
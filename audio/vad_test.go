@@ -0,0 +1,130 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+)
+
+// summarize describes the sent messages as activity markers and the number of
+// consecutive audio samples.
+func summarize(inputs []genai.LiveRealtimeInput) []any {
+	var got []any
+	for _, input := range inputs {
+		switch {
+		case input.ActivityStart != nil:
+			got = append(got, "start")
+		case input.ActivityEnd != nil:
+			got = append(got, "end")
+		case input.Audio != nil:
+			n := len(input.Audio.Data) / 2
+			if len(got) > 0 {
+				if prev, ok := got[len(got)-1].(int); ok {
+					got[len(got)-1] = prev + n
+					continue
+				}
+			}
+			got = append(got, n)
+		}
+	}
+	return got
+}
+
+func TestStreamVAD(t *testing.T) {
+	// A sample value of 3000 is about -21 dBFS, and 230 is about -43 dBFS.
+	tests := []struct {
+		desc  string
+		input []byte
+		vad   *VADConfig
+		want  []any
+	}{
+		{
+			desc:  "silence",
+			input: constant(0, 16000),
+			vad:   &VADConfig{},
+			want:  nil,
+		},
+		{
+			desc:  "speech between silence",
+			input: bytes.Join([][]byte{constant(0, 16000), constant(3000, 3200), constant(0, 16000)}, nil),
+			vad:   &VADConfig{},
+			// 300ms of pre-roll, 200ms of speech and 600ms of hangover.
+			want: []any{"start", 17600, "end"},
+		},
+		{
+			desc:  "no pre-roll and short hangover",
+			input: bytes.Join([][]byte{constant(0, 16000), constant(3000, 3200), constant(0, 16000)}, nil),
+			vad:   &VADConfig{PreRoll: -1, Hangover: 100 * time.Millisecond},
+			want:  []any{"start", 3200 + 1600, "end"},
+		},
+		{
+			desc:  "quiet speech continues activity",
+			input: bytes.Join([][]byte{constant(3000, 1600), constant(230, 16000), constant(0, 16000)}, nil),
+			vad:   &VADConfig{PreRoll: -1},
+			want:  []any{"start", 1600 + 16000 + 9600, "end"},
+		},
+		{
+			desc:  "quiet speech doesn't start activity",
+			input: constant(230, 16000),
+			vad:   &VADConfig{},
+			want:  nil,
+		},
+		{
+			desc:  "end of stream ends activity",
+			input: bytes.Join([][]byte{constant(0, 1600), constant(3000, 1605)}, nil),
+			vad:   &VADConfig{},
+			want:  []any{"start", 3205, "end"},
+		},
+		{
+			desc:  "two activities",
+			input: bytes.Join([][]byte{constant(3000, 1600), constant(0, 16000), constant(3000, 1600), constant(0, 16000)}, nil),
+			vad:   &VADConfig{PreRoll: -1},
+			want:  []any{"start", 1600 + 9600, "end", "start", 1600 + 9600, "end"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			sender := &fakeSender{}
+			err := Stream(context.Background(), sender, bytes.NewReader(tt.input), &StreamConfig{
+				Format:        Format{SampleRate: 16000, Channels: 1},
+				DisablePacing: true,
+				VAD:           tt.vad,
+			})
+			if err != nil {
+				t.Fatalf("Stream() failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, summarize(sender.inputs)); diff != "" {
+				t.Errorf("sent messages mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewVADErrors(t *testing.T) {
+	for _, config := range []*VADConfig{
+		{StartThreshold: 3},
+		{StartThreshold: -50, EndThreshold: -40},
+	} {
+		if _, err := newVAD(config, InputSampleRate); err == nil {
+			t.Errorf("newVAD(%+v) succeeded, want error", config)
+		}
+	}
+}
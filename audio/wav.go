@@ -0,0 +1,100 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const wavHeaderSize = 44
+
+// WAVWriter writes PCM audio to a WAV file. The sizes in the WAV header are
+// written by [WAVWriter.Close], so the underlying writer must support
+// seeking, as an [*os.File] does.
+//
+//	f, err := os.Create("reply.wav")
+//	...
+//	w, err := audio.NewWAVWriter(f, player.Format())
+//	...
+//	_, err = io.Copy(w, player)
+//	...
+//	err = w.Close()
+type WAVWriter struct {
+	w      io.WriteSeeker
+	format Format
+	size   int64
+}
+
+// NewWAVWriter writes a WAV header for format to w and returns a writer for
+// the audio data.
+func NewWAVWriter(w io.WriteSeeker, format Format) (*WAVWriter, error) {
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+	ww := &WAVWriter{w: w, format: format}
+	if _, err := w.Write(ww.header()); err != nil {
+		return nil, fmt.Errorf("failed to write WAV header: %w", err)
+	}
+	return ww, nil
+}
+
+// Write writes PCM audio in the writer's format.
+func (w *WAVWriter) Write(pcm []byte) (int, error) {
+	n, err := w.w.Write(pcm)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close writes the final sizes to the WAV header. It doesn't close the
+// underlying writer.
+func (w *WAVWriter) Close() error {
+	if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to WAV header: %w", err)
+	}
+	if _, err := w.w.Write(w.header()); err != nil {
+		return fmt.Errorf("failed to write WAV header: %w", err)
+	}
+	_, err := w.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// header returns the WAV header for the audio written so far.
+func (w *WAVWriter) header() []byte {
+	return EncodeWAVHeader(w.format, w.size)
+}
+
+// EncodeWAVHeader returns the 44-byte header of a WAV file that contains
+// dataSize bytes of PCM audio in format. Prepend it to the audio to get a
+// WAV file when the size is known in advance.
+func EncodeWAVHeader(format Format, dataSize int64) []byte {
+	h := make([]byte, wavHeaderSize)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], uint32(36+dataSize))
+	copy(h[8:], "WAVE")
+	copy(h[12:], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	// PCM.
+	binary.LittleEndian.PutUint16(h[20:], 1)
+	binary.LittleEndian.PutUint16(h[22:], uint16(format.Channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(format.SampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(format.SampleRate*format.frameSize()))
+	binary.LittleEndian.PutUint16(h[32:], uint16(format.frameSize()))
+	binary.LittleEndian.PutUint16(h[34:], 16)
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], uint32(dataSize))
+	return h
+}
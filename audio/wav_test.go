@@ -0,0 +1,75 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	format := Format{SampleRate: OutputSampleRate, Channels: 1}
+	w, err := NewWAVWriter(f, format)
+	if err != nil {
+		t.Fatalf("NewWAVWriter() failed: %v", err)
+	}
+	data := constant(5, 100)
+	for _, chunk := range [][]byte{data[:50], data[50:]} {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != wavHeaderSize+len(data) {
+		t.Fatalf("WAV file has %d bytes, want %d", len(got), wavHeaderSize+len(data))
+	}
+	for _, field := range []struct {
+		name   string
+		offset int
+		want   uint32
+	}{
+		{"RIFF size", 4, uint32(36 + len(data))},
+		{"sample rate", 24, OutputSampleRate},
+		{"byte rate", 28, 2 * OutputSampleRate},
+		{"data size", 40, uint32(len(data))},
+	} {
+		if got := binary.LittleEndian.Uint32(got[field.offset:]); got != field.want {
+			t.Errorf("%s = %d, want %d", field.name, got, field.want)
+		}
+	}
+	if string(got[0:4]) != "RIFF" || string(got[8:12]) != "WAVE" || string(got[36:40]) != "data" {
+		t.Errorf("WAV header = %q, want RIFF/WAVE/data chunks", got[:wavHeaderSize])
+	}
+	if !bytes.Equal(got[wavHeaderSize:], data) {
+		t.Errorf("WAV data mismatch")
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBatchPollInterval = 10 * time.Second
+	// batchKeyMetadata is the metadata key of the inlined requests that holds
+	// the key of the request.
+	batchKeyMetadata = "key"
+)
+
+// BatchRequest is a request of a batch job created with
+// [Batches.CreateFromRequests].
+type BatchRequest struct {
+	// Required. Key identifies the request and its result. Keys must be unique
+	// within a batch job.
+	Key string
+	// Required. Contents of the request, as for [Models.GenerateContent].
+	Contents []*Content
+	// Optional. Config of the request, as for [Models.GenerateContent].
+	Config *GenerateContentConfig
+}
+
+// BatchResult is the result of a request of a batch job, returned by
+// [Batches.Results].
+type BatchResult struct {
+	// Key is the key of the request.
+	Key string
+	// Response is the response of the request, or nil if it failed.
+	Response *GenerateContentResponse
+	// Error is the error of the request, or nil if it succeeded.
+	Error *JobError
+}
+
+// CreateFromRequestsConfig configures [Batches.CreateFromRequests].
+type CreateFromRequestsConfig struct {
+	// Optional. Used to override HTTP request options.
+	HTTPOptions *HTTPOptions
+	// Optional. The user-defined name of the batch job.
+	DisplayName string
+	// Optional. Inline sends the requests in the batch job creation request, as
+	// [BatchJobSource.InlinedRequests], instead of uploading them as a JSONL
+	// file. The results are then inlined in the batch job too. Inlined requests
+	// are limited in size.
+	Inline bool
+}
+
+// WaitBatchJobConfig configures [Batches.Wait].
+type WaitBatchJobConfig struct {
+	// Optional. Used to override HTTP request options of the polls.
+	HTTPOptions *HTTPOptions
+	// Optional. PollInterval is the delay between two polls of the batch job. If
+	// zero, 10 seconds is used.
+	PollInterval time.Duration
+	// Optional. OnProgress is called with the batch job after each poll. Its
+	// CompletionStats, if set, report the progress of the requests.
+	OnProgress func(job *BatchJob)
+}
+
+// CreateFromRequests creates a batch job of model for requests. Unless
+// [CreateFromRequestsConfig.Inline] is set, the requests are written to a JSONL
+// file uploaded with [Files.Upload], which is the source of the batch job.
+//
+// Wait for the batch job with [Batches.Wait] and read its results with
+// [Batches.Results]:
+//
+//	job, err := client.Batches.CreateFromRequests(ctx, "gemini-2.5-flash", requests, nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	job, err = client.Batches.Wait(ctx, job.Name, nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	for result, err := range client.Batches.Results(ctx, job) {
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		fmt.Println(result.Key, result.Response.Text())
+//	}
+func (m Batches) CreateFromRequests(ctx context.Context, model string, requests []*BatchRequest, config *CreateFromRequestsConfig) (*BatchJob, error) {
+	if m.apiClient.clientConfig.Backend == BackendVertexAI {
+		return nil, fmt.Errorf("method CreateFromRequests is only supported in Gemini Developer API mode, not in Gemini Enterprise Agent Platform mode. You can choose to use Gemini Developer client by setting ClientConfig.Backend to BackendGeminiAPI.")
+	}
+	if config == nil {
+		config = &CreateFromRequestsConfig{}
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("at least one request is required")
+	}
+	keys := map[string]bool{}
+	for _, request := range requests {
+		if request == nil || request.Key == "" {
+			return nil, fmt.Errorf("batch requests must have a key")
+		}
+		if keys[request.Key] {
+			return nil, fmt.Errorf("duplicate batch request key %q", request.Key)
+		}
+		keys[request.Key] = true
+	}
+	createConfig := &CreateBatchJobConfig{HTTPOptions: config.HTTPOptions, DisplayName: config.DisplayName}
+
+	if config.Inline {
+		src := &BatchJobSource{}
+		for _, request := range requests {
+			src.InlinedRequests = append(src.InlinedRequests, &InlinedRequest{
+				Contents: request.Contents,
+				Config:   request.Config,
+				Metadata: map[string]string{batchKeyMetadata: request.Key},
+			})
+		}
+		return m.Create(ctx, model, src, createConfig)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, request := range requests {
+		line, err := m.batchRequestLine(request)
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch request %q: %w", request.Key, err)
+		}
+		if err := enc.Encode(line); err != nil {
+			return nil, fmt.Errorf("invalid batch request %q: %w", request.Key, err)
+		}
+	}
+	file, err := Files{apiClient: m.apiClient}.Upload(ctx, &buf, &UploadFileConfig{
+		HTTPOptions: config.HTTPOptions,
+		MIMEType:    "application/jsonl",
+		DisplayName: config.DisplayName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload batch requests: %w", err)
+	}
+	return m.Create(ctx, model, &BatchJobSource{FileName: file.Name}, createConfig)
+}
+
+// batchRequestLine returns the line of the JSONL file of a batch job for
+// request, with the request in the format of the API.
+func (m Batches) batchRequestLine(request *BatchRequest) (map[string]any, error) {
+	parameterMap := make(map[string]any)
+	if err := InternalDeepMarshal(&InlinedRequest{Contents: request.Contents, Config: request.Config}, &parameterMap); err != nil {
+		return nil, err
+	}
+	converted, err := inlinedRequestToMldev(m.apiClient, parameterMap, nil, parameterMap)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"key": request.Key, "request": converted["request"]}, nil
+}
+
+// Wait polls the batch job with the given name until it is done, and returns
+// it. If the batch job failed, was cancelled or expired, it is returned with an
+// error.
+//
+// Wait waits until ctx is done; use a context with a deadline to bound the
+// wait.
+func (m Batches) Wait(ctx context.Context, name string, config *WaitBatchJobConfig) (*BatchJob, error) {
+	if config == nil {
+		config = &WaitBatchJobConfig{}
+	}
+	interval := config.PollInterval
+	if interval <= 0 {
+		interval = defaultBatchPollInterval
+	}
+	for {
+		job, err := m.getWithStats(ctx, name, config.HTTPOptions)
+		if err != nil {
+			return nil, err
+		}
+		if config.OnProgress != nil {
+			config.OnProgress(job)
+		}
+		switch job.State {
+		case JobStateSucceeded, JobStatePartiallySucceeded:
+			return job, nil
+		case JobStateFailed, JobStateCancelled, JobStateExpired:
+			if job.Error != nil {
+				return job, fmt.Errorf("batch job %s ended in state %s: %s", job.Name, job.State, job.Error.Message)
+			}
+			return job, fmt.Errorf("batch job %s ended in state %s", job.Name, job.State)
+		}
+		select {
+		case <-ctx.Done():
+			return job, fmt.Errorf("batch job %s is still %s: %w", name, job.State, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// getWithStats returns the batch job with the given name, like [Batches.Get].
+// The Gemini Developer API reports the progress of batch jobs in batchStats,
+// which is returned as the CompletionStats of the job, but dropped by
+// [Batches.Get].
+func (m Batches) getWithStats(ctx context.Context, name string, configHTTPOptions *HTTPOptions) (*BatchJob, error) {
+	if m.apiClient.clientConfig.Backend == BackendVertexAI {
+		return m.Get(ctx, name, &GetBatchJobConfig{HTTPOptions: configHTTPOptions})
+	}
+	var httpOptions HTTPOptions
+	if configHTTPOptions != nil {
+		httpOptions = *configHTTPOptions
+	}
+	if httpOptions.Headers == nil {
+		httpOptions.Headers = http.Header{}
+	}
+	path := "batches/" + strings.TrimPrefix(name, "batches/")
+	responseMap, err := sendRequest(ctx, m.apiClient, path, http.MethodGet, nil, &httpOptions)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := batchJobFromMldev(responseMap, nil, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	job := new(BatchJob)
+	if err := mapToStruct(converted, job); err != nil {
+		return nil, err
+	}
+	if stats, ok := InternalGetValueByPath(responseMap, []string{"metadata", "batchStats"}).(map[string]any); ok {
+		var batchStats struct {
+			SuccessfulRequestCount int64 `json:"successfulRequestCount,string"`
+			FailedRequestCount     int64 `json:"failedRequestCount,string"`
+			PendingRequestCount    int64 `json:"pendingRequestCount,string"`
+		}
+		if err := mapToStruct(stats, &batchStats); err != nil {
+			return nil, fmt.Errorf("invalid batch stats: %w", err)
+		}
+		job.CompletionStats = &CompletionStats{
+			SuccessfulCount: batchStats.SuccessfulRequestCount,
+			FailedCount:     batchStats.FailedRequestCount,
+			IncompleteCount: batchStats.PendingRequestCount,
+		}
+	}
+	return job, nil
+}
+
+// Results returns an iterator over the results of the requests of a batch job
+// created with [Batches.CreateFromRequests], once it is done.
+//
+// The results of a batch job with a responses file are streamed from the file,
+// without holding it in memory. The results of a batch job with inlined
+// responses are read from the job, with the same keys; inlined responses
+// without a key are keyed by their index.
+func (m Batches) Results(ctx context.Context, job *BatchJob) iter.Seq2[*BatchResult, error] {
+	return func(yield func(*BatchResult, error) bool) {
+		if job == nil || job.Dest == nil {
+			yield(nil, fmt.Errorf("batch job has no results, wait for it with Batches.Wait"))
+			return
+		}
+		if job.Dest.FileName == "" {
+			for i, response := range job.Dest.InlinedResponses {
+				result := &BatchResult{Key: strconv.Itoa(i)}
+				if response != nil {
+					if key, ok := response.Metadata[batchKeyMetadata]; ok {
+						result.Key = key
+					}
+					result.Response, result.Error = response.Response, response.Error
+				}
+				if !yield(result, nil) {
+					return
+				}
+			}
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		r, w := io.Pipe()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := Files{apiClient: m.apiClient}.DownloadTo(ctx, &File{DownloadURI: job.Dest.FileName}, w, nil)
+			w.CloseWithError(err)
+		}()
+		defer func() {
+			r.Close()
+			<-done
+		}()
+
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				result, parseErr := parseBatchResultLine(line)
+				if !yield(result, parseErr) || parseErr != nil {
+					return
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("failed to download batch results: %w", err))
+				return
+			}
+		}
+	}
+}
+
+// parseBatchResultLine parses a line of the responses file of a batch job.
+func parseBatchResultLine(line []byte) (*BatchResult, error) {
+	var lineMap map[string]any
+	if err := json.Unmarshal(line, &lineMap); err != nil {
+		return nil, fmt.Errorf("invalid batch result: %w", err)
+	}
+	converted, err := inlinedResponseFromMldev(lineMap, nil, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	var response InlinedResponse
+	if err := mapToStruct(converted, &response); err != nil {
+		return nil, fmt.Errorf("invalid batch result: %w", err)
+	}
+	key, _ := lineMap["key"].(string)
+	return &BatchResult{Key: key, Response: response.Response, Error: response.Error}, nil
+}
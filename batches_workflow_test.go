@@ -0,0 +1,191 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testBatchServer is a [testFilesAPI] that runs a batch job of the uploaded
+// requests file: the job is running for the first poll, then succeeds with
+// results as its responses file.
+type testBatchServer struct {
+	*testFilesAPI
+	create  string
+	polls   int
+	results string
+	// pollHeaders is the headers of the last poll of the batch job.
+	pollHeaders http.Header
+}
+
+func newTestBatchClient(t *testing.T, s *testBatchServer) *Client {
+	t.Helper()
+	s.testFilesAPI = newTestFilesAPI(t)
+	s.files["files/results"] = &File{Name: "files/results"}
+	s.data["files/results"] = []byte(s.results)
+	s.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		switch path := r.URL.Path; {
+		case strings.HasSuffix(path, ":batchGenerateContent"):
+			s.create = string(body)
+			fmt.Fprint(w, `{"name": "batches/1", "metadata": {"state": "BATCH_STATE_PENDING"}}`)
+		case path == "/v1beta/batches/1":
+			s.pollHeaders = r.Header.Clone()
+			if s.polls++; s.polls == 1 {
+				fmt.Fprint(w, `{"name": "batches/1", "metadata": {"state": "BATCH_STATE_RUNNING", "batchStats": {"requestCount": "3", "successfulRequestCount": "1", "pendingRequestCount": "2"}}}`)
+				return true
+			}
+			fmt.Fprint(w, `{"name": "batches/1", "metadata": {"state": "BATCH_STATE_SUCCEEDED", "output": {"responsesFile": "files/results"}, "batchStats": {"requestCount": "3", "successfulRequestCount": "2", "failedRequestCount": "1"}}}`)
+		default:
+			return false
+		}
+		return true
+	}
+	return s.client()
+}
+
+func TestBatchWorkflow(t *testing.T) {
+	ctx := context.Background()
+	s := &testBatchServer{results: `{"key": "b", "response": {"candidates": [{"content": {"role": "model", "parts": [{"text": "B"}]}}]}}
+{"key": "a", "response": {"candidates": [{"content": {"role": "model", "parts": [{"text": "A"}]}}]}}
+
+{"key": "c", "error": {"code": 3, "message": "invalid request"}}
+`}
+	client := newTestBatchClient(t, s)
+	requests := []*BatchRequest{
+		{Key: "a", Contents: Text("Say A."), Config: &GenerateContentConfig{Temperature: Ptr[float32](0), SystemInstruction: Text("Be brief.")[0]}},
+		{Key: "b", Contents: Text("Say B.")},
+		{Key: "c", Contents: Text("Say C.")},
+	}
+
+	job, err := client.Batches.CreateFromRequests(ctx, "gemini-2.5-flash", requests, &CreateFromRequestsConfig{DisplayName: "letters"})
+	if err != nil {
+		t.Fatalf("CreateFromRequests failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(s.data["files/1"])), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("uploaded %d requests, want 3", len(lines))
+	}
+	var first map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("invalid request line %s: %v", lines[0], err)
+	}
+	wantFirst := map[string]any{
+		"key": "a",
+		"request": map[string]any{
+			"contents":          []any{map[string]any{"role": "user", "parts": []any{map[string]any{"text": "Say A."}}}},
+			"generationConfig":  map[string]any{"temperature": float64(0)},
+			"systemInstruction": map[string]any{"role": "user", "parts": []any{map[string]any{"text": "Be brief."}}},
+		},
+	}
+	if diff := cmp.Diff(wantFirst, first); diff != "" {
+		t.Errorf("request line mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(s.create, `"fileName":"files/1"`) {
+		t.Errorf("batch job creation request = %s, want the uploaded file as source", s.create)
+	}
+
+	var progress []CompletionStats
+	job, err = client.Batches.Wait(ctx, job.Name, &WaitBatchJobConfig{
+		HTTPOptions:  &HTTPOptions{Headers: http.Header{"X-Test": []string{"poll"}}},
+		PollInterval: time.Millisecond,
+		OnProgress:   func(job *BatchJob) { progress = append(progress, *job.CompletionStats) },
+	})
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	wantProgress := []CompletionStats{{SuccessfulCount: 1, IncompleteCount: 2}, {SuccessfulCount: 2, FailedCount: 1}}
+	if diff := cmp.Diff(wantProgress, progress); diff != "" {
+		t.Errorf("progress mismatch (-want +got):\n%s", diff)
+	}
+	if got := s.pollHeaders.Get("X-Test"); got != "poll" {
+		t.Errorf("X-Test header of the polls = %q, want the header of WaitBatchJobConfig.HTTPOptions", got)
+	}
+
+	var got []string
+	for result, err := range client.Batches.Results(ctx, job) {
+		if err != nil {
+			t.Fatalf("Results failed: %v", err)
+		}
+		if result.Error != nil {
+			got = append(got, result.Key+" error "+result.Error.Message)
+			continue
+		}
+		got = append(got, result.Key+" "+result.Response.Text())
+	}
+	if diff := cmp.Diff([]string{"b B", "a A", "c error invalid request"}, got); diff != "" {
+		t.Errorf("results mismatch (-want +got):\n%s", diff)
+	}
+
+	// Stopping early stops the download.
+	for range client.Batches.Results(ctx, job) {
+		break
+	}
+}
+
+func TestBatchWorkflowInline(t *testing.T) {
+	ctx := context.Background()
+	s := &testBatchServer{}
+	client := newTestBatchClient(t, s)
+	requests := []*BatchRequest{{Key: "a", Contents: Text("Say A.")}, {Key: "b", Contents: Text("Say B.")}}
+
+	if _, err := client.Batches.CreateFromRequests(ctx, "gemini-2.5-flash", requests, &CreateFromRequestsConfig{Inline: true}); err != nil {
+		t.Fatalf("CreateFromRequests failed: %v", err)
+	}
+	if s.data["files/1"] != nil || !strings.Contains(s.create, `"metadata":{"key":"b"}`) {
+		t.Errorf("batch job creation request = %s, want inlined requests with their keys", s.create)
+	}
+
+	job := &BatchJob{Dest: &BatchJobDestination{InlinedResponses: []*InlinedResponse{
+		{Metadata: map[string]string{"key": "b"}, Error: &JobError{Message: "invalid request"}},
+		{Metadata: map[string]string{"key": "a"}, Response: &GenerateContentResponse{}},
+		{Response: &GenerateContentResponse{}},
+	}}}
+	var got []*BatchResult
+	for result, err := range client.Batches.Results(ctx, job) {
+		if err != nil {
+			t.Fatalf("Results failed: %v", err)
+		}
+		got = append(got, result)
+	}
+	want := []*BatchResult{
+		{Key: "b", Error: &JobError{Message: "invalid request"}},
+		{Key: "a", Response: &GenerateContentResponse{}},
+		{Key: "2", Response: &GenerateContentResponse{}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("results mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCreateFromRequestsInvalidKeys(t *testing.T) {
+	client := newTestBatchClient(t, &testBatchServer{})
+	for _, requests := range [][]*BatchRequest{
+		nil,
+		{{Contents: Text("No key.")}},
+		{{Key: "a", Contents: Text("A.")}, {Key: "a", Contents: Text("Again A.")}},
+	} {
+		if _, err := client.Batches.CreateFromRequests(context.Background(), "gemini-2.5-flash", requests, nil); err == nil {
+			t.Errorf("CreateFromRequests(%d requests) succeeded, want an error", len(requests))
+		}
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheManagerTTL    = 10 * time.Minute
+	defaultCacheManagerPrefix = "genai-cache-"
+)
+
+// CacheManagerConfig configures [Caches.NewManager].
+type CacheManagerConfig struct {
+	// Optional. TTL is the time to live of the caches, set on creation and
+	// extended while they are leased. If zero, 10 minutes is used.
+	TTL time.Duration
+	// Optional. RefreshInterval is the delay between two extensions of the TTL
+	// of a leased cache. It must be less than TTL. If zero, half of TTL is
+	// used.
+	RefreshInterval time.Duration
+	// Optional. DisplayNamePrefix is the prefix of the display names of the
+	// managed caches, followed by the hash of their content. Caches without it
+	// are never reused or swept. If empty, "genai-cache-" is used.
+	DisplayNamePrefix string
+	// Optional. KeepOnRelease keeps caches when their last lease is released,
+	// until they expire or are swept. Set it when caches are shared by several
+	// processes, since the leases of other processes are unknown.
+	KeepOnRelease bool
+	// Optional. OnError is called with the errors of the background TTL
+	// extensions and of the deletions on release.
+	OnError func(err error)
+}
+
+// CacheManager creates [CachedContent] on demand and manages their lifecycle.
+// Caches are identified by the hash of their model, system instruction, tools,
+// tool config and contents, stored in their display name, so that identical
+// content reuses the same live cache, even across processes.
+//
+// A cache is leased with [CacheManager.Acquire], and its TTL is extended in
+// the background while at least one lease is held. A cache created by the
+// manager is deleted when its last lease is released, unless
+// [CacheManagerConfig.KeepOnRelease] is set; a cache reused from another
+// manager is left to expire.
+// [CacheManager.Sweep] deletes the managed caches that were orphaned, for
+// example by a crashed process.
+type CacheManager struct {
+	caches        Caches
+	ttl           time.Duration
+	refresh       time.Duration
+	prefix        string
+	keepOnRelease bool
+	onError       func(err error)
+	now           func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*managedCache
+}
+
+// managedCache is a cache leased by a [CacheManager].
+type managedCache struct {
+	// ready is closed once cache or err is set.
+	ready  chan struct{}
+	cache  *CachedContent
+	err    error
+	leases int
+	// created reports whether the cache was created by the manager, rather
+	// than reused.
+	created bool
+	// stop stops extending the TTL of the cache.
+	stop context.CancelFunc
+	// deleted is set when the last lease is released and the cache is being
+	// deleted, and closed once it is. Until then, the entry stays in the
+	// manager so that the cache isn't reused.
+	deleted chan struct{}
+}
+
+// CacheLease is a lease on a cache acquired with [CacheManager.Acquire].
+type CacheLease struct {
+	// Cache is the leased cache. Its ExpireTime is not updated by the
+	// background TTL extensions.
+	Cache *CachedContent
+
+	manager  *CacheManager
+	key      string
+	entry    *managedCache
+	released sync.Once
+}
+
+// NewManager returns a [CacheManager] that manages caches with m.
+func (m Caches) NewManager(config *CacheManagerConfig) *CacheManager {
+	if config == nil {
+		config = &CacheManagerConfig{}
+	}
+	cm := &CacheManager{
+		caches:        m,
+		ttl:           config.TTL,
+		refresh:       config.RefreshInterval,
+		prefix:        config.DisplayNamePrefix,
+		keepOnRelease: config.KeepOnRelease,
+		onError:       config.OnError,
+		now:           time.Now,
+		entries:       map[string]*managedCache{},
+	}
+	if cm.ttl <= 0 {
+		cm.ttl = defaultCacheManagerTTL
+	}
+	if cm.refresh <= 0 || cm.refresh >= cm.ttl {
+		cm.refresh = cm.ttl / 2
+	}
+	if cm.prefix == "" {
+		cm.prefix = defaultCacheManagerPrefix
+	}
+	if cm.onError == nil {
+		cm.onError = func(error) {}
+	}
+	return cm
+}
+
+// Acquire returns a lease on a live cache of the content of config for model,
+// creating the cache with [Caches.Create] if no managed cache of the same
+// content is live. The TTL, ExpireTime and DisplayName of config are ignored.
+//
+// The lease must be released with [CacheLease.Release] once the cache is no
+// longer used.
+func (cm *CacheManager) Acquire(ctx context.Context, model string, config *CreateCachedContentConfig) (*CacheLease, error) {
+	if config == nil {
+		config = &CreateCachedContentConfig{}
+	}
+	key, err := cacheKey(model, config)
+	if err != nil {
+		return nil, err
+	}
+
+	cm.mu.Lock()
+	entry, ok := cm.entries[key]
+	for ok && entry.deleted != nil {
+		deleted := entry.deleted
+		cm.mu.Unlock()
+		select {
+		case <-deleted:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		cm.mu.Lock()
+		entry, ok = cm.entries[key]
+	}
+	if !ok {
+		entry = &managedCache{ready: make(chan struct{})}
+		cm.entries[key] = entry
+	}
+	entry.leases++
+	cm.mu.Unlock()
+
+	if !ok {
+		cache, created, err := cm.findOrCreate(ctx, key, model, config)
+		cm.mu.Lock()
+		entry.cache, entry.created, entry.err = cache, created, err
+		if err == nil {
+			keepaliveCtx, stop := context.WithCancel(context.Background())
+			entry.stop = stop
+			go cm.keepalive(keepaliveCtx, cache.Name)
+		}
+		cm.mu.Unlock()
+		close(entry.ready)
+	} else {
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			cm.release(ctx, key, entry)
+			return nil, ctx.Err()
+		}
+	}
+	if entry.err != nil {
+		cm.release(ctx, key, entry)
+		return nil, entry.err
+	}
+	return &CacheLease{Cache: entry.cache, manager: cm, key: key, entry: entry}, nil
+}
+
+// Name returns the name of the leased cache, to set as
+// [GenerateContentConfig.CachedContent].
+func (l *CacheLease) Name() string {
+	return l.Cache.Name
+}
+
+// Release releases the lease. If it is the last lease on the cache, the TTL of
+// the cache is no longer extended and the cache is deleted if the manager
+// created it, unless [CacheManagerConfig.KeepOnRelease] is set. Releasing a
+// lease several times has no effect.
+func (l *CacheLease) Release(ctx context.Context) error {
+	var err error
+	l.released.Do(func() {
+		err = l.manager.release(ctx, l.key, l.entry)
+	})
+	return err
+}
+
+// release releases a lease on entry. If it was the last one, the entry is
+// removed and its TTL no longer extended, and the cache is deleted if the
+// manager created it.
+func (cm *CacheManager) release(ctx context.Context, key string, entry *managedCache) error {
+	if !cm.unlease(key, entry) {
+		return nil
+	}
+	var err error
+	if _, deleteErr := cm.caches.Delete(ctx, entry.cache.Name, nil); deleteErr != nil {
+		err = fmt.Errorf("failed to delete cache %s: %w", entry.cache.Name, deleteErr)
+		cm.onError(err)
+	}
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.entries[key] == entry {
+		delete(cm.entries, key)
+	}
+	close(entry.deleted)
+	return err
+}
+
+// unlease decrements the leases of entry and reports whether its cache must
+// be deleted. After the last lease, the TTL of the cache is no longer
+// extended, and the entry is removed, unless its cache must be deleted, in
+// which case the entry is marked deleted.
+func (cm *CacheManager) unlease(key string, entry *managedCache) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	entry.leases--
+	if entry.leases > 0 {
+		return false
+	}
+	if entry.stop != nil {
+		entry.stop()
+	}
+	if entry.err == nil && entry.created && !cm.keepOnRelease {
+		entry.deleted = make(chan struct{})
+		return true
+	}
+	if cm.entries[key] == entry {
+		delete(cm.entries, key)
+	}
+	return false
+}
+
+// findOrCreate returns a live managed cache with the given key, extending its
+// TTL, or creates one. It reports whether the cache was created.
+func (cm *CacheManager) findOrCreate(ctx context.Context, key, model string, config *CreateCachedContentConfig) (*CachedContent, bool, error) {
+	displayName := cm.prefix + key
+	for cache, err := range cm.caches.All(ctx) {
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list caches: %w", err)
+		}
+		if cache.DisplayName != displayName || !cache.ExpireTime.After(cm.now().Add(cm.refresh)) {
+			continue
+		}
+		updated, err := cm.caches.Update(ctx, cache.Name, &UpdateCachedContentConfig{TTL: cm.ttl})
+		if err == nil {
+			return updated, false, nil
+		}
+		// The cache may have expired or been deleted in the meantime.
+		var apiErr APIError
+		if !errors.As(err, &apiErr) || apiErr.Code != 404 {
+			return nil, false, fmt.Errorf("failed to extend cache %s: %w", cache.Name, err)
+		}
+	}
+
+	createConfig := *config
+	createConfig.TTL = cm.ttl
+	createConfig.ExpireTime = time.Time{}
+	createConfig.DisplayName = displayName
+	cache, err := cm.caches.Create(ctx, model, &createConfig)
+	return cache, err == nil, err
+}
+
+// keepalive extends the TTL of the cache every refresh interval until ctx is
+// done.
+func (cm *CacheManager) keepalive(ctx context.Context, name string) {
+	ticker := time.NewTicker(cm.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := cm.caches.Update(ctx, name, &UpdateCachedContentConfig{TTL: cm.ttl}); err != nil && ctx.Err() == nil {
+			cm.onError(fmt.Errorf("failed to extend cache %s: %w", name, err))
+		}
+	}
+}
+
+// Sweep deletes the managed caches of [Caches.All] created more than maxAge
+// ago that are not leased by this manager, such as the caches orphaned by a
+// crashed process. Caches leased by other processes are deleted too if they
+// are older than maxAge.
+//
+// The returned summary lists the names of the caches. Its error joins the
+// errors of the failed deletions; the summary is returned even if it isn't
+// nil.
+func (cm *CacheManager) Sweep(ctx context.Context, maxAge time.Duration) (*DeleteSummary, error) {
+	cm.mu.Lock()
+	leased := map[string]bool{}
+	for _, entry := range cm.entries {
+		// Caches being created are not listed yet.
+		if entry.cache != nil {
+			leased[entry.cache.Name] = true
+		}
+	}
+	cm.mu.Unlock()
+
+	summary := &DeleteSummary{Failed: map[string]error{}}
+	var names []string
+	for cache, err := range cm.caches.All(ctx) {
+		if err != nil {
+			return summary, fmt.Errorf("failed to list caches: %w", err)
+		}
+		if strings.HasPrefix(cache.DisplayName, cm.prefix) && !leased[cache.Name] && cm.now().Sub(cache.CreateTime) > maxAge {
+			names = append(names, cache.Name)
+		}
+	}
+
+	var mu sync.Mutex
+	forEachConcurrently(0, names, func(name string) {
+		_, err := cm.caches.Delete(ctx, name, nil)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			summary.Failed[name] = err
+			return
+		}
+		summary.Deleted = append(summary.Deleted, name)
+	})
+	slices.Sort(summary.Deleted)
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(summary.Failed)) {
+		errs = append(errs, fmt.Errorf("failed to delete cache %s: %w", name, summary.Failed[name]))
+	}
+	return summary, errors.Join(errs...)
+}
+
+// cacheKey returns the hexadecimal SHA-256 hash of the model and of the cached
+// content of config.
+func cacheKey(model string, config *CreateCachedContentConfig) (string, error) {
+	data, err := json.Marshal(struct {
+		Model             string      `json:"model"`
+		SystemInstruction *Content    `json:"systemInstruction,omitempty"`
+		Tools             []*Tool     `json:"tools,omitempty"`
+		ToolConfig        *ToolConfig `json:"toolConfig,omitempty"`
+		Contents          []*Content  `json:"contents,omitempty"`
+		KmsKeyName        string      `json:"kmsKeyName,omitempty"`
+	}{
+		Model:             strings.TrimPrefix(model, "models/"),
+		SystemInstruction: config.SystemInstruction,
+		Tools:             config.Tools,
+		ToolConfig:        config.ToolConfig,
+		Contents:          config.Contents,
+		KmsKeyName:        config.KmsKeyName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash cached content: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
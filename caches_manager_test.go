@@ -0,0 +1,295 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testCachesServer is a [testFilesAPI] that stores caches.
+type testCachesServer struct {
+	*testFilesAPI
+	caches  map[string]*CachedContent
+	creates int
+	updates int
+	// beforeDelete, if set, is called before a deletion is handled.
+	beforeDelete func()
+}
+
+func newTestCachesClient(t *testing.T, s *testCachesServer) *Client {
+	t.Helper()
+	if s.caches == nil {
+		s.caches = map[string]*CachedContent{}
+	}
+	s.testFilesAPI = newTestFilesAPI(t)
+	s.before = func(r *http.Request) {
+		if r.Method == http.MethodDelete && s.beforeDelete != nil {
+			s.beforeDelete()
+		}
+	}
+	s.handle = func(w http.ResponseWriter, r *http.Request, data []byte) bool {
+		var body struct {
+			DisplayName string `json:"displayName"`
+			Model       string `json:"model"`
+			TTL         string `json:"ttl"`
+		}
+		json.Unmarshal(data, &body)
+		ttl, _ := time.ParseDuration(body.TTL)
+		name := strings.TrimPrefix(r.URL.Path, "/v1beta/")
+		switch {
+		case !strings.HasPrefix(name, "cachedContents"):
+			return false
+		case name == "cachedContents" && r.Method == http.MethodPost:
+			s.creates++
+			cache := &CachedContent{
+				Name:        fmt.Sprintf("cachedContents/%d", s.creates),
+				DisplayName: body.DisplayName,
+				Model:       body.Model,
+				CreateTime:  time.Now(),
+				ExpireTime:  time.Now().Add(ttl),
+			}
+			s.caches[cache.Name] = cache
+			json.NewEncoder(w).Encode(cache)
+		case name == "cachedContents":
+			json.NewEncoder(w).Encode(map[string]any{"cachedContents": slices.Collect(maps.Values(s.caches))})
+		case s.caches[name] == nil:
+			writeTestNotFound(w)
+		case r.Method == http.MethodPatch:
+			s.updates++
+			s.caches[name].ExpireTime = time.Now().Add(ttl)
+			json.NewEncoder(w).Encode(s.caches[name])
+		case r.Method == http.MethodDelete:
+			delete(s.caches, name)
+			fmt.Fprint(w, `{}`)
+		default:
+			return false
+		}
+		return true
+	}
+	return s.client()
+}
+
+func (s *testCachesServer) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Sorted(maps.Keys(s.caches))
+}
+
+func TestCacheManagerLeases(t *testing.T) {
+	ctx := context.Background()
+	s := &testCachesServer{}
+	client := newTestCachesClient(t, s)
+	cm := client.Caches.NewManager(nil)
+	book := &CreateCachedContentConfig{Contents: Text("A long book.")}
+
+	// Concurrent leases on the same content share a cache.
+	leases := make([]*CacheLease, 4)
+	var wg sync.WaitGroup
+	for i := range leases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lease, err := cm.Acquire(ctx, "gemini-2.5-flash", book)
+			if err != nil {
+				t.Errorf("Acquire failed: %v", err)
+				return
+			}
+			leases[i] = lease
+		}()
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+	other, err := cm.Acquire(ctx, "models/gemini-2.5-flash", &CreateCachedContentConfig{Contents: Text("Another book.")})
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if s.creates != 2 {
+		t.Errorf("created %d caches, want 2", s.creates)
+	}
+	for _, lease := range leases {
+		if lease.Name() != leases[0].Name() {
+			t.Errorf("leases of the same content have caches %s and %s", lease.Name(), leases[0].Name())
+		}
+	}
+	if !strings.HasPrefix(leases[0].Cache.DisplayName, "genai-cache-") {
+		t.Errorf("DisplayName = %q, want the genai-cache- prefix", leases[0].Cache.DisplayName)
+	}
+
+	// The cache is deleted with its last lease.
+	for _, lease := range leases[1:] {
+		if err := lease.Release(ctx); err != nil {
+			t.Fatalf("Release failed: %v", err)
+		}
+		// Releasing a lease again has no effect.
+		lease.Release(ctx)
+	}
+	if diff := cmp.Diff([]string{"cachedContents/1", "cachedContents/2"}, s.names()); diff != "" {
+		t.Errorf("caches mismatch (-want +got):\n%s", diff)
+	}
+	if err := leases[0].Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{other.Name()}, s.names()); diff != "" {
+		t.Errorf("caches mismatch after the last release (-want +got):\n%s", diff)
+	}
+}
+
+func TestCacheManagerReuse(t *testing.T) {
+	ctx := context.Background()
+	s := &testCachesServer{}
+	client := newTestCachesClient(t, s)
+	book := &CreateCachedContentConfig{Contents: Text("A long book."), SystemInstruction: Text("Be brief.")[0]}
+
+	// A cache kept by another manager, as by another process, is reused.
+	lease, err := client.Caches.NewManager(&CacheManagerConfig{KeepOnRelease: true}).Acquire(ctx, "gemini-2.5-flash", book)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	reused, err := client.Caches.NewManager(nil).Acquire(ctx, "gemini-2.5-flash", book)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if reused.Name() != lease.Name() || s.creates != 1 || s.updates != 1 {
+		t.Errorf("Acquire returned %s after %d creations and %d updates, want %s extended once", reused.Name(), s.creates, s.updates, lease.Name())
+	}
+	// The reused cache wasn't created by the manager, so it is kept.
+	if err := reused.Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{lease.Name()}, s.names()); diff != "" {
+		t.Errorf("caches mismatch after releasing a reused cache (-want +got):\n%s", diff)
+	}
+}
+
+func TestCacheManagerConcurrentRelease(t *testing.T) {
+	ctx := context.Background()
+	deleting, deleted := make(chan struct{}), make(chan struct{})
+	s := &testCachesServer{beforeDelete: func() {
+		close(deleting)
+		<-deleted
+	}}
+	client := newTestCachesClient(t, s)
+	finishDelete := sync.OnceFunc(func() { close(deleted) })
+	t.Cleanup(finishDelete)
+	cm := client.Caches.NewManager(nil)
+	book := &CreateCachedContentConfig{Contents: Text("A long book.")}
+
+	lease, err := cm.Acquire(ctx, "gemini-2.5-flash", book)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	released := make(chan error)
+	go func() { released <- lease.Release(ctx) }()
+
+	// A cache acquired while the last lease deletes the cache is not the
+	// deleted one.
+	<-deleting
+	acquired := make(chan *CacheLease)
+	go func() {
+		lease, err := cm.Acquire(ctx, "gemini-2.5-flash", book)
+		if err != nil {
+			t.Errorf("Acquire failed: %v", err)
+		}
+		acquired <- lease
+	}()
+	select {
+	case other := <-acquired:
+		t.Fatalf("Acquire returned %s while the cache was being deleted, want it to wait", other.Name())
+	case <-time.After(20 * time.Millisecond):
+	}
+	finishDelete()
+	if err := <-released; err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	other := <-acquired
+	if other == nil {
+		return
+	}
+	if diff := cmp.Diff([]string{other.Name()}, s.names()); other.Name() == lease.Name() || diff != "" {
+		t.Errorf("Acquire returned %s, want a new cache (-want +got):\n%s", other.Name(), diff)
+	}
+}
+
+func TestCacheManagerKeepalive(t *testing.T) {
+	ctx := context.Background()
+	s := &testCachesServer{}
+	client := newTestCachesClient(t, s)
+	cm := client.Caches.NewManager(&CacheManagerConfig{TTL: time.Minute, RefreshInterval: 5 * time.Millisecond})
+	lease, err := cm.Acquire(ctx, "gemini-2.5-flash", &CreateCachedContentConfig{Contents: Text("A long book.")})
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		s.mu.Lock()
+		updates := s.updates
+		s.mu.Unlock()
+		if updates >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the TTL was extended %d times, want at least 2", updates)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+}
+
+func TestCacheManagerSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := &testCachesServer{caches: map[string]*CachedContent{
+		"cachedContents/old":       {Name: "cachedContents/old", DisplayName: "genai-cache-1", CreateTime: now.Add(-2 * time.Hour), ExpireTime: now.Add(time.Hour)},
+		"cachedContents/young":     {Name: "cachedContents/young", DisplayName: "genai-cache-2", CreateTime: now.Add(150 * time.Minute), ExpireTime: now.Add(time.Hour)},
+		"cachedContents/unmanaged": {Name: "cachedContents/unmanaged", DisplayName: "mine", CreateTime: now.Add(-2 * time.Hour), ExpireTime: now.Add(time.Hour)},
+	}}
+	client := newTestCachesClient(t, s)
+	cm := client.Caches.NewManager(nil)
+	lease, err := cm.Acquire(ctx, "gemini-2.5-flash", &CreateCachedContentConfig{Contents: Text("A long book.")})
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer lease.Release(ctx)
+	// Three hours later, the leased cache is older than an hour too.
+	cm.now = func() time.Time { return now.Add(3 * time.Hour) }
+
+	summary, err := cm.Sweep(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"cachedContents/old"}, summary.Deleted); diff != "" {
+		t.Errorf("Sweep deleted mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{lease.Name(), "cachedContents/unmanaged", "cachedContents/young"}, s.names()); diff != "" {
+		t.Errorf("caches mismatch (-want +got):\n%s", diff)
+	}
+}
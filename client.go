@@ -122,6 +122,10 @@ type ClientConfig struct {
 	// Optional HTTP options to override.
 	HTTPOptions HTTPOptions
 
+	// Optional. Preview. Options for the WebSocket connections opened by
+	// [Live.Connect], such as timeouts and keepalive.
+	LiveOptions LiveOptions
+
 	envVarProvider func() map[string]string
 }
 
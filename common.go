@@ -476,6 +476,9 @@ func mergeHTTPOptions(clientConfig *ClientConfig, configHTTPOptions *HTTPOptions
 		if configHTTPOptions.ExtrasRequestProvider != nil {
 			result.ExtrasRequestProvider = configHTTPOptions.ExtrasRequestProvider
 		}
+		// The timeout of the client is applied when the request is sent, so only
+		// the timeout of the request is kept.
+		result.Timeout = configHTTPOptions.Timeout
 	}
 	result.Headers = mergeHeaders(clientHTTPOptions, configHTTPOptions)
 	return &result
@@ -17,6 +17,7 @@ package genai
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -101,6 +102,23 @@ func TestMergeHTTPOptions(t *testing.T) {
 				Headers:    http.Header{},
 			},
 		},
+		{
+			name: "request timeout is kept",
+			clientConfig: &ClientConfig{
+				HTTPOptions: HTTPOptions{
+					BaseURL: "https://client.com",
+					Timeout: Ptr(time.Minute),
+				},
+			},
+			requestHTTPOptions: &HTTPOptions{
+				Timeout: Ptr(time.Second),
+			},
+			want: &HTTPOptions{
+				BaseURL: "https://client.com",
+				Timeout: Ptr(time.Second),
+				Headers: http.Header{},
+			},
+		},
 		{
 			name: "client config only",
 			clientConfig: &ClientConfig{
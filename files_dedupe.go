@@ -0,0 +1,379 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultDedupeMinTTL is the default of [FileDeduplicatorConfig.MinTTL].
+const defaultDedupeMinTTL = time.Hour
+
+// UploadManifest stores the files uploaded by a [FileDeduplicator], keyed by
+// the hexadecimal SHA-256 hash of their data. Implementations must be safe for
+// concurrent use.
+type UploadManifest interface {
+	// Load returns the file stored for hash, or nil if there is none.
+	Load(ctx context.Context, hash string) (*File, error)
+	// Store stores file for hash.
+	Store(ctx context.Context, hash string, file *File) error
+	// Delete deletes the file stored for hash, if any.
+	Delete(ctx context.Context, hash string) error
+}
+
+// FileDeduplicatorConfig configures [Files.NewDeduplicator].
+type FileDeduplicatorConfig struct {
+	// Optional. Manifest stores the uploaded files. If nil, an in-memory
+	// manifest is used, which only deduplicates uploads of the same
+	// deduplicator.
+	Manifest UploadManifest
+	// Optional. ScanFiles enables looking up files missing from the manifest
+	// with [Files.All], which finds files uploaded by other processes at the
+	// cost of listing all files.
+	ScanFiles bool
+	// Optional. MinTTL is the minimum remaining lifetime of a file to be reused.
+	// Files that expire sooner are uploaded again. If zero, 1 hour is used.
+	MinTTL time.Duration
+}
+
+// FileDeduplicator uploads files like [Files.Upload] and
+// [Files.UploadFromPath], but reuses an existing unexpired file with the same
+// content instead of uploading it again. Files are identified by the SHA-256
+// hash of their data, computed locally. Files from the manifest are only
+// reused if [Files.Get] still finds them.
+type FileDeduplicator struct {
+	files     Files
+	manifest  UploadManifest
+	scanFiles bool
+	minTTL    time.Duration
+	now       func() time.Time
+}
+
+// NewDeduplicator returns a [FileDeduplicator] that uploads with m.
+func (m Files) NewDeduplicator(config *FileDeduplicatorConfig) *FileDeduplicator {
+	if config == nil {
+		config = &FileDeduplicatorConfig{}
+	}
+	d := &FileDeduplicator{
+		files:     m,
+		manifest:  config.Manifest,
+		scanFiles: config.ScanFiles,
+		minTTL:    config.MinTTL,
+		now:       time.Now,
+	}
+	if d.manifest == nil {
+		d.manifest = NewMemoryUploadManifest()
+	}
+	if d.minTTL == 0 {
+		d.minTTL = defaultDedupeMinTTL
+	}
+	return d
+}
+
+// Upload uploads the data of r like [Files.Upload], unless a file with the
+// same data and MIME type can be reused. Readers that don't implement
+// [io.Seeker] are read into memory to compute their hash.
+//
+// Files with a [UploadFileConfig.Name] are always uploaded.
+func (d *FileDeduplicator) Upload(ctx context.Context, r io.Reader, config *UploadFileConfig) (*File, error) {
+	if config != nil && config.Name != "" {
+		return d.files.Upload(ctx, r, config)
+	}
+	if s, ok := r.(io.ReadSeeker); ok {
+		start, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := hashReader(s)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return d.upload(ctx, hash, mimeTypeOf(config), func() (*File, error) {
+			return d.files.Upload(ctx, s, config)
+		})
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return d.upload(ctx, hex.EncodeToString(sum[:]), mimeTypeOf(config), func() (*File, error) {
+		return d.files.Upload(ctx, bytes.NewReader(data), config)
+	})
+}
+
+// UploadFromPath uploads the file at path like [Files.UploadFromPath], unless
+// a file with the same data and MIME type can be reused.
+func (d *FileDeduplicator) UploadFromPath(ctx context.Context, path string, config *UploadFileConfig) (*File, error) {
+	if config != nil && config.Name != "" {
+		return d.files.UploadFromPath(ctx, path, config)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hashReader(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	mimeType := mimeTypeOf(config)
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(path))
+	}
+	return d.upload(ctx, hash, mimeType, func() (*File, error) {
+		return d.files.UploadFromPath(ctx, path, config)
+	})
+}
+
+// upload returns a reusable file with the given hash and MIME type, or uploads
+// a new one and stores it in the manifest.
+func (d *FileDeduplicator) upload(ctx context.Context, hash, mimeType string, upload func() (*File, error)) (*File, error) {
+	file, err := d.manifest.Load(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upload manifest entry: %w", err)
+	}
+	stored := file != nil
+	if file != nil && d.reusable(file, mimeType) {
+		// The file may have been deleted since it was stored, so the manifest
+		// entry is only trusted if the file still exists.
+		file, err = d.refresh(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if file != nil && !d.reusable(file, mimeType) {
+		file = nil
+	}
+	if stored && file == nil {
+		if err := d.manifest.Delete(ctx, hash); err != nil {
+			return nil, fmt.Errorf("failed to delete upload manifest entry: %w", err)
+		}
+	}
+	if file == nil && d.scanFiles {
+		file, err = d.scan(ctx, hash, mimeType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if file == nil {
+		file, err = upload()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := d.manifest.Store(ctx, hash, file); err != nil {
+		return nil, fmt.Errorf("failed to store upload manifest entry: %w", err)
+	}
+	return file, nil
+}
+
+// refresh returns the current metadata of file, or nil if it was deleted.
+func (d *FileDeduplicator) refresh(ctx context.Context, file *File) (*File, error) {
+	current, err := d.files.Get(ctx, file.Name, nil)
+	var apiErr APIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file %s: %w", file.Name, err)
+	}
+	return current, nil
+}
+
+// scan looks up a reusable file with the given hash and MIME type in
+// [Files.All].
+func (d *FileDeduplicator) scan(ctx context.Context, hash, mimeType string) (*File, error) {
+	sum, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, err
+	}
+	for file, err := range d.files.All(ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		if file.Sha256Hash != "" && sha256Matches(file.Sha256Hash, sum) && d.reusable(file, mimeType) {
+			return file, nil
+		}
+	}
+	return nil, nil
+}
+
+// reusable reports whether file can be reused for data of the given MIME
+// type: it has the same MIME type, didn't fail processing and doesn't expire
+// within the minimum TTL.
+func (d *FileDeduplicator) reusable(file *File, mimeType string) bool {
+	if file.State == FileStateFailed {
+		return false
+	}
+	if mimeType != "" && file.MIMEType != "" && file.MIMEType != mimeType {
+		return false
+	}
+	return file.ExpirationTime.IsZero() || file.ExpirationTime.After(d.now().Add(d.minTTL))
+}
+
+// hashReader returns the hexadecimal SHA-256 hash of the data of r.
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to hash upload data: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func mimeTypeOf(config *UploadFileConfig) string {
+	if config == nil {
+		return ""
+	}
+	return config.MIMEType
+}
+
+// MemoryUploadManifest is an [UploadManifest] held in memory.
+type MemoryUploadManifest struct {
+	mu      sync.Mutex
+	entries map[string]*File
+	now     func() time.Time
+}
+
+// NewMemoryUploadManifest returns an empty [MemoryUploadManifest].
+func NewMemoryUploadManifest() *MemoryUploadManifest {
+	return &MemoryUploadManifest{entries: map[string]*File{}, now: time.Now}
+}
+
+// Load returns the file stored for hash, or nil if there is none or it
+// expired. Expired entries are dropped.
+func (m *MemoryUploadManifest) Load(ctx context.Context, hash string) (*File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dropExpired(m.entries, m.now())
+	return m.entries[hash], nil
+}
+
+// Store stores file for hash.
+func (m *MemoryUploadManifest) Store(ctx context.Context, hash string, file *File) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[hash] = file
+	return nil
+}
+
+// Delete deletes the file stored for hash.
+func (m *MemoryUploadManifest) Delete(ctx context.Context, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, hash)
+	return nil
+}
+
+// FileUploadManifest is an [UploadManifest] stored in a local JSON file, so
+// that uploads are deduplicated across runs. The file is read and rewritten on
+// each change; expired entries are dropped when it is rewritten.
+type FileUploadManifest struct {
+	path string
+	mu   sync.Mutex
+	now  func() time.Time
+}
+
+// NewFileUploadManifest returns a [FileUploadManifest] stored at path. The file
+// is created on the first change if it doesn't exist.
+func NewFileUploadManifest(path string) *FileUploadManifest {
+	return &FileUploadManifest{path: path, now: time.Now}
+}
+
+// Load returns the file stored for hash, or nil if there is none or it
+// expired.
+func (m *FileUploadManifest) Load(ctx context.Context, hash string) (*File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries, err := m.read()
+	if err != nil {
+		return nil, err
+	}
+	dropExpired(entries, m.now())
+	return entries[hash], nil
+}
+
+// Store stores file for hash.
+func (m *FileUploadManifest) Store(ctx context.Context, hash string, file *File) error {
+	return m.update(func(entries map[string]*File) { entries[hash] = file })
+}
+
+// Delete deletes the file stored for hash.
+func (m *FileUploadManifest) Delete(ctx context.Context, hash string) error {
+	return m.update(func(entries map[string]*File) { delete(entries, hash) })
+}
+
+func (m *FileUploadManifest) update(f func(entries map[string]*File)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries, err := m.read()
+	if err != nil {
+		return err
+	}
+	f(entries)
+	dropExpired(entries, m.now())
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a crash doesn't leave a
+	// truncated manifest.
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+func (m *FileUploadManifest) read() (map[string]*File, error) {
+	entries := map[string]*File{}
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid upload manifest %s: %w", m.path, err)
+	}
+	return entries, nil
+}
+
+// dropExpired deletes the entries of files that expired at now.
+func dropExpired(entries map[string]*File, now time.Time) {
+	for hash, file := range entries {
+		if file == nil || (!file.ExpirationTime.IsZero() && !file.ExpirationTime.After(now)) {
+			delete(entries, hash)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testDedupeNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestDedupeAPI returns a [testFilesAPI] where the nth uploaded file
+// expires n*48 hours after testDedupeNow.
+func newTestDedupeAPI(t *testing.T) *testFilesAPI {
+	s := newTestFilesAPI(t)
+	s.newFile = func(file *File) {
+		file.ExpirationTime = testDedupeNow.Add(time.Duration(s.starts) * 48 * time.Hour)
+	}
+	return s
+}
+
+func newTestDeduplicator(client *Client, config *FileDeduplicatorConfig, now *time.Time) *FileDeduplicator {
+	d := client.Files.NewDeduplicator(config)
+	d.now = func() time.Time { return *now }
+	if manifest, ok := d.manifest.(*MemoryUploadManifest); ok {
+		manifest.now = d.now
+	}
+	return d
+}
+
+func TestFileDeduplicator(t *testing.T) {
+	ctx := context.Background()
+	s := newTestDedupeAPI(t)
+	client := s.client()
+	now := testDedupeNow
+	d := newTestDeduplicator(client, nil, &now)
+	text := &UploadFileConfig{MIMEType: "text/plain"}
+
+	upload := func(r io.Reader, config *UploadFileConfig, wantName string, wantUploads int) {
+		t.Helper()
+		file, err := d.Upload(ctx, r, config)
+		if err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if file.Name != wantName || s.uploads != wantUploads {
+			t.Errorf("Upload returned %s after %d uploads, want %s after %d uploads", file.Name, s.uploads, wantName, wantUploads)
+		}
+	}
+	upload(strings.NewReader("report"), text, "files/1", 1)
+	// Readers are hashed from their current offset, seekable or not.
+	r := strings.NewReader("xxreport")
+	r.Seek(2, io.SeekStart)
+	upload(r, text, "files/1", 1)
+	upload(io.MultiReader(strings.NewReader("report")), text, "files/1", 1)
+	// The same data with another MIME type is uploaded again.
+	upload(strings.NewReader("report"), &UploadFileConfig{MIMEType: "text/csv"}, "files/2", 2)
+	// Named files are always uploaded.
+	upload(strings.NewReader("report"), &UploadFileConfig{MIMEType: "text/plain", Name: "report"}, "files/report", 3)
+	// Files that expire within the minimum TTL are uploaded again.
+	now = testDedupeNow.Add(47*time.Hour + 30*time.Minute)
+	upload(strings.NewReader("report"), text, "files/4", 4)
+	upload(strings.NewReader("report"), text, "files/4", 4)
+}
+
+func TestFileDeduplicatorDeletedFile(t *testing.T) {
+	ctx := context.Background()
+	s := newTestDedupeAPI(t)
+	client := s.client()
+	now := testDedupeNow
+	d := newTestDeduplicator(client, nil, &now)
+	text := &UploadFileConfig{MIMEType: "text/plain"}
+
+	if _, err := d.Upload(ctx, strings.NewReader("report"), text); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	// The file is deleted behind the manifest, so it is uploaded again.
+	if _, err := client.Files.Delete(ctx, "files/1", nil); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	for range 2 {
+		file, err := d.Upload(ctx, strings.NewReader("report"), text)
+		if err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if file.Name != "files/2" || s.uploads != 2 {
+			t.Errorf("Upload returned %s after %d uploads, want files/2 after 2 uploads", file.Name, s.uploads)
+		}
+	}
+}
+
+func TestFileDeduplicatorScanFiles(t *testing.T) {
+	ctx := context.Background()
+	sum := sha256.Sum256([]byte("image data"))
+	s := newTestDedupeAPI(t)
+	for _, file := range []*File{
+		{Name: "files/failed", MIMEType: "image/png", Sha256Hash: base64.StdEncoding.EncodeToString(sum[:]), State: FileStateFailed},
+		{Name: "files/other", MIMEType: "image/png", Sha256Hash: base64.StdEncoding.EncodeToString(make([]byte, 32))},
+		{Name: "files/existing", MIMEType: "image/png", Sha256Hash: base64.StdEncoding.EncodeToString(sum[:]), ExpirationTime: testDedupeNow.Add(24 * time.Hour)},
+	} {
+		s.files[file.Name] = file
+	}
+	client := s.client()
+	now := testDedupeNow
+	d := newTestDeduplicator(client, &FileDeduplicatorConfig{ScanFiles: true}, &now)
+
+	path := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(path, []byte("image data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := d.UploadFromPath(ctx, path, nil)
+	if err != nil {
+		t.Fatalf("UploadFromPath failed: %v", err)
+	}
+	if file.Name != "files/existing" || s.uploads != 0 {
+		t.Errorf("UploadFromPath returned %s after %d uploads, want files/existing without uploading", file.Name, s.uploads)
+	}
+}
+
+func TestFileUploadManifest(t *testing.T) {
+	ctx := context.Background()
+	s := newTestDedupeAPI(t)
+	client := s.client()
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	now := testDedupeNow
+
+	// The manifest deduplicates uploads across deduplicators, as across runs.
+	for range 2 {
+		manifest := NewFileUploadManifest(path)
+		manifest.now = func() time.Time { return now }
+		d := newTestDeduplicator(client, &FileDeduplicatorConfig{Manifest: manifest}, &now)
+		if _, err := d.Upload(ctx, strings.NewReader("data"), &UploadFileConfig{MIMEType: "text/plain"}); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+	}
+	if s.uploads != 1 {
+		t.Errorf("uploaded %d files, want 1", s.uploads)
+	}
+
+	// Expired entries are dropped.
+	manifest := NewFileUploadManifest(path)
+	manifest.now = func() time.Time { return testDedupeNow.Add(49 * time.Hour) }
+	if err := manifest.Store(ctx, "other", &File{Name: "files/other"}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries map[string]*File
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("invalid manifest: %v", err)
+	}
+	if len(entries) != 1 || entries["other"] == nil {
+		t.Errorf("manifest = %s, want only the unexpired entry", data)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrDownloadMismatch is returned when downloaded data doesn't match the size
+// or the SHA-256 hash of the [File] metadata.
+var ErrDownloadMismatch = errors.New("downloaded data doesn't match the file metadata")
+
+// DownloadToConfig configures [Files.DownloadTo]. It extends
+// [DownloadFileConfig] with the offset to resume a download from and a
+// progress callback.
+type DownloadToConfig struct {
+	DownloadFileConfig
+	// Optional. Offset is the number of bytes of the file that w already holds,
+	// to resume an interrupted download. The rest of the file is requested with
+	// an HTTP Range.
+	Offset int64
+	// Optional. OnProgress is called as the data of the download is received. It
+	// is called from the goroutine of the download.
+	OnProgress func(progress TransferProgress)
+}
+
+// DownloadMediaToConfig configures [FileSearchStores.DownloadMediaTo]. It
+// extends [DownloadMediaConfig] with a progress callback.
+type DownloadMediaToConfig struct {
+	DownloadMediaConfig
+	// Optional. OnProgress is called as the data of the download is received. It
+	// is called from the goroutine of the download.
+	OnProgress func(progress TransferProgress)
+}
+
+// DownloadTo streams the file at uri to w and returns the number of bytes
+// written. Unlike [Files.Download], the file is not held in memory, which
+// suits large files such as generated videos.
+//
+// If uri is a [File] with SizeBytes or Sha256Hash, the downloaded data is
+// verified against them and [ErrDownloadMismatch] is returned if it doesn't
+// match. The hash is only verified when the whole file is downloaded, that is
+// when [DownloadToConfig.Offset] is zero.
+//
+// Responses with an HTTP error status are returned as [APIError].
+func (m Files) DownloadTo(ctx context.Context, uri DownloadURI, w io.Writer, config *DownloadToConfig) (int64, error) {
+	if m.apiClient.clientConfig.Backend == BackendVertexAI {
+		return 0, fmt.Errorf("method DownloadTo is only supported in Gemini Developer API mode, not in Gemini Enterprise Agent Platform mode. You can choose to use Gemini Developer client by setting ClientConfig.Backend to BackendGeminiAPI.")
+	}
+	if uri.uri() == "" {
+		return 0, fmt.Errorf("the resource doesn't support download")
+	}
+	fileName, err := tFileName(uri.uri())
+	if err != nil {
+		return 0, err
+	}
+	path := fmt.Sprintf("files/%s:download?alt=media", fileName)
+
+	if config == nil {
+		config = &DownloadToConfig{}
+	}
+	offset := config.Offset
+	if offset < 0 {
+		return 0, fmt.Errorf("download offset must not be negative, got %d", offset)
+	}
+	httpOptions := mergeHTTPOptions(m.apiClient.clientConfig, config.HTTPOptions)
+
+	file, _ := uri.(*File)
+	var hasher hash.Hash
+	if file != nil && file.Sha256Hash != "" && offset == 0 {
+		hasher = sha256.New()
+		w = io.MultiWriter(w, hasher)
+	}
+	n, err := downloadFileTo(ctx, m.apiClient, path, httpOptions, w, offset, config.OnProgress)
+	if err != nil {
+		return n, err
+	}
+	if file != nil && file.SizeBytes != nil && offset+n != *file.SizeBytes {
+		return n, fmt.Errorf("%w: downloaded %d bytes, want %d", ErrDownloadMismatch, offset+n, *file.SizeBytes)
+	}
+	if hasher != nil && !sha256Matches(file.Sha256Hash, hasher.Sum(nil)) {
+		return n, fmt.Errorf("%w: SHA-256 hash is %x, want %s", ErrDownloadMismatch, hasher.Sum(nil), file.Sha256Hash)
+	}
+	return n, nil
+}
+
+// DownloadToPath downloads the file at uri to the local file at path, like
+// [Files.DownloadTo]. The data is written to a temporary file in the same
+// directory, which is renamed to path once the download is complete and
+// verified, so path never holds a partial download.
+func (m Files) DownloadToPath(ctx context.Context, uri DownloadURI, path string, config *DownloadFileConfig) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	downloadConfig := &DownloadToConfig{}
+	if config != nil {
+		downloadConfig.DownloadFileConfig = *config
+	}
+	_, err = m.DownloadTo(ctx, uri, f, downloadConfig)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// sha256Matches reports whether want, a SHA-256 hash from the file metadata,
+// is sum. The API encodes hashes in base64, of either the raw or the
+// hexadecimal digest; plain hexadecimal digests are accepted too.
+func sha256Matches(want string, sum []byte) bool {
+	hexSum := hex.EncodeToString(sum)
+	if strings.EqualFold(want, hexSum) {
+		return true
+	}
+	decoded, err := base64.StdEncoding.DecodeString(want)
+	if err != nil {
+		decoded, err = base64.URLEncoding.DecodeString(want)
+	}
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(decoded, sum) || strings.EqualFold(string(decoded), hexSum)
+}
+
+// DownloadMediaTo streams the media at uri to w and returns the number of bytes
+// written, like [FileSearchStores.DownloadMedia] without holding the media in
+// memory. Responses with an HTTP error status are returned as [APIError].
+func (m FileSearchStores) DownloadMediaTo(ctx context.Context, uri string, w io.Writer, config *DownloadMediaToConfig) (int64, error) {
+	if m.apiClient.clientConfig.Backend == BackendVertexAI {
+		return 0, fmt.Errorf("method DownloadMediaTo is only supported in the Gemini Developer client. You can choose to use Gemini Developer client by setting ClientConfig.Backend to BackendGeminiAPI.")
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse uri: %w", err)
+	}
+	path := strings.TrimPrefix(u.Path, "/")
+	if !strings.Contains(path, "/media/") {
+		return 0, fmt.Errorf("invalid uri format: %s, expected to contain /media/", uri)
+	}
+	q := u.Query()
+	q.Set("alt", "media")
+	path = fmt.Sprintf("%s?%s", path, q.Encode())
+
+	var configHTTPOptions *HTTPOptions
+	var onProgress func(TransferProgress)
+	if config != nil {
+		configHTTPOptions, onProgress = config.HTTPOptions, config.OnProgress
+	}
+	httpOptions := mergeHTTPOptions(m.apiClient.clientConfig, configHTTPOptions)
+	return downloadFileTo(ctx, m.apiClient, path, httpOptions, w, 0, onProgress)
+}
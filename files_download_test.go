@@ -0,0 +1,165 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestDownloadClient returns a client of a server that serves data at
+// files/ranged with support for ranges, at files/plain without, and a 404
+// error for other files.
+func newTestDownloadClient(t *testing.T, data []byte) (*Client, *[]string) {
+	t.Helper()
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/ranged:download"), strings.Contains(r.URL.Path, "/media/"):
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+		case strings.HasSuffix(r.URL.Path, "/plain:download"):
+			w.Write(data)
+		default:
+			http.Error(w, `{"error": {"code": 404, "message": "File not found.", "status": "NOT_FOUND"}}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	client, err := NewClient(context.Background(), &ClientConfig{
+		Backend:     BackendGeminiAPI,
+		APIKey:      "test-api-key",
+		HTTPOptions: HTTPOptions{BaseURL: ts.URL},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return client, &ranges
+}
+
+func TestDownloadTo(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	sum := sha256.Sum256(data)
+	size := int64(len(data))
+	otherSize := size + 1
+
+	tests := []struct {
+		name      string
+		file      *File
+		offset    int64
+		wantRange string
+		wantErr   error
+	}{
+		{name: "Hex", file: &File{DownloadURI: "files/ranged", SizeBytes: &size, Sha256Hash: hex.EncodeToString(sum[:])}},
+		{name: "Base64", file: &File{DownloadURI: "files/ranged", Sha256Hash: base64.StdEncoding.EncodeToString(sum[:])}},
+		{name: "Base64Hex", file: &File{DownloadURI: "files/ranged", Sha256Hash: base64.StdEncoding.EncodeToString([]byte(hex.EncodeToString(sum[:])))}},
+		{name: "Resume", file: &File{DownloadURI: "files/ranged", SizeBytes: &size}, offset: 1234, wantRange: "bytes=1234-"},
+		{name: "ResumeIgnoredRange", file: &File{DownloadURI: "files/plain", SizeBytes: &size}, offset: 1234, wantRange: "bytes=1234-"},
+		{name: "SizeMismatch", file: &File{DownloadURI: "files/ranged", SizeBytes: &otherSize}, wantErr: ErrDownloadMismatch},
+		{name: "HashMismatch", file: &File{DownloadURI: "files/ranged", Sha256Hash: hex.EncodeToString(make([]byte, 32))}, wantErr: ErrDownloadMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, ranges := newTestDownloadClient(t, data)
+			var buf bytes.Buffer
+			buf.Write(data[:tt.offset])
+			n, err := client.Files.DownloadTo(ctx, tt.file, &buf, &DownloadToConfig{Offset: tt.offset})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("DownloadTo error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DownloadTo failed: %v", err)
+			}
+			if n != size-tt.offset || !bytes.Equal(buf.Bytes(), data) {
+				t.Errorf("DownloadTo wrote %d bytes, want %d bytes that complete the file", n, size-tt.offset)
+			}
+			if (*ranges)[0] != tt.wantRange {
+				t.Errorf("Range = %q, want %q", (*ranges)[0], tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestDownloadErrors(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestDownloadClient(t, []byte("data"))
+	missing := &File{DownloadURI: "files/missing"}
+
+	var apiErr APIError
+	if _, err := client.Files.DownloadTo(ctx, missing, &bytes.Buffer{}, nil); !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		t.Errorf("DownloadTo error = %v, want an APIError with code 404", err)
+	}
+	// Download used to return the error page as the file.
+	if _, err := client.Files.Download(ctx, missing, nil); !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		t.Errorf("Download error = %v, want an APIError with code 404", err)
+	}
+}
+
+func TestDownloadToPath(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("generated video")
+	client, _ := newTestDownloadClient(t, data)
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "video.mp4")
+	video := &Video{URI: "https://generativelanguage.googleapis.com/v1beta/files/ranged:download?alt=media"}
+	if err := client.Files.DownloadToPath(ctx, video, path, nil); err != nil {
+		t.Fatalf("DownloadToPath failed: %v", err)
+	}
+	if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadFile(%s) = %q, %v, want %q", path, got, err, data)
+	}
+
+	// A failed download leaves nothing behind.
+	wrongSize := int64(1)
+	failed := filepath.Join(dir, "failed.mp4")
+	if err := client.Files.DownloadToPath(ctx, &File{DownloadURI: "files/ranged", SizeBytes: &wrongSize}, failed, nil); !errors.Is(err, ErrDownloadMismatch) {
+		t.Errorf("DownloadToPath error = %v, want %v", err, ErrDownloadMismatch)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries after a failed download, want only the first video", len(entries))
+	}
+}
+
+func TestDownloadMediaTo(t *testing.T) {
+	data := []byte("document")
+	client, _ := newTestDownloadClient(t, data)
+	var buf bytes.Buffer
+	uri := "https://generativelanguage.googleapis.com/v1beta/fileSearchStores/store/media/doc"
+	if _, err := client.FileSearchStores.DownloadMediaTo(context.Background(), uri, &buf, nil); err != nil {
+		t.Fatalf("DownloadMediaTo failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("DownloadMediaTo wrote %q, want %q", buf.Bytes(), data)
+	}
+}
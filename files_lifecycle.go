@@ -0,0 +1,278 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWaitActiveInterval    = time.Second
+	defaultWaitActiveMaxInterval = 10 * time.Second
+	defaultFilesConcurrency      = 4
+)
+
+// FileFailedError is returned by [Files.WaitActive] when the processing of a
+// file failed.
+type FileFailedError struct {
+	// File is the failed file. Its Error field holds the processing error, if
+	// the API returned one.
+	File *File
+}
+
+// Error returns a description of the processing error.
+func (e *FileFailedError) Error() string {
+	if e.File.Error == nil {
+		return fmt.Sprintf("file %s failed processing", e.File.Name)
+	}
+	code := int32(0)
+	if e.File.Error.Code != nil {
+		code = *e.File.Error.Code
+	}
+	return fmt.Sprintf("file %s failed processing: %s (code %d)", e.File.Name, e.File.Error.Message, code)
+}
+
+// WaitActiveConfig configures [Files.WaitActive].
+type WaitActiveConfig struct {
+	// Optional. Used to override HTTP request options.
+	HTTPOptions *HTTPOptions
+	// Optional. Interval is the initial delay between two polls of the file. It
+	// doubles after each poll, up to MaxInterval. If zero, 1 second is used.
+	Interval time.Duration
+	// Optional. MaxInterval is the maximum delay between two polls. If zero, 10
+	// seconds is used.
+	MaxInterval time.Duration
+}
+
+// WaitActive polls the file with the given name until it leaves the
+// PROCESSING state, and returns it once it is ACTIVE. If the processing
+// failed, a [*FileFailedError] with the file and its [FileStatus] is returned.
+//
+// WaitActive waits until ctx is done; use a context with a deadline to bound
+// the wait.
+func (m Files) WaitActive(ctx context.Context, name string, config *WaitActiveConfig) (*File, error) {
+	if config == nil {
+		config = &WaitActiveConfig{}
+	}
+	interval, maxInterval := config.Interval, config.MaxInterval
+	if interval <= 0 {
+		interval = defaultWaitActiveInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = defaultWaitActiveMaxInterval
+	}
+	for {
+		file, err := m.Get(ctx, name, &GetFileConfig{HTTPOptions: config.HTTPOptions})
+		if err != nil {
+			return nil, err
+		}
+		switch file.State {
+		case FileStateActive:
+			return file, nil
+		case FileStateFailed:
+			return nil, &FileFailedError{File: file}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("file %s is still %s: %w", name, file.State, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval = min(2*interval, maxInterval)
+	}
+}
+
+// UploadDirConfig configures [Files.UploadDir].
+type UploadDirConfig struct {
+	// Optional. Concurrency is the maximum number of concurrent uploads. If
+	// zero, 4 is used.
+	Concurrency int
+	// Optional. Filter reports whether to upload the file at path, relative to
+	// the directory. If nil, all regular files are uploaded, recursively.
+	Filter func(path string, entry fs.DirEntry) bool
+	// Optional. FileConfig returns the upload config of the file at path,
+	// relative to the directory. If nil, the MIME type is inferred from the file
+	// extension and the display name is the relative path.
+	FileConfig func(path string) *UploadFileConfig
+	// Optional. If not nil, UploadDir waits for each uploaded file to be ACTIVE
+	// with [Files.WaitActive], using this config.
+	WaitActive *WaitActiveConfig
+}
+
+// UploadDirResult is the result of the upload of a file by [Files.UploadDir].
+type UploadDirResult struct {
+	// Path is the path of the file, relative to the directory.
+	Path string
+	// File is the uploaded file, or nil if the upload failed.
+	File *File
+	// Err is the error of the upload, if it failed.
+	Err error
+}
+
+// UploadDir uploads the files of the directory dir and its subdirectories,
+// with up to [UploadDirConfig.Concurrency] uploads at a time.
+//
+// It returns a result for each file, sorted by path, even if some uploads
+// failed. The returned error joins the errors of the failed uploads; it is nil
+// if all uploads succeeded.
+func (m Files) UploadDir(ctx context.Context, dir string, config *UploadDirConfig) ([]*UploadDirResult, error) {
+	if config == nil {
+		config = &UploadDirConfig{}
+	}
+	var results []*UploadDirResult
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if config.Filter == nil || config.Filter(rel, entry) {
+			results = append(results, &UploadDirResult{Path: rel})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	forEachConcurrently(config.Concurrency, results, func(result *UploadDirResult) {
+		var fileConfig *UploadFileConfig
+		if config.FileConfig != nil {
+			fileConfig = config.FileConfig(result.Path)
+		} else {
+			fileConfig = &UploadFileConfig{DisplayName: result.Path}
+		}
+		file, err := m.UploadFromPath(ctx, filepath.Join(dir, filepath.FromSlash(result.Path)), fileConfig)
+		if err == nil && config.WaitActive != nil {
+			file, err = m.WaitActive(ctx, file.Name, config.WaitActive)
+		}
+		if err != nil {
+			result.Err = fmt.Errorf("failed to upload %s: %w", result.Path, err)
+			return
+		}
+		result.File = file
+	})
+
+	var errs []error
+	for _, result := range results {
+		errs = append(errs, result.Err)
+	}
+	return results, errors.Join(errs...)
+}
+
+// DeleteWhereConfig configures [Files.DeleteWhere].
+type DeleteWhereConfig struct {
+	// Optional. Concurrency is the maximum number of concurrent deletions. If
+	// zero, 4 is used.
+	Concurrency int
+	// Optional. DryRun reports the files that match without deleting them.
+	DryRun bool
+}
+
+// DeleteSummary reports the files deleted by [Files.DeleteWhere].
+type DeleteSummary struct {
+	// Deleted is the names of the deleted files, sorted. With
+	// [DeleteWhereConfig.DryRun], it is the names of the files that would be
+	// deleted.
+	Deleted []string
+	// Failed maps the names of the files that couldn't be deleted to their
+	// errors.
+	Failed map[string]error
+}
+
+// DeleteWhere deletes the files of [Files.All] for which match returns true,
+// with up to [DeleteWhereConfig.Concurrency] deletions at a time. All files
+// are listed before any is deleted, and none is deleted if the listing fails.
+// match is required.
+//
+// The returned error joins the errors of the failed deletions; the summary is
+// returned even if it isn't nil.
+func (m Files) DeleteWhere(ctx context.Context, match func(file *File) bool, config *DeleteWhereConfig) (*DeleteSummary, error) {
+	if match == nil {
+		return nil, fmt.Errorf("match function is required")
+	}
+	if config == nil {
+		config = &DeleteWhereConfig{}
+	}
+	summary := &DeleteSummary{Failed: map[string]error{}}
+	var names []string
+	for file, err := range m.All(ctx) {
+		if err != nil {
+			return summary, fmt.Errorf("failed to list files: %w", err)
+		}
+		if match(file) {
+			names = append(names, file.Name)
+		}
+	}
+	if config.DryRun {
+		slices.Sort(names)
+		summary.Deleted = names
+		return summary, nil
+	}
+
+	var mu sync.Mutex
+	forEachConcurrently(config.Concurrency, names, func(name string) {
+		_, err := m.Delete(ctx, name, nil)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			summary.Failed[name] = err
+			return
+		}
+		summary.Deleted = append(summary.Deleted, name)
+	})
+	slices.Sort(summary.Deleted)
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(summary.Failed)) {
+		errs = append(errs, fmt.Errorf("failed to delete %s: %w", name, summary.Failed[name]))
+	}
+	return summary, errors.Join(errs...)
+}
+
+// forEachConcurrently calls f for each item, with at most concurrency calls
+// at a time, and returns once all calls returned.
+func forEachConcurrently[T any](concurrency int, items []T, f func(T)) {
+	if concurrency <= 0 {
+		concurrency = defaultFilesConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			f(item)
+		}()
+	}
+	wg.Wait()
+}
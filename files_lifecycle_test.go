@@ -0,0 +1,172 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testLifecycleServer is a [testFilesAPI] where each file is PROCESSING for
+// the first processingPolls gets, then ACTIVE, or FAILED if its display name
+// contains "bad".
+type testLifecycleServer struct {
+	*testFilesAPI
+	polls           map[string]int
+	processingPolls int
+	deleteFails     map[string]bool
+}
+
+func newTestLifecycleClient(t *testing.T, s *testLifecycleServer) *Client {
+	t.Helper()
+	s.testFilesAPI, s.polls = newTestFilesAPI(t), map[string]int{}
+	s.newFile = func(file *File) { file.State = FileStateProcessing }
+	s.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		name := "files/" + strings.TrimPrefix(r.URL.Path, "/v1beta/files/")
+		file := s.files[name]
+		switch {
+		case file == nil:
+		case r.Method == http.MethodDelete && s.deleteFails[name]:
+			http.Error(w, `{"error": {"code": 403, "message": "Permission denied.", "status": "PERMISSION_DENIED"}}`, http.StatusForbidden)
+			return true
+		case r.Method == http.MethodGet:
+			if s.polls[name]++; s.polls[name] > s.processingPolls {
+				file.State = FileStateActive
+				if strings.Contains(file.DisplayName, "bad") {
+					file.State = FileStateFailed
+					file.Error = &FileStatus{Message: "unsupported video codec", Code: Ptr[int32](3)}
+				}
+			}
+		}
+		return false
+	}
+	return s.client()
+}
+
+func TestWaitActive(t *testing.T) {
+	ctx := context.Background()
+	s := &testLifecycleServer{processingPolls: 3}
+	client := newTestLifecycleClient(t, s)
+	s.files["files/video"] = &File{Name: "files/video", DisplayName: "video", State: FileStateProcessing}
+	s.files["files/bad"] = &File{Name: "files/bad", DisplayName: "bad", State: FileStateProcessing}
+	config := &WaitActiveConfig{Interval: time.Millisecond}
+
+	file, err := client.Files.WaitActive(ctx, "files/video", config)
+	if err != nil {
+		t.Fatalf("WaitActive failed: %v", err)
+	}
+	if file.State != FileStateActive || s.polls["files/video"] != 4 {
+		t.Errorf("WaitActive returned a %s file after %d polls, want an ACTIVE file after 4 polls", file.State, s.polls["files/video"])
+	}
+
+	_, err = client.Files.WaitActive(ctx, "bad", config)
+	var failedErr *FileFailedError
+	if !errors.As(err, &failedErr) || failedErr.File.Error.Message != "unsupported video codec" {
+		t.Fatalf("WaitActive error = %v, want a FileFailedError with the file status", err)
+	}
+
+	s.processingPolls = 1000
+	s.files["files/slow"] = &File{Name: "files/slow", State: FileStateProcessing}
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.Files.WaitActive(ctx, "files/slow", config); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitActive error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestUploadDir(t *testing.T) {
+	ctx := context.Background()
+	s := &testLifecycleServer{processingPolls: 1}
+	client := newTestLifecycleClient(t, s)
+	dir := t.TempDir()
+	for _, path := range []string{"a.txt", "sub/b.txt", "sub/bad.txt", "skip.log"} {
+		path = filepath.Join(dir, path)
+		os.MkdirAll(filepath.Dir(path), 0o700)
+		if err := os.WriteFile(path, []byte(path), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := client.Files.UploadDir(ctx, dir, &UploadDirConfig{
+		Concurrency: 2,
+		Filter:      func(path string, _ fs.DirEntry) bool { return filepath.Ext(path) == ".txt" },
+		WaitActive:  &WaitActiveConfig{Interval: time.Millisecond},
+	})
+	var failedErr *FileFailedError
+	if !errors.As(err, &failedErr) {
+		t.Errorf("UploadDir error = %v, want a FileFailedError", err)
+	}
+	var got []string
+	for _, result := range results {
+		state := "error"
+		if result.File != nil {
+			state = string(result.File.State)
+		}
+		got = append(got, result.Path+" "+state)
+	}
+	want := []string{"a.txt ACTIVE", "sub/b.txt ACTIVE", "sub/bad.txt error"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("UploadDir results mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	ctx := context.Background()
+	s := &testLifecycleServer{deleteFails: map[string]bool{"files/locked-tmp": true}}
+	client := newTestLifecycleClient(t, s)
+	for _, name := range []string{"files/keep", "files/a-tmp", "files/b-tmp", "files/locked-tmp"} {
+		s.files[name] = &File{Name: name}
+	}
+	isTmp := func(file *File) bool { return strings.HasSuffix(file.Name, "-tmp") }
+
+	summary, err := client.Files.DeleteWhere(ctx, isTmp, &DeleteWhereConfig{DryRun: true})
+	if err != nil || len(s.files) != 4 {
+		t.Fatalf("DeleteWhere with DryRun = %v, left %d files, want no error and 4 files", err, len(s.files))
+	}
+	if diff := cmp.Diff([]string{"files/a-tmp", "files/b-tmp", "files/locked-tmp"}, summary.Deleted); diff != "" {
+		t.Errorf("DeleteWhere with DryRun mismatch (-want +got):\n%s", diff)
+	}
+
+	summary, err = client.Files.DeleteWhere(ctx, isTmp, nil)
+	var apiErr APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		t.Errorf("DeleteWhere error = %v, want an APIError with code 403", err)
+	}
+	if diff := cmp.Diff([]string{"files/a-tmp", "files/b-tmp"}, summary.Deleted); diff != "" {
+		t.Errorf("DeleteWhere deleted mismatch (-want +got):\n%s", diff)
+	}
+	if summary.Failed["files/locked-tmp"] == nil {
+		t.Errorf("DeleteWhere failed = %v, want files/locked-tmp", summary.Failed)
+	}
+	remaining := slices.Sorted(maps.Keys(s.files))
+	if diff := cmp.Diff([]string{"files/keep", "files/locked-tmp"}, remaining); diff != "" {
+		t.Errorf("remaining files mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := client.Files.DeleteWhere(ctx, nil, nil); err == nil || len(s.files) != 2 {
+		t.Errorf("DeleteWhere without a match function = %v, left %d files, want an error and 2 files", err, len(s.files))
+	}
+}
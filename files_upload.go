@@ -0,0 +1,318 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// UploadSession is the state of a resumable upload. It can be persisted, for
+// example as JSON, to resume the upload with [Files.ResumeUpload] after a
+// failure or a restart of the process.
+type UploadSession struct {
+	// URL is the upload URL returned when the file was created.
+	URL string `json:"url"`
+	// Offset is the number of bytes committed by the server.
+	Offset int64 `json:"offset"`
+}
+
+// UploadError is the error returned when an upload fails after the file was
+// created. The upload can be resumed from Session with [Files.ResumeUpload].
+type UploadError struct {
+	// Session is the upload session, with the offset committed before the
+	// failure.
+	Session UploadSession
+	// Err is the cause of the failure.
+	Err error
+}
+
+// Error returns the message of the cause of the failure.
+func (e *UploadError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause of the failure.
+func (e *UploadError) Unwrap() error {
+	return e.Err
+}
+
+// ResumeUploadConfig configures [Files.ResumeUpload].
+type ResumeUploadConfig struct {
+	// Optional. Used to override HTTP request options.
+	HTTPOptions *HTTPOptions
+	// Optional. OnCommit is called with the updated session after each chunk
+	// committed by the server, so that the session can be persisted.
+	OnCommit func(session UploadSession)
+	UploadTransferConfig
+}
+
+// UploadWithTransferConfig configures [Files.UploadWithTransfer]. It extends
+// [UploadFileConfig] with the options of the transfer of the data.
+type UploadWithTransferConfig struct {
+	UploadFileConfig
+	UploadTransferConfig
+}
+
+// UploadToFileSearchStoreWithTransferConfig configures
+// [FileSearchStores.UploadToFileSearchStoreWithTransfer]. It extends
+// [UploadToFileSearchStoreConfig] with the options of the transfer of the
+// data.
+type UploadToFileSearchStoreWithTransferConfig struct {
+	UploadToFileSearchStoreConfig
+	UploadTransferConfig
+}
+
+// UploadWithTransfer uploads the data of r like [Files.Upload], with control
+// over the transfer: the chunk size, the timeouts and a progress callback.
+// The size of the file is declared to the server when r reports it, such as
+// [bytes.Reader] and [os.File].
+func (m Files) UploadWithTransfer(ctx context.Context, r io.Reader, config *UploadWithTransferConfig) (*File, error) {
+	if config == nil {
+		config = &UploadWithTransferConfig{}
+	}
+	options, err := config.uploadOptions()
+	if err != nil {
+		return nil, err
+	}
+	size := uploadSize(r, config.HTTPOptions)
+	session, err := m.StartUpload(ctx, size, &config.UploadFileConfig)
+	if err != nil {
+		return nil, err
+	}
+	return m.continueUpload(ctx, *session, r, size, config.HTTPOptions, options)
+}
+
+// StartUpload creates a file and returns the session to upload its data with
+// [Files.ResumeUpload], without uploading any data. size is the size of the
+// file in bytes, or -1 if it is unknown.
+//
+// Unlike [Files.Upload], the session can be persisted before the upload
+// starts, so that the upload can be resumed after the process restarts.
+func (m Files) StartUpload(ctx context.Context, size int64, config *UploadFileConfig) (*UploadSession, error) {
+	if m.apiClient.clientConfig.Backend == BackendVertexAI {
+		return nil, fmt.Errorf("This method is only supported in Gemini Developer API mode, not in Gemini Enterprise Agent Platform mode.")
+	}
+
+	var fileToUpload File
+	if config != nil {
+		fileToUpload.MIMEType = config.MIMEType
+		fileToUpload.Name = config.Name
+		fileToUpload.DisplayName = config.DisplayName
+	}
+	if fileToUpload.Name != "" && !strings.HasPrefix(fileToUpload.Name, "files/") {
+		fileToUpload.Name = "files/" + fileToUpload.Name
+	}
+
+	var configHTTPOptions *HTTPOptions
+	if config != nil {
+		configHTTPOptions = config.HTTPOptions
+	}
+	httpOptions := copyUploadHTTPOptions(configHTTPOptions)
+	httpOptions.APIVersion = ""
+	httpOptions.Headers.Add("Content-Type", "application/json")
+	httpOptions.Headers.Add("X-Goog-Upload-Protocol", "resumable")
+	httpOptions.Headers.Add("X-Goog-Upload-Command", "start")
+	httpOptions.Headers.Add("X-Goog-Upload-Header-Content-Type", fileToUpload.MIMEType)
+	if size >= 0 {
+		httpOptions.Headers.Set("X-Goog-Upload-Header-Content-Length", strconv.FormatInt(size, 10))
+	}
+
+	resp, err := m.create(ctx, &fileToUpload, &CreateFileConfig{HTTPOptions: &httpOptions, ShouldReturnHTTPResponse: true})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create file. Ran into an error: %w", err)
+	}
+	if resp.SDKHTTPResponse == nil || resp.SDKHTTPResponse.Headers == nil {
+		return nil, fmt.Errorf("Failed to create file. Upload URL was not returned from the create file request.")
+	}
+	uploadURL := resp.SDKHTTPResponse.Headers.Get("X-Goog-Upload-Url")
+	if uploadURL == "" {
+		return nil, fmt.Errorf("Failed to create file. Upload URL was not returned from the create file request.")
+	}
+	return &UploadSession{URL: uploadURL}, nil
+}
+
+// ResumeUpload uploads the data of a file to an upload session created by
+// [Files.StartUpload], or returned in an [UploadError] by [Files.Upload] or
+// [Files.UploadFromPath].
+//
+// The server is asked for the offset it committed, which takes precedence
+// over session.Offset, and the upload continues from there. r must read the
+// file from its start: if r implements [io.ReaderAt] or [io.Seeker], such as
+// [os.File], the committed data is skipped without being read; otherwise it is
+// read and discarded.
+//
+// If the server already finalized the upload, the file is returned without
+// uploading anything.
+func (m Files) ResumeUpload(ctx context.Context, session UploadSession, r io.Reader, config *ResumeUploadConfig) (*File, error) {
+	if session.URL == "" {
+		return nil, fmt.Errorf("upload session URL is required")
+	}
+	if config == nil {
+		config = &ResumeUploadConfig{}
+	}
+	options, err := config.uploadOptions()
+	if err != nil {
+		return nil, err
+	}
+	if config.OnCommit != nil {
+		options.onCommit = func(offset int64) {
+			config.OnCommit(UploadSession{URL: session.URL, Offset: offset})
+		}
+	}
+	httpOptions := copyUploadHTTPOptions(config.HTTPOptions)
+
+	status, offset, respBody, err := m.apiClient.queryUpload(ctx, session.URL, &httpOptions)
+	if err != nil {
+		return nil, &UploadError{Session: session, Err: err}
+	}
+	session.Offset = offset
+	switch status {
+	case "final":
+		return uploadedFile(respBody)
+	case "active":
+	default:
+		return nil, fmt.Errorf("upload session can't be resumed, upload status: %s", status)
+	}
+
+	total := uploadSize(r, &httpOptions)
+	r, err = skipUploaded(r, offset)
+	if err != nil {
+		return nil, &UploadError{Session: session, Err: err}
+	}
+	return m.continueUpload(ctx, session, r, total, config.HTTPOptions, options)
+}
+
+// continueUpload uploads the data of r to session, from session.Offset, and
+// returns the uploaded file. r must read the data after session.Offset, and
+// total is the size of the whole file, or -1 if it is unknown.
+func (m Files) continueUpload(ctx context.Context, session UploadSession, r io.Reader, total int64, configHTTPOptions *HTTPOptions, options *uploadOptions) (*File, error) {
+	httpOptions := copyUploadHTTPOptions(configHTTPOptions)
+	respBody, err := m.apiClient.uploadFrom(ctx, r, session.URL, session.Offset, total, &httpOptions, options)
+	if err != nil {
+		return nil, err
+	}
+	return uploadedFile(respBody)
+}
+
+// copyUploadHTTPOptions returns a copy of the HTTP options of an upload
+// config, which may be nil, with non-nil headers.
+func copyUploadHTTPOptions(configHTTPOptions *HTTPOptions) HTTPOptions {
+	httpOptions := HTTPOptions{Headers: http.Header{}}
+	if configHTTPOptions != nil {
+		deepCopy(*configHTTPOptions, &httpOptions)
+	}
+	if httpOptions.Headers == nil {
+		httpOptions.Headers = http.Header{}
+	}
+	return httpOptions
+}
+
+// skipUploaded returns a reader of the data of r after offset.
+func skipUploaded(r io.Reader, offset int64) (io.Reader, error) {
+	if ra, ok := r.(io.ReaderAt); ok {
+		return io.NewSectionReader(ra, offset, math.MaxInt64-offset), nil
+	}
+	if s, ok := r.(io.Seeker); ok {
+		if _, err := s.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek to the committed offset %d: %w", offset, err)
+		}
+		return r, nil
+	}
+	n, err := io.CopyN(io.Discard, r, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to skip the committed %d bytes, skipped %d: %w", offset, n, err)
+	}
+	return r, nil
+}
+
+// uploadedFile returns the file of the response body of a finalized upload.
+func uploadedFile(respBody map[string]any) (*File, error) {
+	fileMap, ok := respBody["file"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("upload completed but response body has no file")
+	}
+	var file = new(File)
+	if err := mapToStruct(fileMap, &file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// UploadToFileSearchStoreWithTransfer uploads the data of r to a file search
+// store like [FileSearchStores.UploadToFileSearchStore], with control over the
+// transfer: the chunk size, the timeouts and a progress callback.
+func (m FileSearchStores) UploadToFileSearchStoreWithTransfer(ctx context.Context, r io.Reader, fileSearchStoreName string, config *UploadToFileSearchStoreWithTransferConfig) (*UploadToFileSearchStoreOperation, error) {
+	if m.apiClient.clientConfig.Backend == BackendVertexAI {
+		return nil, fmt.Errorf("This method is only supported in the Gemini Developer client.")
+	}
+	if config == nil || config.MIMEType == "" {
+		return nil, fmt.Errorf("MIMEType is required but was not provided. Please set the `MIMEType` in the config")
+	}
+	options, err := config.uploadOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	httpOptions := copyUploadHTTPOptions(config.HTTPOptions)
+	httpOptions.APIVersion = ""
+	httpOptions.Headers.Add("Content-Type", "application/json")
+	httpOptions.Headers.Add("X-Goog-Upload-Protocol", "resumable")
+	httpOptions.Headers.Add("X-Goog-Upload-Command", "start")
+	httpOptions.Headers.Add("X-Goog-Upload-Header-Content-Type", config.MIMEType)
+	if size := uploadSize(r, &httpOptions); size >= 0 {
+		httpOptions.Headers.Set("X-Goog-Upload-Header-Content-Length", strconv.FormatInt(size, 10))
+	}
+
+	var startConfig UploadToFileSearchStoreConfig
+	deepCopy(config.UploadToFileSearchStoreConfig, &startConfig)
+	startConfig.HTTPOptions = &httpOptions
+	startConfig.ShouldReturnHTTPResponse = Ptr(true)
+
+	resp, err := m.uploadToFileSearchStore(ctx, fileSearchStoreName, &startConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to upload to FileSearchStore store. Ran into an error: %w", err)
+	}
+	if resp.SDKHTTPResponse == nil || resp.SDKHTTPResponse.Headers == nil {
+		return nil, fmt.Errorf("Failed to upload to FileSearchStore store. Upload URL was not returned from the request.")
+	}
+	uploadURL := resp.SDKHTTPResponse.Headers.Get("X-Goog-Upload-Url")
+	if uploadURL == "" {
+		return nil, fmt.Errorf("Failed to upload to FileSearchStore store. Upload URL was not returned from the request.")
+	}
+	respBody, err := m.apiClient.upload(ctx, r, uploadURL, &httpOptions, options)
+	if err != nil {
+		return nil, err
+	}
+	return uploadedOperation(respBody)
+}
+
+// uploadedOperation returns the operation of the response body of a finalized
+// upload to a file search store.
+func uploadedOperation(respBody map[string]any) (*UploadToFileSearchStoreOperation, error) {
+	if respBody == nil {
+		return nil, fmt.Errorf("upload completed but response body was empty")
+	}
+	var operation = new(UploadToFileSearchStoreOperation)
+	if err := mapToStruct(respBody, &operation); err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
@@ -0,0 +1,322 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testFilesAPI is an in-memory fake of the Files API, including the resumable
+// upload protocol. Tests of features built on files extend it with their own
+// endpoints in handle.
+type testFilesAPI struct {
+	*httptest.Server
+	t *testing.T
+
+	mu       sync.Mutex
+	files    map[string]*File
+	data     map[string][]byte // the uploaded data of files, by name
+	sessions map[string]*testUploadSession
+	starts   int // number of started uploads
+	uploads  int // number of chunk requests
+	// failures is the number of chunks to fail from failOffset.
+	failures   int
+	failOffset int
+
+	// before is called for each request before it is served, without mu
+	// held, for example to block it.
+	before func(r *http.Request)
+	// The other hooks are called with mu held.
+	//
+	// handle is called first for each request with its body, and reports
+	// whether it served the request. Unserved requests are served by the fake
+	// Files API, or fail with NOT_FOUND.
+	handle func(w http.ResponseWriter, r *http.Request, body []byte) bool
+	// newFile is called with the file created by an upload to the Files API,
+	// which is named files/<n> for the nth upload and is ACTIVE by default.
+	newFile func(file *File)
+	// finalize returns the response of the finalized uploads that aren't to the
+	// Files API.
+	finalize func(session *testUploadSession) any
+}
+
+// testUploadSession is a resumable upload session of a [testFilesAPI].
+type testUploadSession struct {
+	id     string
+	header http.Header // the headers of the request that started the upload
+	body   []byte      // the body of the request that started the upload
+	file   *File       // the uploaded file, for uploads to the Files API
+	data   []byte
+	final  bool
+}
+
+func newTestFilesAPI(t *testing.T) *testFilesAPI {
+	t.Helper()
+	s := &testFilesAPI{
+		t:        t,
+		files:    map[string]*File{},
+		data:     map[string][]byte{},
+		sessions: map[string]*testUploadSession{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// client returns a Gemini API client of the server.
+func (s *testFilesAPI) client() *Client {
+	s.t.Helper()
+	client, err := NewClient(context.Background(), &ClientConfig{
+		Backend:     BackendGeminiAPI,
+		APIKey:      "test-api-key",
+		HTTPOptions: HTTPOptions{BaseURL: s.URL},
+	})
+	if err != nil {
+		s.t.Fatalf("NewClient failed: %v", err)
+	}
+	return client
+}
+
+func (s *testFilesAPI) serve(w http.ResponseWriter, r *http.Request) {
+	// The body is read first, so that the server notices when the client
+	// gives up.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.t.Errorf("ReadAll failed: %v", err)
+	}
+	if s.before != nil {
+		s.before(r)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handle != nil && s.handle(w, r, body) {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/v1beta/")
+	switch {
+	case r.Header.Get("X-Goog-Upload-Command") == "start":
+		s.start(w, r, body)
+	case strings.HasPrefix(r.URL.Path, "/upload/session/"):
+		s.upload(w, r, body)
+	case name == "files" && r.Method == http.MethodGet:
+		files := slices.SortedFunc(maps.Values(s.files), func(a, b *File) int { return strings.Compare(a.Name, b.Name) })
+		json.NewEncoder(w).Encode(map[string]any{"files": files})
+	case s.files[strings.TrimSuffix(name, ":download")] == nil:
+		writeTestNotFound(w)
+	case strings.HasSuffix(name, ":download"):
+		w.Write(s.data[strings.TrimSuffix(name, ":download")])
+	case r.Method == http.MethodDelete:
+		delete(s.files, name)
+		delete(s.data, name)
+		fmt.Fprint(w, `{}`)
+	case r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(s.files[name])
+	default:
+		writeTestNotFound(w)
+	}
+}
+
+func (s *testFilesAPI) start(w http.ResponseWriter, r *http.Request, body []byte) {
+	s.starts++
+	id := strconv.Itoa(s.starts)
+	session := &testUploadSession{id: id, header: r.Header.Clone(), body: body}
+	if r.URL.Path == "/upload/v1beta/files" {
+		var req struct{ File File }
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.t.Errorf("invalid upload start request %s: %v", body, err)
+		}
+		file := &req.File
+		if file.Name == "" {
+			file.Name = "files/" + id
+		}
+		file.MIMEType = r.Header.Get("X-Goog-Upload-Header-Content-Type")
+		file.State = FileStateActive
+		if s.newFile != nil {
+			s.newFile(file)
+		}
+		s.files[file.Name] = file
+		session.file = file
+	}
+	s.sessions[id] = session
+	w.Header().Set("X-Goog-Upload-Url", s.URL+"/upload/session/"+id)
+	fmt.Fprint(w, `{}`)
+}
+
+func (s *testFilesAPI) upload(w http.ResponseWriter, r *http.Request, body []byte) {
+	session := s.sessions[strings.TrimPrefix(r.URL.Path, "/upload/session/")]
+	if session == nil {
+		writeTestNotFound(w)
+		return
+	}
+	switch command := r.Header.Get("X-Goog-Upload-Command"); {
+	case command == "query":
+		w.Header().Set("X-Goog-Upload-Size-Received", strconv.Itoa(len(session.data)))
+		if session.final {
+			s.writeFinal(w, session)
+			return
+		}
+		w.Header().Set("X-Goog-Upload-Status", "active")
+	case strings.HasPrefix(command, "upload"):
+		s.uploads++
+		if offset := r.Header.Get("X-Goog-Upload-Offset"); offset != strconv.Itoa(len(session.data)) {
+			s.t.Errorf("X-Goog-Upload-Offset = %s, want %d", offset, len(session.data))
+		}
+		if s.failures > 0 && len(session.data) >= s.failOffset {
+			s.failures--
+			w.Header().Set("X-Goog-Upload-Status", "active")
+			http.Error(w, "backend unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		session.data = append(session.data, body...)
+		if strings.Contains(command, "finalize") {
+			session.final = true
+			s.writeFinal(w, session)
+			return
+		}
+		w.Header().Set("X-Goog-Upload-Status", "active")
+	default:
+		s.t.Errorf("unexpected upload command %q", command)
+		http.Error(w, "bad command", http.StatusBadRequest)
+	}
+}
+
+// writeFinal writes the response of the finalized upload session.
+func (s *testFilesAPI) writeFinal(w http.ResponseWriter, session *testUploadSession) {
+	w.Header().Set("X-Goog-Upload-Status", "final")
+	if session.file == nil {
+		json.NewEncoder(w).Encode(s.finalize(session))
+		return
+	}
+	session.file.SizeBytes = Ptr(int64(len(session.data)))
+	s.data[session.file.Name] = session.data
+	json.NewEncoder(w).Encode(map[string]any{"file": session.file})
+}
+
+// writeTestNotFound writes a NOT_FOUND API error.
+func writeTestNotFound(w http.ResponseWriter) {
+	http.Error(w, `{"error": {"code": 404, "message": "Not found.", "status": "NOT_FOUND"}}`, http.StatusNotFound)
+}
+
+func TestResumeUpload(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("0123456789"), maxChunkSize/10+100)
+
+	tests := []struct {
+		name   string
+		reader func() io.Reader
+	}{
+		{name: "ReaderAt", reader: func() io.Reader { return bytes.NewReader(data) }},
+		{name: "Seeker", reader: func() io.Reader { return struct{ io.ReadSeeker }{bytes.NewReader(data)} }},
+		{name: "Reader", reader: func() io.Reader { return struct{ io.Reader }{bytes.NewReader(data)} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestFilesAPI(t)
+			client := s.client()
+
+			// The second chunk fails, after the first one was committed.
+			s.failures, s.failOffset = 1, maxChunkSize
+			_, err := client.Files.Upload(ctx, tt.reader(), &UploadFileConfig{MIMEType: "text/plain"})
+			var uploadErr *UploadError
+			if !errors.As(err, &uploadErr) {
+				t.Fatalf("Upload error = %v, want an *UploadError", err)
+			}
+			var apiErr APIError
+			if !errors.As(err, &apiErr) || apiErr.Code != http.StatusServiceUnavailable {
+				t.Errorf("Upload error = %v, want an APIError with code 503", err)
+			}
+			want := UploadSession{URL: s.URL + "/upload/session/1", Offset: maxChunkSize}
+			if uploadErr.Session != want {
+				t.Errorf("Session = %+v, want %+v", uploadErr.Session, want)
+			}
+
+			// The committed offset is queried from the server, even if the
+			// persisted session is stale.
+			stale := uploadErr.Session
+			stale.Offset = 0
+			file, err := client.Files.ResumeUpload(ctx, stale, tt.reader(), nil)
+			if err != nil {
+				t.Fatalf("ResumeUpload failed: %v", err)
+			}
+			if file.Name != "files/1" || *file.SizeBytes != int64(len(data)) {
+				t.Errorf("file = %+v, want files/1 of %d bytes", file, len(data))
+			}
+			if !bytes.Equal(s.data[file.Name], data) {
+				t.Errorf("uploaded %d bytes that don't match the %d bytes of the file", len(s.data[file.Name]), len(data))
+			}
+		})
+	}
+}
+
+func TestStartUpload(t *testing.T) {
+	ctx := context.Background()
+	s := newTestFilesAPI(t)
+	client := s.client()
+	data := bytes.Repeat([]byte("x"), maxChunkSize+10)
+
+	session, err := client.Files.StartUpload(ctx, int64(len(data)), &UploadFileConfig{MIMEType: "text/plain"})
+	if err != nil {
+		t.Fatalf("StartUpload failed: %v", err)
+	}
+	if size := s.sessions["1"].header.Get("X-Goog-Upload-Header-Content-Length"); size != strconv.Itoa(len(data)) {
+		t.Errorf("X-Goog-Upload-Header-Content-Length = %q, want %d", size, len(data))
+	}
+
+	var commits []UploadSession
+	config := &ResumeUploadConfig{OnCommit: func(session UploadSession) { commits = append(commits, session) }}
+	if _, err := client.Files.ResumeUpload(ctx, *session, bytes.NewReader(data), config); err != nil {
+		t.Fatalf("ResumeUpload failed: %v", err)
+	}
+	want := []UploadSession{{URL: session.URL, Offset: maxChunkSize}}
+	if len(commits) != 1 || commits[0] != want[0] {
+		t.Errorf("commits = %+v, want %+v", commits, want)
+	}
+
+	// Resuming a finalized upload returns the file without uploading.
+	uploads := s.uploads
+	file, err := client.Files.ResumeUpload(ctx, *session, bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("ResumeUpload failed: %v", err)
+	}
+	if file.Name != "files/1" || s.uploads != uploads {
+		t.Errorf("ResumeUpload of a finalized upload returned %+v after %d uploads, want files/1 and no uploads", file, s.uploads-uploads)
+	}
+
+	if _, err := client.Files.ResumeUpload(ctx, UploadSession{}, bytes.NewReader(data), nil); err == nil {
+		t.Errorf("ResumeUpload without a URL succeeded, want an error")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second} {
+		if got := retryDelay(attempt); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
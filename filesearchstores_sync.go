@@ -0,0 +1,315 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSyncPathKey      = "sync_path"
+	defaultSyncHashKey      = "sync_sha256"
+	defaultSyncPollInterval = 5 * time.Second
+)
+
+// SyncDirectoryConfig configures [FileSearchStores.SyncDirectory].
+type SyncDirectoryConfig struct {
+	// Optional. Concurrency is the maximum number of concurrent uploads and
+	// deletions. If zero, 4 is used.
+	Concurrency int
+	// Optional. DryRun computes the changes without applying them.
+	DryRun bool
+	// Optional. Filter reports whether to sync the file at path. If nil, all
+	// regular files are synced.
+	Filter func(path string, entry fs.DirEntry) bool
+	// Optional. FileConfig returns the upload config of the file at path, for
+	// example to set its ChunkingConfig or CustomMetadata. If nil, or if the
+	// returned config has no MIME type, the MIME type is inferred from the file
+	// extension. If the returned config has no display name, the path is used.
+	// The custom metadata must not use PathKey or HashKey, which are set by
+	// SyncDirectory.
+	FileConfig func(path string) *UploadToFileSearchStoreConfig
+	// Optional. PathKey is the custom metadata key that holds the path of the
+	// file a document was uploaded from. Documents without it are not managed by
+	// SyncDirectory and are left untouched. If empty, "sync_path" is used.
+	PathKey string
+	// Optional. HashKey is the custom metadata key that holds the hexadecimal
+	// SHA-256 hash of the content a document was uploaded from. If empty,
+	// "sync_sha256" is used.
+	HashKey string
+	// Optional. PollInterval is the delay between two polls of an upload
+	// operation. If zero, 5 seconds is used.
+	PollInterval time.Duration
+}
+
+// SyncReport reports the changes made by [FileSearchStores.SyncDirectory]. All
+// paths are sorted.
+type SyncReport struct {
+	// Added is the paths of the files uploaded for the first time.
+	Added []string
+	// Updated is the paths of the files whose content changed, uploaded again.
+	Updated []string
+	// Deleted is the paths of the files whose documents were deleted because the
+	// files were removed.
+	Deleted []string
+	// Unchanged is the paths of the files whose documents are up to date.
+	Unchanged []string
+	// Failed maps the paths of the files that couldn't be synced to their
+	// errors.
+	Failed map[string]error
+}
+
+// syncChange is a change to apply to a file search store.
+type syncChange struct {
+	path string
+	hash string
+	// stale is the documents to delete once the file is uploaded, or right away
+	// if hash is empty.
+	stale []*Document
+}
+
+// SyncDirectory makes the documents of the file search store mirror the files
+// of fsys. New and changed files are uploaded with
+// [FileSearchStores.UploadToFileSearchStore], and SyncDirectory waits for their
+// upload operations to complete; the documents of removed files are deleted.
+// Up to [SyncDirectoryConfig.Concurrency] files are synced at a time.
+//
+// Files and documents are matched with custom metadata holding the path and
+// the SHA-256 hash of the content of each file, set on upload. The previous
+// document of a changed file is only deleted once the new one is uploaded.
+// The uploaded content is hashed again as it is sent: if the file changed
+// since it was first hashed, the new document is deleted and the file fails,
+// so that no document holds content that doesn't match its hash.
+//
+// The returned error joins the errors of the files that couldn't be synced;
+// the report is returned even if it isn't nil.
+func (m FileSearchStores) SyncDirectory(ctx context.Context, store string, fsys fs.FS, config *SyncDirectoryConfig) (*SyncReport, error) {
+	if config == nil {
+		config = &SyncDirectoryConfig{}
+	}
+	pathKey, hashKey := config.PathKey, config.HashKey
+	if pathKey == "" {
+		pathKey = defaultSyncPathKey
+	}
+	if hashKey == "" {
+		hashKey = defaultSyncHashKey
+	}
+
+	hashes := map[string]string{}
+	err := fs.WalkDir(fsys, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() || (config.Filter != nil && !config.Filter(path, entry)) {
+			return nil
+		}
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		hashes[path], err = hashReader(f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	documents := map[string][]*Document{}
+	for document, err := range m.Documents.All(ctx, store) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
+		if path := customMetadataString(document.CustomMetadata, pathKey); path != "" {
+			documents[path] = append(documents[path], document)
+		}
+	}
+
+	report := &SyncReport{Failed: map[string]error{}}
+	var changes []*syncChange
+	for _, path := range slices.Sorted(maps.Keys(hashes)) {
+		hash := hashes[path]
+		change := &syncChange{path: path, hash: hash}
+		current := false
+		for _, document := range documents[path] {
+			if !current && document.State != DocumentStateFailed && customMetadataString(document.CustomMetadata, hashKey) == hash {
+				current = true
+				continue
+			}
+			change.stale = append(change.stale, document)
+		}
+		switch {
+		case current:
+			report.Unchanged = append(report.Unchanged, path)
+			change.hash = ""
+		case len(documents[path]) == 0:
+			report.Added = append(report.Added, path)
+		default:
+			report.Updated = append(report.Updated, path)
+		}
+		if change.hash != "" || len(change.stale) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(documents)) {
+		if _, ok := hashes[path]; !ok {
+			report.Deleted = append(report.Deleted, path)
+			changes = append(changes, &syncChange{path: path, stale: documents[path]})
+		}
+	}
+	if config.DryRun {
+		return report, nil
+	}
+
+	var mu sync.Mutex
+	forEachConcurrently(config.Concurrency, changes, func(change *syncChange) {
+		if err := m.applySyncChange(ctx, store, fsys, change, config, pathKey, hashKey); err != nil {
+			mu.Lock()
+			report.Failed[change.path] = err
+			mu.Unlock()
+		}
+	})
+
+	var errs []error
+	for _, path := range slices.Sorted(maps.Keys(report.Failed)) {
+		errs = append(errs, fmt.Errorf("failed to sync %s: %w", path, report.Failed[path]))
+	}
+	return report, errors.Join(errs...)
+}
+
+// applySyncChange uploads the file of change, if it has a hash, then deletes
+// its stale documents.
+func (m FileSearchStores) applySyncChange(ctx context.Context, store string, fsys fs.FS, change *syncChange, config *SyncDirectoryConfig, pathKey, hashKey string) error {
+	if change.hash != "" {
+		if err := m.uploadSyncFile(ctx, store, fsys, change, config, pathKey, hashKey); err != nil {
+			return err
+		}
+	}
+	for _, document := range change.stale {
+		if err := m.Documents.Delete(ctx, document.Name, &DeleteDocumentConfig{Force: Ptr(true)}); err != nil {
+			return fmt.Errorf("failed to delete document %s: %w", document.Name, err)
+		}
+	}
+	return nil
+}
+
+// uploadSyncFile uploads the file of change with the path and hash metadata,
+// and waits for the upload operation to complete.
+func (m FileSearchStores) uploadSyncFile(ctx context.Context, store string, fsys fs.FS, change *syncChange, config *SyncDirectoryConfig, pathKey, hashKey string) error {
+	uploadConfig := &UploadToFileSearchStoreConfig{}
+	if config.FileConfig != nil {
+		if fileConfig := config.FileConfig(change.path); fileConfig != nil {
+			*uploadConfig = *fileConfig
+		}
+	}
+	// The config may be shared between files: clone what is modified below.
+	uploadConfig.CustomMetadata = slices.Clone(uploadConfig.CustomMetadata)
+	httpOptions := HTTPOptions{}
+	if uploadConfig.HTTPOptions != nil {
+		httpOptions = *uploadConfig.HTTPOptions
+	}
+	httpOptions.Headers = httpOptions.Headers.Clone()
+	if httpOptions.Headers == nil {
+		httpOptions.Headers = http.Header{}
+	}
+	uploadConfig.HTTPOptions = &httpOptions
+	if uploadConfig.MIMEType == "" {
+		uploadConfig.MIMEType = mime.TypeByExtension(path.Ext(change.path))
+		if uploadConfig.MIMEType == "" {
+			return fmt.Errorf("could not determine the MIME type of %s, set it with SyncDirectoryConfig.FileConfig", change.path)
+		}
+	}
+	if uploadConfig.DisplayName == "" {
+		uploadConfig.DisplayName = change.path
+	}
+	for _, metadata := range uploadConfig.CustomMetadata {
+		if metadata != nil && (metadata.Key == pathKey || metadata.Key == hashKey) {
+			return fmt.Errorf("custom metadata key %q of %s is reserved for the sync path and hash", metadata.Key, change.path)
+		}
+	}
+	uploadConfig.CustomMetadata = append(uploadConfig.CustomMetadata,
+		&CustomMetadata{Key: pathKey, StringValue: change.path},
+		&CustomMetadata{Key: hashKey, StringValue: change.hash},
+	)
+
+	f, err := fsys.Open(change.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil {
+		httpOptions.Headers.Set("X-Goog-Upload-Header-Content-Length", strconv.FormatInt(info.Size(), 10))
+	}
+
+	hasher := sha256.New()
+	operation, err := m.UploadToFileSearchStore(ctx, io.TeeReader(f, hasher), store, uploadConfig)
+	if err != nil {
+		return err
+	}
+	interval := config.PollInterval
+	if interval <= 0 {
+		interval = defaultSyncPollInterval
+	}
+	operations := Operations{apiClient: m.apiClient}
+	for !operation.Done {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("upload operation %s is not done: %w", operation.Name, ctx.Err())
+		case <-time.After(interval):
+		}
+		operation, err = operations.GetUploadToFileSearchStoreOperation(ctx, operation, nil)
+		if err != nil {
+			return err
+		}
+	}
+	if operation.Error != nil {
+		return fmt.Errorf("upload operation %s failed: %v", operation.Name, operation.Error)
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != change.hash {
+		// The document holds content that doesn't match its hash metadata.
+		if operation.Response != nil && operation.Response.DocumentName != "" {
+			if err := m.Documents.Delete(ctx, operation.Response.DocumentName, &DeleteDocumentConfig{Force: Ptr(true)}); err != nil {
+				return fmt.Errorf("%s changed during the sync, failed to delete document %s: %w", change.path, operation.Response.DocumentName, err)
+			}
+		}
+		return fmt.Errorf("%s changed during the sync", change.path)
+	}
+	return nil
+}
+
+// customMetadataString returns the string value of the custom metadata with
+// the given key, or "" if there is none.
+func customMetadataString(metadata []*CustomMetadata, key string) string {
+	for _, m := range metadata {
+		if m != nil && m.Key == key {
+			return m.StringValue
+		}
+	}
+	return ""
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testStoreServer is a [testFilesAPI] that stores the documents of the file
+// search store "store". Uploaded documents are created when their upload
+// operation is first polled.
+type testStoreServer struct {
+	*testFilesAPI
+	documents  map[string]*Document
+	operations map[string]*Document
+	chunking   map[string]*ChunkingConfig
+	deletes    int
+}
+
+func newTestStoreClient(t *testing.T, s *testStoreServer) *Client {
+	t.Helper()
+	s.testFilesAPI = newTestFilesAPI(t)
+	s.operations, s.chunking = map[string]*Document{}, map[string]*ChunkingConfig{}
+	s.finalize = func(session *testUploadSession) any {
+		document := &Document{Name: "fileSearchStores/store/documents/doc-" + session.id, State: DocumentStateActive}
+		json.Unmarshal(session.body, document)
+		var config UploadToFileSearchStoreConfig
+		json.Unmarshal(session.body, &config)
+		s.chunking[document.DisplayName] = config.ChunkingConfig
+		s.operations[session.id] = document
+		return map[string]any{"name": "fileSearchStores/store/upload/operations/" + session.id, "done": false}
+	}
+	s.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		name := strings.TrimPrefix(r.URL.Path, "/v1beta/")
+		switch {
+		case strings.Contains(name, "/operations/"):
+			document := s.operations[name[strings.LastIndex(name, "/")+1:]]
+			if strings.Contains(document.DisplayName, "bad") {
+				fmt.Fprintf(w, `{"name": %q, "done": true, "error": {"code": 3, "message": "unsupported file"}}`, name)
+				return true
+			}
+			s.documents[document.Name] = document
+			fmt.Fprintf(w, `{"name": %q, "done": true, "response": {"documentName": %q}}`, name, document.Name)
+		case name == "fileSearchStores/store/documents":
+			json.NewEncoder(w).Encode(map[string]any{"documents": slices.Collect(maps.Values(s.documents))})
+		case r.Method == http.MethodDelete && s.documents[name] != nil:
+			s.deletes++
+			delete(s.documents, name)
+			fmt.Fprint(w, `{}`)
+		default:
+			return false
+		}
+		return true
+	}
+	return s.client()
+}
+
+func testSyncDocument(name, path, data string) *Document {
+	sum := sha256.Sum256([]byte(data))
+	return &Document{
+		Name:  "fileSearchStores/store/documents/" + name,
+		State: DocumentStateActive,
+		CustomMetadata: []*CustomMetadata{
+			{Key: "sync_path", StringValue: path},
+			{Key: "sync_sha256", StringValue: hex.EncodeToString(sum[:])},
+		},
+	}
+}
+
+func TestSyncDirectory(t *testing.T) {
+	ctx := context.Background()
+	s := &testStoreServer{documents: map[string]*Document{}}
+	for _, document := range []*Document{
+		testSyncDocument("a", "a.txt", "A"),
+		testSyncDocument("b", "docs/b.txt", "old B"),
+		testSyncDocument("gone", "gone.txt", "gone"),
+		{Name: "fileSearchStores/store/documents/manual", DisplayName: "uploaded by hand"},
+	} {
+		s.documents[document.Name] = document
+	}
+	client := newTestStoreClient(t, s)
+	fsys := fstest.MapFS{
+		"a.txt":      {Data: []byte("A")},
+		"docs/b.txt": {Data: []byte("new B")},
+		"docs/c.md":  {Data: []byte("# C")},
+		"skip.log":   {Data: []byte("log")},
+	}
+	chunking := &ChunkingConfig{WhiteSpaceConfig: &WhiteSpaceConfig{MaxTokensPerChunk: Ptr[int32](200)}}
+	config := &SyncDirectoryConfig{
+		DryRun: true,
+		Filter: func(path string, _ fs.DirEntry) bool { return !strings.HasSuffix(path, ".log") },
+		FileConfig: func(path string) *UploadToFileSearchStoreConfig {
+			if strings.HasSuffix(path, ".md") {
+				return &UploadToFileSearchStoreConfig{MIMEType: "text/markdown", ChunkingConfig: chunking}
+			}
+			return nil
+		},
+		PollInterval: time.Millisecond,
+	}
+	want := &SyncReport{
+		Added:     []string{"docs/c.md"},
+		Updated:   []string{"docs/b.txt"},
+		Deleted:   []string{"gone.txt"},
+		Unchanged: []string{"a.txt"},
+		Failed:    map[string]error{},
+	}
+
+	report, err := client.FileSearchStores.SyncDirectory(ctx, "fileSearchStores/store", fsys, config)
+	if err != nil {
+		t.Fatalf("SyncDirectory with DryRun failed: %v", err)
+	}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("SyncDirectory with DryRun report mismatch (-want +got):\n%s", diff)
+	}
+	if s.uploads != 0 || s.deletes != 0 {
+		t.Errorf("SyncDirectory with DryRun made %d uploads and %d deletions, want none", s.uploads, s.deletes)
+	}
+
+	config.DryRun = false
+	report, err = client.FileSearchStores.SyncDirectory(ctx, "fileSearchStores/store", fsys, config)
+	if err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("SyncDirectory report mismatch (-want +got):\n%s", diff)
+	}
+	var got []string
+	for _, document := range s.documents {
+		got = append(got, fmt.Sprintf("%s %s", document.DisplayName, customMetadataString(document.CustomMetadata, "sync_path")))
+	}
+	slices.Sort(got)
+	if diff := cmp.Diff([]string{" a.txt", "docs/b.txt docs/b.txt", "docs/c.md docs/c.md", "uploaded by hand "}, got); diff != "" {
+		t.Errorf("documents mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(chunking, s.chunking["docs/c.md"]); diff != "" {
+		t.Errorf("chunking config mismatch (-want +got):\n%s", diff)
+	}
+
+	// Once synced, nothing changes.
+	report, err = client.FileSearchStores.SyncDirectory(ctx, "fileSearchStores/store", fsys, config)
+	if err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+	want = &SyncReport{Unchanged: []string{"a.txt", "docs/b.txt", "docs/c.md"}, Failed: map[string]error{}}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("SyncDirectory report mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSyncDirectoryFailedUpload(t *testing.T) {
+	ctx := context.Background()
+	s := &testStoreServer{documents: map[string]*Document{}}
+	old := testSyncDocument("bad", "bad.txt", "old")
+	s.documents[old.Name] = old
+	client := newTestStoreClient(t, s)
+	fsys := fstest.MapFS{"bad.txt": {Data: []byte("new")}}
+
+	report, err := client.FileSearchStores.SyncDirectory(ctx, "fileSearchStores/store", fsys, &SyncDirectoryConfig{PollInterval: time.Millisecond})
+	if err == nil || report.Failed["bad.txt"] == nil {
+		t.Fatalf("SyncDirectory = %v, %v, want bad.txt to fail", report.Failed, err)
+	}
+	// The previous document is kept when the new one fails to upload.
+	if s.documents[old.Name] == nil || s.deletes != 0 {
+		t.Errorf("SyncDirectory deleted the previous document of a failed upload")
+	}
+}
+
+func TestSyncDirectoryFileChanged(t *testing.T) {
+	ctx := context.Background()
+	s := &testStoreServer{documents: map[string]*Document{}}
+	client := newTestStoreClient(t, s)
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("A")}}
+	config := &SyncDirectoryConfig{
+		// The file changes after it is hashed, before it is uploaded.
+		FileConfig: func(path string) *UploadToFileSearchStoreConfig {
+			fsys[path] = &fstest.MapFile{Data: []byte("changed A")}
+			return nil
+		},
+		PollInterval: time.Millisecond,
+	}
+
+	report, err := client.FileSearchStores.SyncDirectory(ctx, "fileSearchStores/store", fsys, config)
+	if err == nil || report.Failed["a.txt"] == nil {
+		t.Fatalf("SyncDirectory = %v, %v, want a.txt to fail", report.Failed, err)
+	}
+	// The document with the content of the changed file is deleted.
+	if len(s.documents) != 0 || s.deletes != 1 {
+		t.Errorf("SyncDirectory left %d documents after %d deletions, want none after 1", len(s.documents), s.deletes)
+	}
+}
+
+func TestSyncDirectoryReservedMetadata(t *testing.T) {
+	ctx := context.Background()
+	s := &testStoreServer{documents: map[string]*Document{}}
+	client := newTestStoreClient(t, s)
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("A")}}
+	config := &SyncDirectoryConfig{
+		FileConfig: func(path string) *UploadToFileSearchStoreConfig {
+			return &UploadToFileSearchStoreConfig{CustomMetadata: []*CustomMetadata{{Key: "sync_path", StringValue: "other.txt"}}}
+		},
+		PollInterval: time.Millisecond,
+	}
+
+	report, err := client.FileSearchStores.SyncDirectory(ctx, "fileSearchStores/store", fsys, config)
+	if err == nil || report.Failed["a.txt"] == nil {
+		t.Fatalf("SyncDirectory = %v, %v, want a.txt to fail", report.Failed, err)
+	}
+	if s.uploads != 0 {
+		t.Errorf("SyncDirectory made %d uploads, want none", s.uploads)
+	}
+}
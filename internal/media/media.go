@@ -0,0 +1,62 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package media holds the helpers shared by the Live session code of the genai
+// package and the audio and video packages.
+package media
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsePCMRate returns the sample rate of an "audio/pcm" MIME type such as
+// "audio/pcm;rate=24000", or defaultRate if it doesn't specify one. It returns
+// false for other MIME types and invalid rates.
+func ParsePCMRate(mimeType string, defaultRate int) (int, bool) {
+	parts := strings.Split(mimeType, ";")
+	if strings.TrimSpace(strings.ToLower(parts[0])) != "audio/pcm" {
+		return 0, false
+	}
+	for _, p := range parts[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok || strings.ToLower(key) != "rate" {
+			continue
+		}
+		rate, err := strconv.Atoi(value)
+		if err != nil || rate <= 0 {
+			return 0, false
+		}
+		return rate, true
+	}
+	return defaultRate, true
+}
+
+// SleepUntil waits until t or until ctx is done.
+func SleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package media
+
+import "testing"
+
+func TestParsePCMRate(t *testing.T) {
+	const defaultRate = 24000
+	tests := []struct {
+		mimeType string
+		wantRate int
+		wantOK   bool
+	}{
+		{"audio/pcm;rate=24000", 24000, true},
+		{"audio/pcm; rate=16000", 16000, true},
+		{"audio/pcm", defaultRate, true},
+		{"audio/wav", 0, false},
+		{"audio/pcm;rate=abc", 0, false},
+	}
+	for _, tt := range tests {
+		rate, ok := ParsePCMRate(tt.mimeType, defaultRate)
+		if rate != tt.wantRate || ok != tt.wantOK {
+			t.Errorf("ParsePCMRate(%q) = %d, %t, want %d, %t", tt.mimeType, rate, ok, tt.wantRate, tt.wantOK)
+		}
+	}
+}
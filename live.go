@@ -16,17 +16,104 @@ package genai
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// ErrLiveSessionClosed is the error returned when reading from or writing to a
+// [Session] that has been closed with [Session.Close].
+var ErrLiveSessionClosed = errors.New("live session is closed")
+
+// ErrLiveKeepaliveTimeout is the error returned when no frame is received from
+// the server within the keepalive window configured by [LiveOptions]. It
+// usually means the connection is half-open.
+var ErrLiveKeepaliveTimeout = errors.New("live session keepalive timed out")
+
+// Preview. LiveOptions configures the WebSocket connections opened by
+// [Live.Connect]. It is set on [ClientConfig.LiveOptions].
+type LiveOptions struct {
+	// Optional. HandshakeTimeout bounds the WebSocket opening handshake. If zero,
+	// a default of 45 seconds is used. The handshake is also bounded by the
+	// context passed to [Live.Connect].
+	HandshakeTimeout time.Duration
+	// Optional. WriteTimeout bounds each message sent on a [Session]. If zero,
+	// sending a message doesn't time out.
+	WriteTimeout time.Duration
+	// Optional. KeepaliveInterval is the interval between WebSocket pings sent to
+	// the server. If zero, no pings are sent and half-open connections are not
+	// detected.
+	KeepaliveInterval time.Duration
+	// Optional. KeepaliveTimeout is how long to wait for a pong after a ping
+	// before the connection is considered dead. If zero, KeepaliveInterval is
+	// used. Only used when KeepaliveInterval is set.
+	KeepaliveTimeout time.Duration
+	// Optional. NetDialContext opens the network connection for the WebSocket,
+	// for example to reach the server through a custom transport. If nil,
+	// [net.Dialer.DialContext] is used.
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// Optional. Proxy returns the proxy for a WebSocket handshake request. If
+	// nil, [http.ProxyFromEnvironment] is used.
+	Proxy func(*http.Request) (*url.URL, error)
+	// Optional. TLSClientConfig is the TLS configuration used for wss
+	// connections. If nil, the default configuration is used.
+	TLSClientConfig *tls.Config
+}
+
+// dialer returns a WebSocket dialer based on [websocket.DefaultDialer] with
+// the options applied.
+func (o LiveOptions) dialer() *websocket.Dialer {
+	dialer := *websocket.DefaultDialer
+	if o.HandshakeTimeout > 0 {
+		dialer.HandshakeTimeout = o.HandshakeTimeout
+	}
+	if o.NetDialContext != nil {
+		dialer.NetDialContext = o.NetDialContext
+	}
+	if o.Proxy != nil {
+		dialer.Proxy = o.Proxy
+	}
+	if o.TLSClientConfig != nil {
+		dialer.TLSClientConfig = o.TLSClientConfig
+	}
+	return &dialer
+}
+
+func (o LiveOptions) keepaliveTimeout() time.Duration {
+	if o.KeepaliveTimeout > 0 {
+		return o.KeepaliveTimeout
+	}
+	return o.KeepaliveInterval
+}
+
+// Preview. LiveCloseError is returned by [Session.Receive] and [Session.Messages]
+// when the server closes the WebSocket connection. The code and reason are
+// decoded from the WebSocket close frame.
+type LiveCloseError struct {
+	// Code is the WebSocket close status code, e.g. 1000 for a normal closure
+	// or 1011 for an internal server error.
+	Code int
+	// Reason is the close reason sent by the server. It may be empty.
+	Reason string
+}
+
+// Error returns a string representation of the LiveCloseError.
+func (e LiveCloseError) Error() string {
+	return fmt.Sprintf("live session closed by server. Code: %d, Reason: %s", e.Code, e.Reason)
+}
+
 // Preview. Live serves as the entry point for establishing real-time WebSocket
 // connections to the API. It manages the initial handshake and setup process.
 //
@@ -43,20 +130,50 @@ type Live struct {
 // Preview. Session represents an active, real-time WebSocket connection to the
 // Generative AI API. It provides methods for sending client messages and
 // receiving server messages over the established connection.
+//
+// A Session is safe for concurrent use. Writes to the underlying connection are
+// serialized, so audio, video and tool responses can be sent from different
+// goroutines. Server messages are read by a single reader goroutine, which is
+// started on the first call to [Session.Receive] or [Session.Messages].
 type Session struct {
 	conn      *websocket.Conn
 	apiClient *apiClient
+	options   LiveOptions
+
+	// writeMu serializes writes, as the websocket connection supports only one
+	// concurrent writer.
+	writeMu sync.Mutex
+
+	readerOnce sync.Once
+	results    chan liveReceiveResult
+	// readErr is the error that terminated the reader goroutine. It is set
+	// before results is closed.
+	readErr error
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// liveReceiveResult is a message or an error produced by the reader goroutine.
+type liveReceiveResult struct {
+	message *LiveServerMessage
+	err     error
+	// fatal is true if err terminated the reader goroutine.
+	fatal bool
 }
 
 // Preview. Connect establishes a WebSocket connection to the specified
 // model with the given configuration. It sends the initial
 // setup message and returns a [Session] object representing the connection.
-func (r *Live) Connect(context context.Context, model string, config *LiveConnectConfig) (*Session, error) {
-	// TODO: b/406076143 - Support per request HTTP options.
-	if config != nil && config.HTTPOptions != nil {
-		return nil, fmt.Errorf("live module does not support httpOptions at request-level in LiveConnectConfig yet. Please use the client-level httpOptions configuration instead")
+//
+// The context bounds dialing, the WebSocket handshake and sending the setup
+// message. It does not affect the returned [Session] once Connect returns.
+func (r *Live) Connect(ctx context.Context, model string, config *LiveConnectConfig) (*Session, error) {
+	var configHTTPOptions *HTTPOptions
+	if config != nil {
+		configHTTPOptions = config.HTTPOptions
 	}
-	httpOptions := r.apiClient.clientConfig.HTTPOptions
+	httpOptions := mergeHTTPOptions(r.apiClient.clientConfig, configHTTPOptions)
 	if httpOptions.APIVersion == "" {
 		return nil, fmt.Errorf("live module requires APIVersion to be set. You can set APIVersion to v1beta1 for BackendVertexAI or v1apha for BackendGeminiAPI")
 	}
@@ -71,11 +188,11 @@ func (r *Live) Connect(context context.Context, model string, config *LiveConnec
 	}
 
 	var u url.URL
-	var header http.Header = mergeHeaders(&httpOptions, nil)
+	var header http.Header = httpOptions.Headers
 	if r.apiClient.clientConfig.Backend == BackendVertexAI {
 		hasStandardAuth := r.apiClient.clientConfig.Project != "" && r.apiClient.clientConfig.Location != ""
 		if r.apiClient.clientConfig.Credentials != nil {
-			token, err := r.apiClient.clientConfig.Credentials.Token(context)
+			token, err := r.apiClient.clientConfig.Credentials.Token(ctx)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get token: %w", err)
 			}
@@ -100,7 +217,7 @@ func (r *Live) Connect(context context.Context, model string, config *LiveConnec
 			var method string
 			if strings.HasPrefix(apiKey, "auth_tokens/") {
 				log.Println("Warning: Ephemeral token support is experimental and may change in future.")
-				if r.apiClient.clientConfig.HTTPOptions.APIVersion != "v1alpha" {
+				if httpOptions.APIVersion != "v1alpha" {
 					return nil, fmt.Errorf("Warning: Ephemeral token support is only supported in v1alpha API version. Please use clientConfig: ClientConfig{HTTPOptions: HTTPOptions{APIVersion: \"v1alpha\"}}")
 				}
 				header.Set("Authorization", fmt.Sprintf("Token %s", apiKey))
@@ -118,22 +235,32 @@ func (r *Live) Connect(context context.Context, model string, config *LiveConnec
 		}
 	}
 
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+	options := r.apiClient.clientConfig.LiveOptions
+	dialer := options.dialer()
+	conn, _, err := dialer.DialContext(ctx, u.String(), header)
 	if err != nil {
+		// The dialer reports an expired context deadline as an i/o timeout,
+		// possibly before ctx itself reports it.
+		ctxErr := ctx.Err()
+		if deadline, ok := ctx.Deadline(); ok && ctxErr == nil && !time.Now().Before(deadline) {
+			ctxErr = context.DeadlineExceeded
+		}
+		if ctxErr != nil && !errors.Is(err, ctxErr) {
+			err = fmt.Errorf("%w: %w", ctxErr, err)
+		}
 		return nil, fmt.Errorf("Connect to %s failed: %w", u.String(), err)
 	}
-	s := &Session{
-		conn:      conn,
-		apiClient: r.apiClient,
-	}
+	s := newSession(conn, r.apiClient, options)
 	modelFullName, err := tModelFullName(r.apiClient, model)
 	if err != nil {
+		s.Close()
 		return nil, err
 	}
 	kwargs := map[string]any{"model": modelFullName, "config": config}
 	parameterMap := make(map[string]any)
 	err = deepMarshal(kwargs, &parameterMap)
 	if err != nil {
+		s.Close()
 		return nil, err
 	}
 
@@ -145,21 +272,38 @@ func (r *Live) Connect(context context.Context, model string, config *LiveConnec
 	}
 	body, err := toConverter(r.apiClient, parameterMap, nil, parameterMap)
 	if err != nil {
+		s.Close()
 		return nil, err
 	}
 	delete(body, "config")
 
 	clientBytes, err := json.Marshal(body)
 	if err != nil {
+		s.Close()
 		return nil, fmt.Errorf("marshal LiveClientSetup failed: %w", err)
 	}
-	err = s.conn.WriteMessage(websocket.TextMessage, clientBytes)
+	deadline, _ := ctx.Deadline()
+	err = s.writeWithDeadline(clientBytes, deadline)
 	if err != nil {
+		s.Close()
 		return nil, fmt.Errorf("failed to write LiveClientSetup: %w", err)
 	}
+	if options.KeepaliveInterval > 0 {
+		go s.keepalive()
+	}
 	return s, nil
 }
 
+func newSession(conn *websocket.Conn, ac *apiClient, options LiveOptions) *Session {
+	return &Session{
+		conn:      conn,
+		apiClient: ac,
+		options:   options,
+		results:   make(chan liveReceiveResult),
+		done:      make(chan struct{}),
+	}
+}
+
 // Preview. LiveClientContentInput is the input for [SendClientContent].
 type LiveClientContentInput = LiveSendClientContentParameters
 
@@ -217,28 +361,38 @@ type LiveRealtimeInput = LiveSendRealtimeInputParameters
 // It accepts a [LiveRealtimeInput] parameter containing the media data.
 // Only one argument (e.g., Media, Audio, Video, Text) should be provided per call.
 func (s *Session) SendRealtimeInput(input LiveRealtimeInput) error {
+	data, err := encodeLiveRealtimeInput(s.apiClient, input)
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+// encodeLiveRealtimeInput converts input into a realtimeInput frame for the
+// client's backend.
+func encodeLiveRealtimeInput(ac *apiClient, input LiveRealtimeInput) ([]byte, error) {
 	parameterMap := make(map[string]any)
 	err := deepMarshal(input, &parameterMap)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var toConverter func(map[string]any, map[string]any, map[string]any) (map[string]any, error)
-	if s.apiClient.clientConfig.Backend == BackendVertexAI {
+	if ac.clientConfig.Backend == BackendVertexAI {
 		toConverter = liveSendRealtimeInputParametersToVertex
 	} else {
 		toConverter = liveSendRealtimeInputParametersToMldev
 	}
 	body, err := toConverter(parameterMap, nil, parameterMap)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	data, err := json.Marshal(map[string]any{"realtimeInput": body})
 	if err != nil {
-		return fmt.Errorf("marshal client message error: %w", err)
+		return nil, fmt.Errorf("marshal client message error: %w", err)
 	}
-	return s.conn.WriteMessage(websocket.TextMessage, []byte(data))
+	return data, nil
 }
 
 // Preview. LiveToolResponseInput is the input for [SendToolResponse].
@@ -257,32 +411,88 @@ func (s *Session) SendToolResponse(input LiveToolResponseInput) error {
 // Send transmits a LiveClientMessage over the established connection.
 // It returns an error if sending the message fails.
 func (s *Session) send(input *LiveClientMessage) error {
+	data, err := encodeLiveClientMessage(s.apiClient, input)
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+// encodeLiveClientMessage converts input into a frame for the client's
+// backend.
+func encodeLiveClientMessage(ac *apiClient, input *LiveClientMessage) ([]byte, error) {
 	if input.Setup != nil {
-		return fmt.Errorf("message SetUp is not supported in Send(). Use Connect() instead")
+		return nil, fmt.Errorf("message SetUp is not supported in Send(). Use Connect() instead")
 	}
 
 	parameterMap := make(map[string]any)
 	err := deepMarshal(input, &parameterMap)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var toConverter func(map[string]any, map[string]any, map[string]any) (map[string]any, error)
-	if s.apiClient.clientConfig.Backend == BackendVertexAI {
+	if ac.clientConfig.Backend == BackendVertexAI {
 		toConverter = liveClientMessageToVertex
 	} else {
 		toConverter = liveClientMessageToMldev
 	}
 	body, err := toConverter(parameterMap, nil, parameterMap)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	data, err := json.Marshal(body)
 	if err != nil {
-		return fmt.Errorf("marshal client message error: %w", err)
+		return nil, fmt.Errorf("marshal client message error: %w", err)
+	}
+	return data, nil
+}
+
+// write sends a single text frame, bounded by [LiveOptions.WriteTimeout]. It
+// is safe for concurrent use.
+func (s *Session) write(data []byte) error {
+	var deadline time.Time
+	if s.options.WriteTimeout > 0 {
+		deadline = time.Now().Add(s.options.WriteTimeout)
+	}
+	return s.writeWithDeadline(data, deadline)
+}
+
+// writeWithDeadline sends a single text frame that must complete before
+// deadline. A zero deadline means no deadline.
+func (s *Session) writeWithDeadline(data []byte, deadline time.Time) error {
+	select {
+	case <-s.done:
+		return ErrLiveSessionClosed
+	default:
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// keepalive pings the server every [LiveOptions.KeepaliveInterval] until the
+// session is closed. Missing pongs are detected by the read deadline set in
+// readLoop.
+func (s *Session) keepalive() {
+	ticker := time.NewTicker(s.options.KeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			// WriteControl is safe to call concurrently with WriteMessage.
+			err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(s.options.keepaliveTimeout()))
+			if err != nil {
+				return
+			}
+		}
 	}
-	return s.conn.WriteMessage(websocket.TextMessage, []byte(data))
 }
 
 // Preview. Receive reads a LiveServerMessage from the connection.
@@ -291,13 +501,139 @@ func (s *Session) send(input *LiveClientMessage) error {
 // The returned message represents a part of or a complete model turn.
 // If the received message is a [LiveServerToolCall], the user must call
 // [SendToolResponse] to provide the function execution result and continue the turn.
+//
+// If the server closes the connection, the returned error is a [LiveCloseError].
+// Receive shares the reader goroutine with [Session.Messages]; each server
+// message is delivered to exactly one caller.
+//
+// Receive blocks without a deadline. Use [Session.ReceiveContext] to bound
+// the wait.
 func (s *Session) Receive() (*LiveServerMessage, error) {
-	messageType, msgBytes, err := s.conn.ReadMessage()
-	if err != nil {
-		return nil, err
+	return s.ReceiveContext(context.Background())
+}
+
+// Preview. ReceiveContext is like [Session.Receive] but returns ctx.Err() if
+// ctx is done before a message is received. A message that arrives after ctx
+// is done stays available to the next call.
+func (s *Session) ReceiveContext(ctx context.Context) (*LiveServerMessage, error) {
+	r := s.receive(ctx)
+	return r.message, r.err
+}
+
+// receive returns the next result from the reader goroutine. Results that end
+// the session, including ctx being done, are marked fatal.
+func (s *Session) receive(ctx context.Context) liveReceiveResult {
+	s.readerOnce.Do(func() { go s.readLoop() })
+	select {
+	case r, ok := <-s.results:
+		if !ok {
+			return liveReceiveResult{err: s.readErr, fatal: true}
+		}
+		return r
+	case <-s.done:
+		return liveReceiveResult{err: ErrLiveSessionClosed, fatal: true}
+	case <-ctx.Done():
+		return liveReceiveResult{err: ctx.Err(), fatal: true}
+	}
+}
+
+// Preview. Messages returns an iterator over the messages received from the server.
+//
+// The iterator yields each [LiveServerMessage] in order. Errors for a single
+// malformed or error message are yielded without ending the iteration. The
+// iteration ends after yielding a connection error (for example a
+// [LiveCloseError]), when ctx is done, or when the session is closed.
+//
+//	for message, err := range session.Messages(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (s *Session) Messages(ctx context.Context) iter.Seq2[*LiveServerMessage, error] {
+	return func(yield func(*LiveServerMessage, error) bool) {
+		s.readerOnce.Do(func() { go s.readLoop() })
+		for {
+			select {
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			case <-s.done:
+				return
+			case r, ok := <-s.results:
+				if !ok {
+					yield(nil, s.readErr)
+					return
+				}
+				if !yield(r.message, r.err) || r.fatal {
+					return
+				}
+			}
+		}
+	}
+}
+
+// readLoop reads server messages until the connection fails or the session is
+// closed. It is the only goroutine that reads from the connection.
+func (s *Session) readLoop() {
+	defer close(s.results)
+	keepaliveWindow := s.options.KeepaliveInterval + s.options.keepaliveTimeout()
+	if s.options.KeepaliveInterval > 0 {
+		// Any frame from the server, including pongs, proves the connection is alive.
+		s.conn.SetPongHandler(func(string) error {
+			return s.conn.SetReadDeadline(time.Now().Add(keepaliveWindow))
+		})
 	}
+	for {
+		if s.options.KeepaliveInterval > 0 {
+			if err := s.conn.SetReadDeadline(time.Now().Add(keepaliveWindow)); err != nil {
+				s.readErr = err
+				s.deliver(liveReceiveResult{err: err, fatal: true})
+				return
+			}
+		}
+		messageType, msgBytes, err := s.conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-s.done:
+				s.readErr = ErrLiveSessionClosed
+				return
+			default:
+			}
+			var closeErr *websocket.CloseError
+			var netErr net.Error
+			if errors.As(err, &closeErr) {
+				err = LiveCloseError{Code: closeErr.Code, Reason: closeErr.Text}
+			} else if s.options.KeepaliveInterval > 0 && errors.As(err, &netErr) && netErr.Timeout() {
+				err = fmt.Errorf("%w: no frame received from server in %v: %w", ErrLiveKeepaliveTimeout, keepaliveWindow, err)
+			}
+			s.readErr = err
+			s.deliver(liveReceiveResult{err: err, fatal: true})
+			return
+		}
+		message, err := s.parseServerMessage(messageType, msgBytes)
+		if !s.deliver(liveReceiveResult{message: message, err: err}) {
+			s.readErr = ErrLiveSessionClosed
+			return
+		}
+	}
+}
+
+// deliver hands a result to a waiting receiver. It returns false if the
+// session was closed before the result could be delivered.
+func (s *Session) deliver(r liveReceiveResult) bool {
+	select {
+	case s.results <- r:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// parseServerMessage converts a raw frame into a LiveServerMessage.
+func (s *Session) parseServerMessage(messageType int, msgBytes []byte) (*LiveServerMessage, error) {
 	responseMap := make(map[string]any)
-	err = json.Unmarshal(msgBytes, &responseMap)
+	err := json.Unmarshal(msgBytes, &responseMap)
 	if err != nil {
 		return nil, fmt.Errorf("invalid message format. Error %w. messageType: %d, message: %s", err, messageType, msgBytes)
 	}
@@ -324,10 +660,17 @@ func (s *Session) Receive() (*LiveServerMessage, error) {
 	return message, err
 }
 
-// Preview. Close terminates the connection.
+// Preview. Close terminates the connection. It is safe to call Close more
+// than once and concurrently with other methods; calls after the first are
+// no-ops.
 func (s *Session) Close() error {
-	if s != nil && s.conn != nil {
-		return s.conn.Close()
+	if s == nil || s.conn == nil {
+		return nil
 	}
-	return nil
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.conn.Close()
+	})
+	return err
 }
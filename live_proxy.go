@@ -0,0 +1,375 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultLiveProxySetupTimeout = 10 * time.Second
+	defaultLiveProxyWriteTimeout = 10 * time.Second
+)
+
+// Preview. LiveProxyPolicy is the set of limits applied to the Live session of
+// a browser connection, in the spirit of [LiveConnectConstraints].
+type LiveProxyPolicy struct {
+	// Required. Model is the model of the session. Browsers can't choose it.
+	Model string
+	// Optional. Config is the configuration of the session.
+	Config *LiveConnectConfig
+	// Optional. AllowedConfigFields are the JSON names of the
+	// [LiveConnectConfig] fields, such as "systemInstruction" or
+	// "speechConfig", that the browser may set in its setup message. They
+	// override the fields of Config. All other fields are locked, and
+	// "httpOptions" can never be set by the browser.
+	AllowedConfigFields []string
+	// Optional. MaxDuration is the maximum duration of the session. When it is
+	// reached, both connections are closed. If zero, the duration is not
+	// limited.
+	MaxDuration time.Duration
+}
+
+// Preview. LiveProxyConfig configures a [LiveProxy].
+type LiveProxyConfig struct {
+	// Required. Authorize authenticates the browser's WebSocket handshake
+	// request, for example with a cookie or a query parameter, and returns the
+	// policy of the user. If it returns an error, the handshake is rejected with
+	// 403 Forbidden.
+	Authorize func(r *http.Request) (*LiveProxyPolicy, error)
+	// Optional. CheckOrigin returns true if the Origin of the handshake request
+	// is acceptable. If nil, only same-origin requests are accepted.
+	CheckOrigin func(r *http.Request) bool
+	// Optional. SetupTimeout is how long to wait for the browser's first message
+	// when the policy allows config fields. If zero, 10 seconds is used.
+	SetupTimeout time.Duration
+	// Optional. WriteTimeout bounds each message sent to the browser. If zero,
+	// 10 seconds is used.
+	WriteTimeout time.Duration
+	// Optional. OnError is called with the error that ended a proxied session,
+	// if the session didn't end normally. If nil, the error is logged.
+	OnError func(r *http.Request, err error)
+}
+
+// Preview. LiveProxy is an [http.Handler] that bridges browser WebSocket
+// connections to Live sessions opened with the server's credentials, so that
+// the browser never sees them.
+//
+// The browser sends JSON messages with one of the following fields, and
+// receives the [LiveServerMessage] values of the session as JSON:
+//
+//	{"setup": LiveConnectConfig}               // optional first message
+//	{"clientContent": LiveClientContentInput}
+//	{"realtimeInput": LiveRealtimeInput}
+//	{"toolResponse": LiveToolResponseInput}
+//
+// The setup message is only accepted if the user's policy allows config
+// fields. Messages are forwarded one at a time in each direction, so a slow
+// peer slows down the other side instead of growing a buffer.
+//
+// When either side closes its connection, the other connection is closed
+// too. Close codes sent by the Live server are forwarded to the browser.
+type LiveProxy struct {
+	live     *Live
+	config   LiveProxyConfig
+	upgrader websocket.Upgrader
+}
+
+// liveProxyClientMessage is a message sent by the browser.
+type liveProxyClientMessage struct {
+	Setup         json.RawMessage         `json:"setup,omitempty"`
+	ClientContent *LiveClientContentInput `json:"clientContent,omitempty"`
+	RealtimeInput *LiveRealtimeInput      `json:"realtimeInput,omitempty"`
+	ToolResponse  *LiveToolResponseInput  `json:"toolResponse,omitempty"`
+}
+
+// liveProxyCloseError is an error that closes the browser connection with a
+// specific close code.
+type liveProxyCloseError struct {
+	code   int
+	reason string
+}
+
+func (e *liveProxyCloseError) Error() string {
+	return fmt.Sprintf("live proxy closed the connection. Code: %d, Reason: %s", e.code, e.reason)
+}
+
+// Preview. NewProxy returns a handler that proxies browser WebSocket
+// connections to Live sessions of the client.
+func (r *Live) NewProxy(config *LiveProxyConfig) (*LiveProxy, error) {
+	if config == nil || config.Authorize == nil {
+		return nil, fmt.Errorf("live proxy requires an Authorize function")
+	}
+	p := &LiveProxy{live: r, config: *config}
+	if p.config.SetupTimeout <= 0 {
+		p.config.SetupTimeout = defaultLiveProxySetupTimeout
+	}
+	if p.config.WriteTimeout <= 0 {
+		p.config.WriteTimeout = defaultLiveProxyWriteTimeout
+	}
+	p.upgrader.CheckOrigin = config.CheckOrigin
+	return p, nil
+}
+
+// ServeHTTP authorizes the request, upgrades it to a WebSocket and proxies it
+// until either side closes its connection.
+func (p *LiveProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	policy, err := p.config.Authorize(req)
+	if err != nil {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if policy == nil || policy.Model == "" {
+		p.reportError(req, fmt.Errorf("live proxy policy must specify a model"))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	browser, err := p.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// Upgrade already replied with an HTTP error.
+		return
+	}
+	defer browser.Close()
+
+	err = p.proxy(req, browser, policy)
+	code, reason := websocket.CloseNormalClosure, ""
+	var closeErr *liveProxyCloseError
+	var liveCloseErr LiveCloseError
+	switch {
+	case err == nil:
+	case errors.As(err, &closeErr):
+		code, reason = closeErr.code, closeErr.reason
+	case errors.As(err, &liveCloseErr):
+		code, reason = liveCloseErr.Code, liveCloseErr.Reason
+		if code == websocket.CloseNoStatusReceived || code == websocket.CloseAbnormalClosure {
+			// These codes must not be sent in a close frame.
+			code = websocket.CloseGoingAway
+		}
+	default:
+		code, reason = websocket.CloseInternalServerErr, "internal error"
+	}
+	if err != nil && code != websocket.CloseNormalClosure {
+		p.reportError(req, err)
+	}
+	closeMessage := websocket.FormatCloseMessage(code, reason)
+	_ = browser.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(p.config.WriteTimeout))
+}
+
+// proxy opens the Live session and pumps messages in both directions. It
+// returns nil if the browser closed its connection.
+func (p *LiveProxy) proxy(req *http.Request, browser *websocket.Conn, policy *LiveProxyPolicy) error {
+	var first *liveProxyClientMessage
+	config := policy.Config
+	if len(policy.AllowedConfigFields) > 0 {
+		browser.SetReadDeadline(time.Now().Add(p.config.SetupTimeout))
+		message, err := readLiveProxyMessage(browser)
+		if err != nil {
+			return browserReadError(err)
+		}
+		browser.SetReadDeadline(time.Time{})
+		if message.Setup != nil {
+			config, err = applyLiveProxyConfig(policy, message.Setup)
+			if err != nil {
+				return &liveProxyCloseError{code: websocket.ClosePolicyViolation, reason: err.Error()}
+			}
+		} else {
+			first = message
+		}
+	}
+
+	// The session outlives the handshake request, so its context is not derived
+	// from the request's.
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if policy.MaxDuration > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), policy.MaxDuration)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+	session, err := p.live.Connect(req.Context(), policy.Model, config)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Live API: %w", err)
+	}
+	defer session.Close()
+
+	upstreamErr := make(chan error, 1)
+	go func() {
+		upstreamErr <- p.pumpToBrowser(ctx, session, browser)
+		// Unblock the browser read.
+		browser.SetReadDeadline(time.Now())
+	}()
+
+	browserErr := p.pumpToSession(session, browser, first)
+	session.Close()
+	err = <-upstreamErr
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &liveProxyCloseError{code: websocket.CloseNormalClosure, reason: "maximum session duration reached"}
+	}
+	if browserErr != nil {
+		return browserErr
+	}
+	if errors.Is(err, ErrLiveSessionClosed) {
+		// The browser closed its connection.
+		return nil
+	}
+	return err
+}
+
+// pumpToBrowser forwards the messages of the session to the browser until the
+// session ends.
+func (p *LiveProxy) pumpToBrowser(ctx context.Context, session *Session, browser *websocket.Conn) error {
+	for message, err := range session.Messages(ctx) {
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("failed to marshal Live server message: %w", err)
+		}
+		browser.SetWriteDeadline(time.Now().Add(p.config.WriteTimeout))
+		if err := browser.WriteMessage(websocket.TextMessage, data); err != nil {
+			// The browser is gone, the other pump reports it.
+			return ErrLiveSessionClosed
+		}
+	}
+	return ErrLiveSessionClosed
+}
+
+// pumpToSession forwards the messages of the browser to the session until the
+// browser closes its connection. It returns nil if the browser closed its
+// connection or the session ended.
+func (p *LiveProxy) pumpToSession(session *Session, browser *websocket.Conn, first *liveProxyClientMessage) error {
+	message := first
+	for {
+		if message != nil {
+			if message.Setup != nil {
+				return &liveProxyCloseError{code: websocket.ClosePolicyViolation, reason: "setup is only allowed as the first message"}
+			}
+			if err := sendLiveProxyMessage(session, message); err != nil {
+				if errors.Is(err, ErrLiveSessionClosed) {
+					return nil
+				}
+				return err
+			}
+		}
+		var err error
+		message, err = readLiveProxyMessage(browser)
+		if err != nil {
+			var closeErr *liveProxyCloseError
+			if errors.As(err, &closeErr) {
+				return err
+			}
+			// The browser closed its connection or the read was interrupted
+			// because the session ended.
+			return nil
+		}
+	}
+}
+
+// readLiveProxyMessage reads and decodes a message from the browser.
+func readLiveProxyMessage(browser *websocket.Conn) (*liveProxyClientMessage, error) {
+	_, data, err := browser.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	message := new(liveProxyClientMessage)
+	if err := json.Unmarshal(data, message); err != nil {
+		return nil, &liveProxyCloseError{code: websocket.CloseUnsupportedData, reason: "invalid message"}
+	}
+	return message, nil
+}
+
+// browserReadError converts an error reading the first browser message.
+func browserReadError(err error) error {
+	var closeErr *liveProxyCloseError
+	if errors.As(err, &closeErr) {
+		return err
+	}
+	var wsCloseErr *websocket.CloseError
+	if errors.As(err, &wsCloseErr) {
+		return nil
+	}
+	return &liveProxyCloseError{code: websocket.ClosePolicyViolation, reason: "setup timed out"}
+}
+
+// sendLiveProxyMessage sends a browser message to the session.
+func sendLiveProxyMessage(session *Session, message *liveProxyClientMessage) error {
+	switch {
+	case message.ClientContent != nil:
+		return session.SendClientContent(*message.ClientContent)
+	case message.RealtimeInput != nil:
+		return session.SendRealtimeInput(*message.RealtimeInput)
+	case message.ToolResponse != nil:
+		return session.SendToolResponse(*message.ToolResponse)
+	default:
+		return &liveProxyCloseError{code: websocket.CloseUnsupportedData, reason: "unsupported message"}
+	}
+}
+
+// applyLiveProxyConfig returns the policy's config with the fields set by the
+// browser's setup message. It fails if the browser sets a locked field.
+func applyLiveProxyConfig(policy *LiveProxyPolicy, setup json.RawMessage) (*LiveConnectConfig, error) {
+	var requested map[string]json.RawMessage
+	if err := json.Unmarshal(setup, &requested); err != nil {
+		return nil, fmt.Errorf("invalid setup")
+	}
+	merged := map[string]json.RawMessage{}
+	if policy.Config != nil {
+		data, err := json.Marshal(policy.Config)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &merged); err != nil {
+			return nil, err
+		}
+	}
+	for field, value := range requested {
+		if field == "httpOptions" || !slices.Contains(policy.AllowedConfigFields, field) {
+			return nil, fmt.Errorf("setting %s is not allowed", field)
+		}
+		merged[field] = value
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	config := new(LiveConnectConfig)
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid setup")
+	}
+	if policy.Config != nil {
+		// Keep the fields of HTTPOptions that are not serialized.
+		config.HTTPOptions = policy.Config.HTTPOptions
+	}
+	return config, nil
+}
+
+func (p *LiveProxy) reportError(req *http.Request, err error) {
+	if p.config.OnError != nil {
+		p.config.OnError(req, err)
+		return
+	}
+	log.Printf("live proxy: %v", err)
+}
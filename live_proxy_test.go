@@ -0,0 +1,246 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestLiveProxy starts an upstream Live server that answers the setup and
+// echoes realtime text, and a proxy in front of it. The setup messages
+// received by the upstream server are sent to setups.
+func newTestLiveProxy(t *testing.T, config *LiveProxyConfig, setups chan<- map[string]any) *httptest.Server {
+	t.Helper()
+	var upgrader = websocket.Upgrader{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var message map[string]map[string]any
+			if err := json.Unmarshal(data, &message); err != nil {
+				t.Errorf("Unmarshal failed: %v", err)
+				return
+			}
+			var response string
+			switch {
+			case message["setup"] != nil:
+				if setups != nil {
+					setups <- message["setup"]
+				}
+				response = `{"setupComplete":{}}`
+			case message["realtimeInput"]["text"] == "close":
+				closeMessage := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "overloaded")
+				conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+				return
+			case message["realtimeInput"] != nil:
+				response = `{"serverContent":{"modelTurn":{"parts":[{"text":"` + message["realtimeInput"]["text"].(string) + `"}]}}}`
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(response)); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	proxy, err := newTestLiveClient(t, upstream).Live.NewProxy(config)
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	ts := httptest.NewServer(proxy)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func dialTestLiveProxy(t *testing.T, ts *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(ts.URL, "http", "ws", 1)+"?user=alice", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readTestLiveProxy reads n messages from the proxy.
+func readTestLiveProxy(t *testing.T, conn *websocket.Conn, n int) []string {
+	t.Helper()
+	var messages []string
+	for range n {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage failed: %v", err)
+		}
+		messages = append(messages, string(data))
+	}
+	return messages
+}
+
+func wantTestLiveProxyClose(t *testing.T, conn *websocket.Conn, code int, reason string) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("ReadMessage error = %v, want a close error", err)
+		}
+		if closeErr.Code != code || !strings.Contains(closeErr.Text, reason) {
+			t.Errorf("close = %d %q, want %d %q", closeErr.Code, closeErr.Text, code, reason)
+		}
+		return
+	}
+}
+
+func authorizeTestLiveProxy(policy *LiveProxyPolicy) func(*http.Request) (*LiveProxyPolicy, error) {
+	return func(r *http.Request) (*LiveProxyPolicy, error) {
+		if r.URL.Query().Get("user") != "alice" {
+			return nil, errors.New("unknown user")
+		}
+		return policy, nil
+	}
+}
+
+func TestLiveProxy(t *testing.T) {
+	setups := make(chan map[string]any, 1)
+	ts := newTestLiveProxy(t, &LiveProxyConfig{
+		Authorize: authorizeTestLiveProxy(&LiveProxyPolicy{
+			Model:               "test-model",
+			Config:              &LiveConnectConfig{Temperature: Ptr[float32](0.5)},
+			AllowedConfigFields: []string{"systemInstruction"},
+		}),
+	}, setups)
+	conn := dialTestLiveProxy(t, ts)
+
+	messages := []string{
+		`{"setup":{"systemInstruction":{"parts":[{"text":"Be brief."}]}}}`,
+		`{"realtimeInput":{"text":"hello"}}`,
+		`{"realtimeInput":{"text":"world"}}`,
+	}
+	for _, message := range messages {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+	got := readTestLiveProxy(t, conn, 3)
+	want := []string{`"setupComplete"`, `"text":"hello"`, `"text":"world"`}
+	for i := range want {
+		if !strings.Contains(got[i], want[i]) {
+			t.Errorf("message %d = %s, want it to contain %s", i, got[i], want[i])
+		}
+	}
+
+	setup := <-setups
+	if !strings.HasSuffix(setup["model"].(string), "models/test-model") {
+		t.Errorf("setup model = %v, want test-model", setup["model"])
+	}
+	if got := setup["generationConfig"].(map[string]any)["temperature"]; got != 0.5 {
+		t.Errorf("setup temperature = %v, want 0.5", got)
+	}
+	if setup["systemInstruction"] == nil {
+		t.Errorf("setup has no system instruction, want the one sent by the browser")
+	}
+}
+
+func TestLiveProxyUnauthorized(t *testing.T) {
+	ts := newTestLiveProxy(t, &LiveProxyConfig{
+		Authorize: authorizeTestLiveProxy(&LiveProxyPolicy{Model: "test-model"}),
+	}, nil)
+	_, resp, err := websocket.DefaultDialer.Dial(strings.Replace(ts.URL, "http", "ws", 1)+"?user=mallory", nil)
+	if err == nil {
+		t.Fatal("Dial succeeded, want an error")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Dial response = %v, want 403 Forbidden", resp)
+	}
+}
+
+func TestLiveProxyClose(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     *LiveProxyPolicy
+		messages   []string
+		wantCode   int
+		wantReason string
+	}{
+		{
+			name:       "LockedConfigField",
+			policy:     &LiveProxyPolicy{Model: "test-model", AllowedConfigFields: []string{"systemInstruction"}},
+			messages:   []string{`{"setup":{"temperature":2}}`},
+			wantCode:   websocket.ClosePolicyViolation,
+			wantReason: "setting temperature is not allowed",
+		},
+		{
+			name:       "SetupNotAllowed",
+			policy:     &LiveProxyPolicy{Model: "test-model"},
+			messages:   []string{`{"setup":{}}`},
+			wantCode:   websocket.ClosePolicyViolation,
+			wantReason: "setup is only allowed as the first message",
+		},
+		{
+			name:       "InvalidMessage",
+			policy:     &LiveProxyPolicy{Model: "test-model"},
+			messages:   []string{`not json`},
+			wantCode:   websocket.CloseUnsupportedData,
+			wantReason: "invalid message",
+		},
+		{
+			name:       "UpstreamClose",
+			policy:     &LiveProxyPolicy{Model: "test-model"},
+			messages:   []string{`{"realtimeInput":{"text":"close"}}`},
+			wantCode:   websocket.CloseTryAgainLater,
+			wantReason: "overloaded",
+		},
+		{
+			name:       "MaxDuration",
+			policy:     &LiveProxyPolicy{Model: "test-model", MaxDuration: 100 * time.Millisecond},
+			wantCode:   websocket.CloseNormalClosure,
+			wantReason: "maximum session duration reached",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestLiveProxy(t, &LiveProxyConfig{
+				Authorize: authorizeTestLiveProxy(tt.policy),
+				OnError:   func(*http.Request, error) {},
+			}, nil)
+			conn := dialTestLiveProxy(t, ts)
+			for _, message := range tt.messages {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+					t.Fatalf("WriteMessage failed: %v", err)
+				}
+			}
+			wantTestLiveProxyClose(t, conn, tt.wantCode, tt.wantReason)
+		})
+	}
+}
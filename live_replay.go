@@ -0,0 +1,411 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	liveReplayClientFrame = "client"
+	liveReplayServerFrame = "server"
+)
+
+// liveReplayFile is a recorded Live session. Each connection, including the
+// reconnections of a resumed session, is recorded separately.
+type liveReplayFile struct {
+	ReplayID    string                  `json:"replayId,omitempty"`
+	Connections []*liveReplayConnection `json:"connections,omitempty"`
+}
+
+// liveReplayConnection is a recorded WebSocket connection.
+type liveReplayConnection struct {
+	// URL is the redacted path and query of the handshake request.
+	URL string `json:"url,omitempty"`
+	// Headers are the redacted headers of the handshake request.
+	Headers map[string]string  `json:"headers,omitempty"`
+	Frames  []*liveReplayFrame `json:"frames,omitempty"`
+}
+
+// liveReplayFrame is a recorded message or close frame.
+type liveReplayFrame struct {
+	// Direction is "client" for frames sent by the SDK and "server" for frames
+	// sent by the API.
+	Direction string `json:"direction"`
+	// OffsetMillis is the time of the frame since the connection was opened.
+	OffsetMillis int64 `json:"offsetMillis"`
+	// Binary is true if the message was sent in a binary frame.
+	Binary bool `json:"binary,omitempty"`
+	// Body is the message. Client messages are redacted.
+	Body json.RawMessage `json:"body,omitempty"`
+	// CloseCode and CloseReason describe a close frame sent by the server.
+	CloseCode   int    `json:"closeCode,omitempty"`
+	CloseReason string `json:"closeReason,omitempty"`
+}
+
+// liveReplayIgnoredHeaders are the handshake headers that change between
+// connections and are not recorded.
+var liveReplayIgnoredHeaders = map[string]bool{
+	"connection":               true,
+	"host":                     true,
+	"sec-websocket-extensions": true,
+	"sec-websocket-key":        true,
+	"sec-websocket-version":    true,
+	"upgrade":                  true,
+}
+
+// redactLiveHandshake returns the redacted URL and headers of a handshake
+// request, with the same redactions as the REST replay client.
+func redactLiveHandshake(req *http.Request) (string, map[string]string) {
+	headers := make(map[string]string)
+	for k, v := range req.Header {
+		lowerK := strings.ToLower(k)
+		if liveReplayIgnoredHeaders[lowerK] {
+			continue
+		}
+		headers[lowerK] = strings.Join(v, ",")
+	}
+	return redactProjectLocationPath(req.URL.RequestURI()), redactRequestHeaders(headers)
+}
+
+// redactLiveValue redacts project and location paths in every string of v.
+// Unlike REST requests, Live messages nest them, for example in the model of
+// the setup message.
+func redactLiveValue(v any) any {
+	switch m := v.(type) {
+	case string:
+		return redactProjectLocationPath(m)
+	case map[string]any:
+		for k, value := range m {
+			m[k] = redactLiveValue(value)
+		}
+		return m
+	case []any:
+		for i, item := range m {
+			m[i] = redactLiveValue(item)
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+// normalizeLiveClientMessage unmarshals and redacts a client message for
+// comparison.
+func normalizeLiveClientMessage(data []byte) (any, error) {
+	var m any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	m = convertKeysToCamelCase(redactLiveValue(m), "")
+	omitEmptyValues(m)
+	return m, nil
+}
+
+// liveRecordingServer is a WebSocket proxy that forwards Live connections to
+// an upstream API endpoint and records the frames to a replay file.
+type liveRecordingServer struct {
+	t        *testing.T
+	upstream string
+	path     string
+	server   *httptest.Server
+
+	mu   sync.Mutex
+	file liveReplayFile
+}
+
+// newLiveRecordingServer starts a proxy that forwards Live connections to
+// upstream, for example "wss://generativelanguage.googleapis.com", and writes
+// the recording to replayFilePath when the test ends. Point the client's
+// [HTTPOptions.BaseURL] to [liveRecordingServer.URL].
+func newLiveRecordingServer(t *testing.T, upstream, replayFilePath string) *liveRecordingServer {
+	t.Helper()
+	rs := &liveRecordingServer{
+		t:        t,
+		upstream: strings.TrimSuffix(upstream, "/"),
+		path:     replayFilePath,
+		file:     liveReplayFile{ReplayID: strings.TrimSuffix(filepath.Base(replayFilePath), filepath.Ext(replayFilePath))},
+	}
+	rs.server = httptest.NewServer(rs)
+	t.Cleanup(func() {
+		rs.server.Close()
+		if err := rs.write(); err != nil {
+			t.Errorf("error writing Live replay file: %v", err)
+		}
+	})
+	return rs
+}
+
+// InternalLiveRecordingServer records Live sessions to a replay file.
+type InternalLiveRecordingServer = liveRecordingServer
+
+// NewInternalLiveRecordingServer starts a proxy that records Live sessions to
+// a replay file.
+func NewInternalLiveRecordingServer(t *testing.T, upstream, replayFilePath string) *InternalLiveRecordingServer {
+	return newLiveRecordingServer(t, upstream, replayFilePath)
+}
+
+// URL returns the WebSocket base URL of the proxy.
+func (rs *liveRecordingServer) URL() string {
+	return strings.Replace(rs.server.URL, "http", "ws", 1)
+}
+
+func (rs *liveRecordingServer) write() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	data, err := json.MarshalIndent(rs.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(rs.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(rs.path, data, 0o644)
+}
+
+// ServeHTTP proxies a single Live connection and records its frames.
+func (rs *liveRecordingServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	header := http.Header{}
+	for k, v := range req.Header {
+		if !liveReplayIgnoredHeaders[strings.ToLower(k)] {
+			header[k] = v
+		}
+	}
+	upstream, _, err := websocket.DefaultDialer.DialContext(req.Context(), rs.upstream+req.URL.RequestURI(), header)
+	if err != nil {
+		rs.t.Errorf("error connecting to upstream Live endpoint: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+	var upgrader = websocket.Upgrader{}
+	client, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		rs.t.Errorf("error upgrading Live connection: %v", err)
+		return
+	}
+	defer client.Close()
+
+	url, headers := redactLiveHandshake(req)
+	connection := &liveReplayConnection{URL: url, Headers: headers}
+	rs.mu.Lock()
+	rs.file.Connections = append(rs.file.Connections, connection)
+	rs.mu.Unlock()
+	start := time.Now()
+	record := func(frame *liveReplayFrame) {
+		frame.OffsetMillis = time.Since(start).Milliseconds()
+		rs.mu.Lock()
+		connection.Frames = append(connection.Frames, frame)
+		rs.mu.Unlock()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			messageType, data, err := client.ReadMessage()
+			if err != nil {
+				upstream.Close()
+				return
+			}
+			var body any
+			if err := json.Unmarshal(data, &body); err != nil {
+				rs.t.Errorf("error unmarshalling Live client message: %v", err)
+				return
+			}
+			redacted, err := json.Marshal(redactLiveValue(body))
+			if err != nil {
+				rs.t.Errorf("error marshalling Live client message: %v", err)
+				return
+			}
+			record(&liveReplayFrame{Direction: liveReplayClientFrame, Binary: messageType == websocket.BinaryMessage, Body: redacted})
+			if err := upstream.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		messageType, data, err := upstream.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				record(&liveReplayFrame{Direction: liveReplayServerFrame, CloseCode: closeErr.Code, CloseReason: closeErr.Text})
+				closeMessage := websocket.FormatCloseMessage(closeErr.Code, closeErr.Text)
+				_ = client.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+			}
+			client.Close()
+			break
+		}
+		record(&liveReplayFrame{Direction: liveReplayServerFrame, Binary: messageType == websocket.BinaryMessage, Body: data})
+		if err := client.WriteMessage(messageType, data); err != nil {
+			break
+		}
+	}
+	<-done
+}
+
+// liveReplayServer is a WebSocket server that replays a recorded Live session.
+// It asserts that the client sends the recorded messages, after redaction, and
+// answers with the recorded server messages.
+type liveReplayServer struct {
+	// AssertRequest enables comparing the handshakes and client messages with
+	// the recording.
+	AssertRequest bool
+	// RealTime replays the server messages with their recorded timing instead
+	// of as soon as possible.
+	RealTime bool
+
+	t      *testing.T
+	file   *liveReplayFile
+	server *httptest.Server
+
+	mu sync.Mutex
+	// connectionIndex is the index of the next connection to replay.
+	connectionIndex int
+}
+
+// newLiveReplayServer starts a server that replays the Live session recorded
+// in replayFilePath. Point the client's [HTTPOptions.BaseURL] to
+// [liveReplayServer.URL].
+func newLiveReplayServer(t *testing.T, replayFilePath string) *liveReplayServer {
+	t.Helper()
+	data, err := os.ReadFile(replayFilePath)
+	if err != nil {
+		t.Fatalf("error loading Live replay file, %v", err)
+	}
+	var file liveReplayFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("error unmarshalling Live replay file, %v", err)
+	}
+	rs := &liveReplayServer{AssertRequest: true, t: t, file: &file}
+	rs.server = httptest.NewServer(rs)
+	t.Cleanup(rs.server.Close)
+	return rs
+}
+
+// InternalLiveReplayServer replays a recorded Live session.
+type InternalLiveReplayServer = liveReplayServer
+
+// NewInternalLiveReplayServer starts a server that replays a recorded Live
+// session.
+func NewInternalLiveReplayServer(t *testing.T, replayFilePath string) *InternalLiveReplayServer {
+	return newLiveReplayServer(t, replayFilePath)
+}
+
+// URL returns the WebSocket base URL of the server.
+func (rs *liveReplayServer) URL() string {
+	return strings.Replace(rs.server.URL, "http", "ws", 1)
+}
+
+// ServeHTTP replays the next recorded connection.
+func (rs *liveReplayServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rs.mu.Lock()
+	index := rs.connectionIndex
+	rs.connectionIndex++
+	rs.mu.Unlock()
+	if index >= len(rs.file.Connections) {
+		rs.t.Errorf("no more connections in Live replay session %s", rs.file.ReplayID)
+		http.Error(w, "no more connections in replay session", http.StatusNotFound)
+		return
+	}
+	connection := rs.file.Connections[index]
+
+	if rs.AssertRequest {
+		url, headers := redactLiveHandshake(req)
+		got := map[string]any{"url": url, "headers": headers}
+		want := map[string]any{"url": connection.URL, "headers": connection.Headers}
+		if diff := cmp.Diff(got, want, stringComparator); diff != "" {
+			rs.t.Errorf("Live handshake %d had diffs (-got +want):\n%v", index, diff)
+		}
+	}
+
+	var upgrader = websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		rs.t.Errorf("error upgrading Live connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	for i, frame := range connection.Frames {
+		switch frame.Direction {
+		case liveReplayClientFrame:
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				rs.t.Errorf("error reading Live client message %d of connection %d: %v", i, index, err)
+				return
+			}
+			if rs.AssertRequest {
+				rs.assertClientMessage(data, frame.Body)
+			}
+		case liveReplayServerFrame:
+			if rs.RealTime {
+				time.Sleep(time.Until(start.Add(time.Duration(frame.OffsetMillis) * time.Millisecond)))
+			}
+			if frame.CloseCode != 0 {
+				closeMessage := websocket.FormatCloseMessage(frame.CloseCode, frame.CloseReason)
+				_ = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+				continue
+			}
+			messageType := websocket.TextMessage
+			if frame.Binary {
+				messageType = websocket.BinaryMessage
+			}
+			if err := conn.WriteMessage(messageType, frame.Body); err != nil {
+				rs.t.Errorf("error writing Live server message %d of connection %d: %v", i, index, err)
+				return
+			}
+		default:
+			rs.t.Errorf("unknown direction %q of Live frame %d of connection %d", frame.Direction, i, index)
+			return
+		}
+	}
+	// Wait for the client to close the connection.
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (rs *liveReplayServer) assertClientMessage(data []byte, recorded json.RawMessage) {
+	got, err := normalizeLiveClientMessage(data)
+	if err != nil {
+		rs.t.Errorf("error unmarshalling Live client message: %v", err)
+		return
+	}
+	want, err := normalizeLiveClientMessage(recorded)
+	if err != nil {
+		rs.t.Errorf("error unmarshalling recorded Live client message: %v", err)
+		return
+	}
+	if diff := cmp.Diff(got, want, stringComparator, floatComparator); diff != "" {
+		rs.t.Errorf("Live client messages had diffs (-got +want):\n%v", diff)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+)
+
+// liveReplayConversation connects to baseURL, sends a turn once the setup is
+// complete and returns the text of the received messages until the server
+// closes the connection.
+func liveReplayConversation(t *testing.T, baseURL string) []string {
+	t.Helper()
+	ctx := context.Background()
+	client, err := NewClient(ctx, &ClientConfig{
+		Backend:     BackendGeminiAPI,
+		APIKey:      "test-api-key",
+		HTTPOptions: HTTPOptions{BaseURL: baseURL},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	session, err := client.Live.Connect(ctx, "test-model", &LiveConnectConfig{})
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer session.Close()
+	var got []string
+	for {
+		message, err := session.Receive()
+		if err != nil {
+			got = append(got, "error: "+err.Error())
+			return got
+		}
+		switch {
+		case message.SetupComplete != nil:
+			got = append(got, "setup complete")
+			if err := session.SendClientContent(LiveClientContentInput{Turns: Text("Hello")}); err != nil {
+				t.Fatalf("SendClientContent failed: %v", err)
+			}
+		case message.ServerContent != nil:
+			got = append(got, message.ServerContent.ModelTurn.Parts[0].Text)
+		}
+	}
+}
+
+func TestLiveReplay(t *testing.T) {
+	// upstream answers the setup and the first turn, then closes the connection.
+	var upgrader = websocket.Upgrader{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		responses := []string{
+			`{"setupComplete":{}}`,
+			`{"serverContent":{"modelTurn":{"role":"model","parts":[{"text":"Hi there"}]},"turnComplete":true}}`,
+		}
+		for _, response := range responses {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(response)); err != nil {
+				return
+			}
+		}
+		closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "done")
+		_ = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+	}))
+	defer upstream.Close()
+
+	replayFile := filepath.Join(t.TempDir(), "live", "conversation.json")
+	var recorded []string
+	t.Run("Record", func(t *testing.T) {
+		recorder := newLiveRecordingServer(t, strings.Replace(upstream.URL, "http", "ws", 1), replayFile)
+		recorded = liveReplayConversation(t, recorder.URL())
+	})
+
+	data, err := os.ReadFile(replayFile)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	var file liveReplayFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if file.ReplayID != "conversation" {
+		t.Errorf("ReplayID = %q, want %q", file.ReplayID, "conversation")
+	}
+	if len(file.Connections) != 1 {
+		t.Fatalf("got %d connections, want 1", len(file.Connections))
+	}
+	connection := file.Connections[0]
+	if got, want := connection.Headers["x-goog-api-key"], "{REDACTED}"; got != want {
+		t.Errorf("x-goog-api-key header = %q, want %q", got, want)
+	}
+	var directions []string
+	for _, frame := range connection.Frames {
+		directions = append(directions, frame.Direction)
+	}
+	wantDirections := []string{"client", "server", "client", "server", "server"}
+	if diff := cmp.Diff(wantDirections, directions); diff != "" {
+		t.Errorf("frame directions mismatch (-want +got):\n%s", diff)
+	}
+	if last := connection.Frames[len(connection.Frames)-1]; last.CloseCode != websocket.CloseNormalClosure || last.CloseReason != "done" {
+		t.Errorf("last frame = %+v, want normal close with reason %q", last, "done")
+	}
+
+	t.Run("Replay", func(t *testing.T) {
+		replay := newLiveReplayServer(t, replayFile)
+		replay.RealTime = true
+		got := liveReplayConversation(t, replay.URL())
+		if diff := cmp.Diff(recorded, got); diff != "" {
+			t.Errorf("replayed messages mismatch (-recorded +replayed):\n%s", diff)
+		}
+	})
+}
+
+func TestNormalizeLiveClientMessage(t *testing.T) {
+	got, err := normalizeLiveClientMessage([]byte(`{
+		"setup": {
+			"model": "projects/my-project/locations/us-central1/publishers/google/models/test-model",
+			"generation_config": {"temperature": 0, "response_modalities": ["AUDIO"]},
+			"tools": [{"retrieval": {"vertex_rag_store": {"rag_resources": [{"rag_corpus": "projects/p/locations/l/ragCorpora/1"}]}}}]
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("normalizeLiveClientMessage failed: %v", err)
+	}
+	want := map[string]any{
+		"setup": map[string]any{
+			"model":            "{PROJECT_AND_LOCATION_PATH}/publishers/google/models/test-model",
+			"generationConfig": map[string]any{"responseModalities": []any{"AUDIO"}},
+			"tools": []any{map[string]any{"retrieval": map[string]any{"vertexRagStore": map[string]any{
+				"ragResources": []any{map[string]any{"ragCorpus": "{PROJECT_AND_LOCATION_PATH}/ragCorpora/1"}},
+			}}}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("normalizeLiveClientMessage mismatch (-want +got):\n%s", diff)
+	}
+}
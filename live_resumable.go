@@ -0,0 +1,468 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxReconnectAttempts = 3
+	defaultReconnectBackoff     = time.Second
+	defaultReconnectTimeout     = 30 * time.Second
+	defaultMaxPendingMessages   = 1000
+)
+
+// Preview. ResumableSessionConfig configures a [ResumableSession].
+type ResumableSessionConfig struct {
+	// Optional. MaxReconnectAttempts is the number of consecutive attempts made
+	// to resume the session before it fails. If zero, 3 attempts are made.
+	MaxReconnectAttempts int
+	// Optional. ReconnectBackoff is the delay before the second reconnection
+	// attempt. The delay doubles on each following attempt. If zero, 1 second is
+	// used.
+	ReconnectBackoff time.Duration
+	// Optional. ReconnectTimeout bounds each reconnection attempt, including
+	// sending the setup message. If zero, 30 seconds is used.
+	ReconnectTimeout time.Duration
+	// Optional. MaxPendingMessages is the maximum number of client messages
+	// buffered until the server acknowledges them. Once it is reached, the
+	// oldest message is dropped and isn't replayed if the session is resumed.
+	// If zero, 1000 is used.
+	MaxPendingMessages int
+}
+
+// Preview. ResumableSession is a Live session that survives the server
+// closing the connection. It tracks the latest resumable handle from
+// [LiveServerSessionResumptionUpdate] messages and, when the server sends
+// [LiveServerGoAway] or the connection drops, opens a new connection that
+// resumes the session with that handle.
+//
+// Client messages that the server hasn't acknowledged yet are buffered and
+// replayed on the new connection. If [SessionResumptionConfig.Transparent] is
+// set, messages are acknowledged by
+// [LiveServerSessionResumptionUpdate.LastConsumedClientMessageIndex].
+// Otherwise a new resumable handle only acknowledges the messages sent before
+// the server message preceding it was received: messages sent later may have
+// reached the server after the handle was generated, so they are kept until
+// the next handle. Transparent resumption is only supported by Vertex AI. At
+// most [ResumableSessionConfig.MaxPendingMessages] messages are buffered.
+//
+// [ResumableSession.Messages] and [ResumableSession.Receive] return a single
+// continuous stream across connections. The SetupComplete message of a
+// resumed connection is not returned.
+//
+// A ResumableSession is safe for concurrent use.
+type ResumableSession struct {
+	live        *Live
+	model       string
+	config      LiveConnectConfig
+	transparent bool
+
+	maxAttempts int
+	backoff     time.Duration
+	timeout     time.Duration
+	maxPending  int
+
+	// mu guards the fields below.
+	mu sync.Mutex
+	// session is the current connection. It is nil while reconnecting.
+	session *Session
+	// resumed is true until the SetupComplete of a resumed connection is seen.
+	resumed bool
+	handle  string
+	// pending holds the client messages that aren't acknowledged yet, in the
+	// order they were sent.
+	pending []liveBufferedMessage
+	// sent is the index of the last message sent on the current connection.
+	sent int64
+	// received is the number of server messages received.
+	received int64
+
+	results chan liveReceiveResult
+	// readErr is the error that ended the session. It is set before results is
+	// closed.
+	readErr error
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// liveBufferedMessage is an encoded client message waiting to be acknowledged.
+type liveBufferedMessage struct {
+	// index is the position of the message among the client messages sent on
+	// the current connection, starting at 1. It is 0 if the message was
+	// buffered while reconnecting and hasn't been sent yet.
+	index int64
+	// seen is the number of server messages received when the message was
+	// sent.
+	seen int64
+	data []byte
+}
+
+// Preview. ConnectResumable establishes a Live connection like [Live.Connect]
+// and returns a [ResumableSession] that transparently reconnects when the
+// connection ends.
+//
+// Session resumption is enabled on config if it isn't already. If
+// config.SessionResumption.Handle is set, the session resumes from that
+// handle. The context only bounds the initial connection.
+func (r *Live) ConnectResumable(ctx context.Context, model string, config *LiveConnectConfig, resumableConfig *ResumableSessionConfig) (*ResumableSession, error) {
+	var cfg LiveConnectConfig
+	if config != nil {
+		cfg = *config
+	}
+	var resumption SessionResumptionConfig
+	if cfg.SessionResumption != nil {
+		resumption = *cfg.SessionResumption
+	}
+	cfg.SessionResumption = &resumption
+	if resumableConfig == nil {
+		resumableConfig = &ResumableSessionConfig{}
+	}
+
+	session, err := r.Connect(ctx, model, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &ResumableSession{
+		live:        r,
+		model:       model,
+		config:      cfg,
+		transparent: resumption.Transparent,
+		maxAttempts: resumableConfig.MaxReconnectAttempts,
+		backoff:     resumableConfig.ReconnectBackoff,
+		timeout:     resumableConfig.ReconnectTimeout,
+		maxPending:  resumableConfig.MaxPendingMessages,
+		session:     session,
+		handle:      resumption.Handle,
+		results:     make(chan liveReceiveResult),
+		done:        make(chan struct{}),
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = defaultMaxReconnectAttempts
+	}
+	if s.backoff <= 0 {
+		s.backoff = defaultReconnectBackoff
+	}
+	if s.timeout <= 0 {
+		s.timeout = defaultReconnectTimeout
+	}
+	if s.maxPending <= 0 {
+		s.maxPending = defaultMaxPendingMessages
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run()
+	return s, nil
+}
+
+// Preview. Handle returns the latest resumable handle received from the
+// server, or the handle the session was connected with if none was received
+// yet. It can be used in [SessionResumptionConfig.Handle] to resume the
+// session later.
+func (s *ResumableSession) Handle() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handle
+}
+
+// Preview. SendClientContent is like [Session.SendClientContent]. The message
+// is replayed after a reconnection until the server acknowledges it.
+func (s *ResumableSession) SendClientContent(input LiveClientContentInput) error {
+	data, err := encodeLiveClientMessage(s.live.apiClient, input.toLiveClientMessage())
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+// Preview. SendRealtimeInput is like [Session.SendRealtimeInput]. The message
+// is replayed after a reconnection until the server acknowledges it.
+func (s *ResumableSession) SendRealtimeInput(input LiveRealtimeInput) error {
+	data, err := encodeLiveRealtimeInput(s.live.apiClient, input)
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+// Preview. SendToolResponse is like [Session.SendToolResponse]. The message
+// is replayed after a reconnection until the server acknowledges it.
+func (s *ResumableSession) SendToolResponse(input LiveToolResponseInput) error {
+	data, err := encodeLiveClientMessage(s.live.apiClient, input.toLiveClientMessage())
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+// write buffers data and sends it on the current connection. While
+// reconnecting, data is only buffered and is sent once the new connection is
+// established.
+func (s *ResumableSession) write(data []byte) error {
+	select {
+	case <-s.done:
+		return ErrLiveSessionClosed
+	default:
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	message := liveBufferedMessage{seen: s.received, data: data}
+	if s.session != nil {
+		s.sent++
+		message.index = s.sent
+		// A failed write means the connection is going away. The reader
+		// notices it and the message is replayed on the next connection.
+		_ = s.session.write(data)
+	}
+	if s.maxPending > 0 && len(s.pending) >= s.maxPending {
+		// Without acknowledgements, for example if the server doesn't send
+		// resumable handles, the buffer would grow for the whole session.
+		s.pending = slices.Delete(s.pending, 0, len(s.pending)-s.maxPending+1)
+	}
+	s.pending = append(s.pending, message)
+	return nil
+}
+
+// Preview. Receive is like [Session.Receive], but continues across
+// reconnections. It returns an error that ends the session only if the
+// session can't be resumed.
+func (s *ResumableSession) Receive() (*LiveServerMessage, error) {
+	return s.ReceiveContext(context.Background())
+}
+
+// Preview. ReceiveContext is like [ResumableSession.Receive] but returns
+// ctx.Err() if ctx is done before a message is received.
+func (s *ResumableSession) ReceiveContext(ctx context.Context) (*LiveServerMessage, error) {
+	select {
+	case r, ok := <-s.results:
+		if !ok {
+			return nil, s.readErr
+		}
+		return r.message, r.err
+	case <-s.done:
+		return nil, ErrLiveSessionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Preview. Messages is like [Session.Messages], but the iteration continues
+// across reconnections. It ends after yielding an error if the session can't
+// be resumed, when ctx is done, or when the session is closed.
+func (s *ResumableSession) Messages(ctx context.Context) iter.Seq2[*LiveServerMessage, error] {
+	return func(yield func(*LiveServerMessage, error) bool) {
+		for {
+			select {
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			case <-s.done:
+				return
+			case r, ok := <-s.results:
+				if !ok {
+					yield(nil, s.readErr)
+					return
+				}
+				if !yield(r.message, r.err) || r.fatal {
+					return
+				}
+			}
+		}
+	}
+}
+
+// run reads from the current connection and reconnects when it ends.
+func (s *ResumableSession) run() {
+	defer close(s.results)
+	for {
+		s.mu.Lock()
+		session := s.session
+		s.mu.Unlock()
+
+		r := session.receive(s.ctx)
+		if r.fatal {
+			if s.ctx.Err() != nil {
+				s.readErr = ErrLiveSessionClosed
+				return
+			}
+			if s.Handle() == "" {
+				s.fail(r.err)
+				return
+			}
+			if err := s.reconnect(); err != nil {
+				s.fail(fmt.Errorf("failed to resume live session after %w: %w", r.err, err))
+				return
+			}
+			continue
+		}
+
+		if r.message != nil && !s.observe(r.message) {
+			continue
+		}
+		if !s.deliver(r) {
+			s.readErr = ErrLiveSessionClosed
+			return
+		}
+		if r.message != nil && r.message.GoAway != nil {
+			if err := s.reconnect(); err != nil {
+				s.fail(fmt.Errorf("failed to resume live session after GoAway: %w", err))
+				return
+			}
+		}
+	}
+}
+
+// observe records the resumption state carried by message. It returns false
+// if the message must not be returned to the caller.
+func (s *ResumableSession) observe(message *LiveServerMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received++
+	if message.SetupComplete != nil && s.resumed {
+		s.resumed = false
+		return false
+	}
+	update := message.SessionResumptionUpdate
+	if update == nil || !update.Resumable || update.NewHandle == "" {
+		return true
+	}
+	s.handle = update.NewHandle
+	// Keep the messages the new handle doesn't include, including the ones
+	// not sent on this connection yet.
+	kept := s.pending[:0]
+	for _, m := range s.pending {
+		included := m.index <= update.LastConsumedClientMessageIndex
+		if !s.transparent {
+			// The handle was generated after the server sent the message
+			// preceding it, so it may not include the messages sent after that
+			// message was received.
+			included = m.seen < s.received-1
+		}
+		if m.index == 0 || !included {
+			kept = append(kept, m)
+		}
+	}
+	s.pending = kept
+	return true
+}
+
+// reconnect replaces the current connection with one that resumes the
+// session from the latest handle, and replays the pending messages on it.
+func (s *ResumableSession) reconnect() error {
+	s.mu.Lock()
+	old := s.session
+	s.session = nil
+	handle := s.handle
+	s.mu.Unlock()
+	old.Close()
+
+	cfg := s.config
+	resumption := *cfg.SessionResumption
+	resumption.Handle = handle
+	cfg.SessionResumption = &resumption
+
+	backoff := s.backoff
+	var err error
+	for attempt := 0; attempt < s.maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-s.done:
+				return ErrLiveSessionClosed
+			}
+			backoff *= 2
+		}
+		ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+		var session *Session
+		session, err = s.live.Connect(ctx, s.model, &cfg)
+		cancel()
+		if err != nil {
+			continue
+		}
+		if err = s.replay(session); err != nil {
+			session.Close()
+			continue
+		}
+		return nil
+	}
+	return err
+}
+
+// replay sends the pending messages on session and makes it the current
+// connection.
+func (s *ResumableSession) replay(session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return ErrLiveSessionClosed
+	default:
+	}
+	var index int64
+	for i := range s.pending {
+		index++
+		s.pending[i].index = index
+		s.pending[i].seen = s.received
+		if err := session.write(s.pending[i].data); err != nil {
+			return err
+		}
+	}
+	s.sent = index
+	s.session = session
+	s.resumed = true
+	return nil
+}
+
+// fail delivers err as the error that ends the session.
+func (s *ResumableSession) fail(err error) {
+	s.readErr = err
+	s.deliver(liveReceiveResult{err: err, fatal: true})
+}
+
+// deliver hands a result to a waiting receiver. It returns false if the
+// session was closed before the result could be delivered.
+func (s *ResumableSession) deliver(r liveReceiveResult) bool {
+	select {
+	case s.results <- r:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// Preview. Close terminates the session and its current connection. It is
+// safe to call Close more than once.
+func (s *ResumableSession) Close() error {
+	if s == nil {
+		return nil
+	}
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.cancel()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		err = s.session.Close()
+	})
+	return err
+}
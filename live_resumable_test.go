@@ -0,0 +1,297 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+)
+
+func TestResumableSession(t *testing.T) {
+	tests := []struct {
+		name string
+		// endFirstConnection ends the first connection after the client message
+		// was received.
+		endFirstConnection func(conn *websocket.Conn) error
+		wantMessages       []*LiveServerMessage
+	}{
+		{
+			name: "GoAway",
+			endFirstConnection: func(conn *websocket.Conn) error {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"goAway":{"timeLeft":"10s"}}`)); err != nil {
+					return err
+				}
+				// Wait for the client to go away.
+				_, _, _ = conn.ReadMessage()
+				return nil
+			},
+			wantMessages: []*LiveServerMessage{
+				{SetupComplete: &LiveServerSetupComplete{}},
+				{SessionResumptionUpdate: &LiveServerSessionResumptionUpdate{NewHandle: "handle-1", Resumable: true}},
+				{ServerContent: &LiveServerContent{GenerationComplete: true}},
+				{SessionResumptionUpdate: &LiveServerSessionResumptionUpdate{NewHandle: "handle-2", Resumable: true}},
+				{SessionResumptionUpdate: &LiveServerSessionResumptionUpdate{Resumable: false}},
+				{GoAway: &LiveServerGoAway{TimeLeft: 10 * time.Second}},
+				{ServerContent: &LiveServerContent{TurnComplete: true}},
+			},
+		},
+		{
+			name: "connection drop",
+			endFirstConnection: func(conn *websocket.Conn) error {
+				return conn.UnderlyingConn().Close()
+			},
+			wantMessages: []*LiveServerMessage{
+				{SetupComplete: &LiveServerSetupComplete{}},
+				{SessionResumptionUpdate: &LiveServerSessionResumptionUpdate{NewHandle: "handle-1", Resumable: true}},
+				{ServerContent: &LiveServerContent{GenerationComplete: true}},
+				{SessionResumptionUpdate: &LiveServerSessionResumptionUpdate{NewHandle: "handle-2", Resumable: true}},
+				{SessionResumptionUpdate: &LiveServerSessionResumptionUpdate{Resumable: false}},
+				{ServerContent: &LiveServerContent{TurnComplete: true}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var connections atomic.Int32
+			var upgrader = websocket.Upgrader{}
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				conn, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					t.Errorf("Upgrade failed: %v", err)
+					return
+				}
+				defer conn.Close()
+				_, setup, err := conn.ReadMessage()
+				if err != nil {
+					t.Errorf("ReadMessage failed: %v", err)
+					return
+				}
+				write := func(messages ...string) bool {
+					for _, m := range messages {
+						if err := conn.WriteMessage(websocket.TextMessage, []byte(m)); err != nil {
+							t.Errorf("WriteMessage failed: %v", err)
+							return false
+						}
+					}
+					return true
+				}
+				read := func(want string) bool {
+					_, message, err := conn.ReadMessage()
+					if err != nil {
+						t.Errorf("ReadMessage failed: %v", err)
+						return false
+					}
+					if !strings.Contains(string(message), want) {
+						t.Errorf("server received %s, want message containing %q", message, want)
+					}
+					return true
+				}
+
+				switch connections.Add(1) {
+				case 1:
+					if strings.Contains(string(setup), `"handle"`) {
+						t.Errorf("first setup = %s, want no resumption handle", setup)
+					}
+					if !write(`{"setupComplete":{}}`, `{"sessionResumptionUpdate":{"newHandle":"handle-1","resumable":true}}`) {
+						return
+					}
+					// "first" is included in handle-2 since it was sent before the
+					// response preceding handle-2 was received, "second" isn't.
+					if !read(`"text":"first"`) || !write(`{"serverContent":{"generationComplete":true}}`, `{"sessionResumptionUpdate":{"newHandle":"handle-2","resumable":true}}`) {
+						return
+					}
+					if !read(`"text":"second"`) || !write(`{"sessionResumptionUpdate":{"resumable":false}}`) || !read(`"text":"third"`) {
+						return
+					}
+					if err := tt.endFirstConnection(conn); err != nil {
+						t.Errorf("ending first connection failed: %v", err)
+					}
+				case 2:
+					if !strings.Contains(string(setup), `"handle":"handle-2"`) {
+						t.Errorf("resumed setup = %s, want handle-2", setup)
+					}
+					if !write(`{"setupComplete":{}}`) || !read(`"text":"second"`) || !read(`"text":"third"`) || !write(`{"serverContent":{"turnComplete":true}}`) {
+						return
+					}
+					_, _, _ = conn.ReadMessage()
+				default:
+					t.Errorf("unexpected connection %d", connections.Load())
+				}
+			}))
+			defer ts.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			session, err := newTestLiveClient(t, ts).Live.ConnectResumable(ctx, "test-model", nil, &ResumableSessionConfig{ReconnectBackoff: 10 * time.Millisecond})
+			if err != nil {
+				t.Fatalf("ConnectResumable failed: %v", err)
+			}
+			defer session.Close()
+
+			var gotMessages []*LiveServerMessage
+			for message, err := range session.Messages(ctx) {
+				if err != nil {
+					t.Fatalf("Messages() error: %v", err)
+				}
+				gotMessages = append(gotMessages, message)
+				if update := message.SessionResumptionUpdate; update != nil {
+					input := map[string]string{"handle-1": "first", "handle-2": "second", "": "third"}[update.NewHandle]
+					if err := session.SendRealtimeInput(LiveRealtimeInput{Text: input}); err != nil {
+						t.Fatalf("SendRealtimeInput failed: %v", err)
+					}
+				}
+				if message.ServerContent != nil && message.ServerContent.TurnComplete {
+					break
+				}
+			}
+
+			if diff := cmp.Diff(tt.wantMessages, gotMessages); diff != "" {
+				t.Errorf("Messages() mismatch (-want +got):\n%s", diff)
+			}
+			if got := session.Handle(); got != "handle-2" {
+				t.Errorf("Handle() = %q, want %q", got, "handle-2")
+			}
+			if got := connections.Load(); got != 2 {
+				t.Errorf("server saw %d connections, want 2", got)
+			}
+		})
+	}
+}
+
+func TestResumableSessionWithoutHandle(t *testing.T) {
+	var upgrader = websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Errorf("ReadMessage failed: %v", err)
+			return
+		}
+		closeMessage := websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "internal error")
+		_ = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	session, err := newTestLiveClient(t, ts).Live.ConnectResumable(ctx, "test-model", nil, nil)
+	if err != nil {
+		t.Fatalf("ConnectResumable failed: %v", err)
+	}
+	defer session.Close()
+
+	_, err = session.ReceiveContext(ctx)
+	want := LiveCloseError{Code: websocket.CloseInternalServerErr, Reason: "internal error"}
+	if err != want {
+		t.Errorf("ReceiveContext() error = %v, want %v", err, want)
+	}
+}
+
+func TestResumableSessionTransparentAcknowledgement(t *testing.T) {
+	s := &ResumableSession{
+		transparent: true,
+		pending: []liveBufferedMessage{
+			{index: 1, data: []byte("1")},
+			{index: 2, data: []byte("2")},
+			{index: 3, data: []byte("3")},
+			// Buffered while reconnecting.
+			{index: 0, data: []byte("4")},
+		},
+	}
+
+	// Updates that can't be resumed from don't acknowledge anything.
+	s.observe(&LiveServerMessage{SessionResumptionUpdate: &LiveServerSessionResumptionUpdate{LastConsumedClientMessageIndex: 3}})
+	if got := len(s.pending); got != 4 {
+		t.Errorf("after non-resumable update, got %d pending messages, want 4", got)
+	}
+
+	s.observe(&LiveServerMessage{SessionResumptionUpdate: &LiveServerSessionResumptionUpdate{NewHandle: "handle", Resumable: true, LastConsumedClientMessageIndex: 2}})
+	var got []string
+	for _, m := range s.pending {
+		got = append(got, string(m.data))
+	}
+	if diff := cmp.Diff([]string{"3", "4"}, got); diff != "" {
+		t.Errorf("pending messages mismatch (-want +got):\n%s", diff)
+	}
+	if s.handle != "handle" {
+		t.Errorf("handle = %q, want %q", s.handle, "handle")
+	}
+}
+
+func TestResumableSessionAcknowledgement(t *testing.T) {
+	s := &ResumableSession{}
+	send := func(data string) {
+		s.sent++
+		s.pending = append(s.pending, liveBufferedMessage{index: s.sent, seen: s.received, data: []byte(data)})
+	}
+	pending := func() []string {
+		var got []string
+		for _, m := range s.pending {
+			got = append(got, string(m.data))
+		}
+		return got
+	}
+	content := &LiveServerMessage{ServerContent: &LiveServerContent{GenerationComplete: true}}
+	update := func(handle string) *LiveServerMessage {
+		return &LiveServerMessage{SessionResumptionUpdate: &LiveServerSessionResumptionUpdate{NewHandle: handle, Resumable: true}}
+	}
+
+	s.observe(&LiveServerMessage{SetupComplete: &LiveServerSetupComplete{}})
+	send("1")
+	s.observe(content)
+	// The server may have generated handle-1 before receiving "2".
+	send("2")
+	s.observe(update("handle-1"))
+	if diff := cmp.Diff([]string{"2"}, pending()); diff != "" {
+		t.Errorf("pending messages after handle-1 mismatch (-want +got):\n%s", diff)
+	}
+
+	s.observe(content)
+	send("3")
+	s.observe(update("handle-2"))
+	if diff := cmp.Diff([]string{"3"}, pending()); diff != "" {
+		t.Errorf("pending messages after handle-2 mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResumableSessionMaxPendingMessages(t *testing.T) {
+	s := &ResumableSession{maxPending: 2}
+	for _, data := range []string{"1", "2", "3"} {
+		if err := s.write([]byte(data)); err != nil {
+			t.Fatalf("write(%q) failed: %v", data, err)
+		}
+	}
+	var got []string
+	for _, m := range s.pending {
+		got = append(got, string(m.data))
+	}
+	if diff := cmp.Diff([]string{"2", "3"}, got); diff != "" {
+		t.Errorf("pending messages mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -16,10 +16,15 @@ package genai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/auth"
 	"github.com/google/go-cmp/cmp"
@@ -583,3 +588,501 @@ func setupTestWebsocketServer(t *testing.T, wantRequestBodySlice []string, fakeR
 
 	return ts
 }
+
+func newTestLiveClient(t *testing.T, ts *httptest.Server) *Client {
+	t.Helper()
+	return newTestLiveClientWithOptions(t, ts, LiveOptions{})
+}
+
+func newTestLiveClientWithOptions(t *testing.T, ts *httptest.Server, options LiveOptions) *Client {
+	t.Helper()
+	client, err := NewClient(context.Background(), &ClientConfig{
+		Backend:     BackendGeminiAPI,
+		APIKey:      "test-api-key",
+		HTTPOptions: HTTPOptions{BaseURL: strings.Replace(ts.URL, "http", "ws", 1)},
+		HTTPClient:  ts.Client(),
+		LiveOptions: options,
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return client
+}
+
+func TestLiveSessionConcurrentSend(t *testing.T) {
+	ctx := context.Background()
+	const senders, messagesPerSender = 8, 20
+
+	received := make(chan string, senders*messagesPerSender)
+	var upgrader = websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		// Setup message.
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Errorf("ReadMessage failed: %v", err)
+			return
+		}
+		for i := 0; i < senders*messagesPerSender; i++ {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				t.Errorf("ReadMessage failed: %v", err)
+				return
+			}
+			received <- string(message)
+		}
+	}))
+	defer ts.Close()
+
+	session, err := newTestLiveClient(t, ts).Live.Connect(ctx, "test-model", nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer session.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < messagesPerSender; j++ {
+				var err error
+				switch i % 3 {
+				case 0:
+					err = session.SendRealtimeInput(LiveRealtimeInput{Text: "text"})
+				case 1:
+					err = session.SendClientContent(LiveClientContentInput{Turns: Text("content")})
+				default:
+					err = session.SendToolResponse(LiveToolResponseInput{FunctionResponses: []*FunctionResponse{{Name: "f"}}})
+				}
+				if err != nil {
+					t.Errorf("send failed: %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < senders*messagesPerSender; i++ {
+		select {
+		case message := <-received:
+			var m map[string]any
+			if err := json.Unmarshal([]byte(message), &m); err != nil {
+				t.Errorf("server received a corrupted frame %q: %v", message, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for message %d", i)
+		}
+	}
+}
+
+func TestLiveSessionMessages(t *testing.T) {
+	ctx := context.Background()
+	var upgrader = websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Errorf("ReadMessage failed: %v", err)
+			return
+		}
+		for _, m := range []string{
+			`{"setupComplete":{}}`,
+			`{"serverContent":{"modelTurn":{"parts":[{"text":"hello"}],"role":"model"}}}`,
+			`{"error":{"code":400,"message":"bad message","status":"INVALID_ARGUMENT"}}`,
+			`{"serverContent":{"turnComplete":true}}`,
+		} {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(m)); err != nil {
+				t.Errorf("WriteMessage failed: %v", err)
+				return
+			}
+		}
+		closeMessage := websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "internal error")
+		_ = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+		// Wait for the client to go away.
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer ts.Close()
+
+	session, err := newTestLiveClient(t, ts).Live.Connect(ctx, "test-model", nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer session.Close()
+
+	var gotMessages []*LiveServerMessage
+	var gotErrs []error
+	for message, err := range session.Messages(ctx) {
+		if err != nil {
+			gotErrs = append(gotErrs, err)
+			continue
+		}
+		gotMessages = append(gotMessages, message)
+	}
+
+	wantMessages := []*LiveServerMessage{
+		{SetupComplete: &LiveServerSetupComplete{}},
+		{ServerContent: &LiveServerContent{ModelTurn: &Content{Role: RoleModel, Parts: []*Part{{Text: "hello"}}}}},
+		{ServerContent: &LiveServerContent{TurnComplete: true}},
+	}
+	if diff := cmp.Diff(wantMessages, gotMessages); diff != "" {
+		t.Errorf("Messages() mismatch (-want +got):\n%s", diff)
+	}
+	if len(gotErrs) != 2 {
+		t.Fatalf("Messages() got %d errors, want 2: %v", len(gotErrs), gotErrs)
+	}
+	if !strings.Contains(gotErrs[0].Error(), "bad message") {
+		t.Errorf("Messages() first error = %v, want error containing %q", gotErrs[0], "bad message")
+	}
+	var closeErr LiveCloseError
+	if !errors.As(gotErrs[1], &closeErr) {
+		t.Fatalf("Messages() last error = %v, want LiveCloseError", gotErrs[1])
+	}
+	if diff := cmp.Diff(LiveCloseError{Code: websocket.CloseInternalServerErr, Reason: "internal error"}, closeErr); diff != "" {
+		t.Errorf("LiveCloseError mismatch (-want +got):\n%s", diff)
+	}
+
+	// The terminating error is sticky.
+	if _, err := session.Receive(); !errors.As(err, &closeErr) {
+		t.Errorf("Receive() after close error = %v, want LiveCloseError", err)
+	}
+}
+
+func TestLiveSessionClose(t *testing.T) {
+	ctx := context.Background()
+	var upgrader = websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+
+	session, err := newTestLiveClient(t, ts).Live.Connect(ctx, "test-model", nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	receiveErr := make(chan error)
+	go func() {
+		_, err := session.Receive()
+		receiveErr <- err
+	}()
+	if err := session.Close(); err != nil {
+		t.Errorf("Close() failed: %v", err)
+	}
+	if err := session.Close(); err != nil {
+		t.Errorf("second Close() failed: %v", err)
+	}
+	select {
+	case err := <-receiveErr:
+		if !errors.Is(err, ErrLiveSessionClosed) {
+			t.Errorf("Receive() error = %v, want %v", err, ErrLiveSessionClosed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Receive() did not return after Close()")
+	}
+	if err := session.SendRealtimeInput(LiveRealtimeInput{Text: "text"}); !errors.Is(err, ErrLiveSessionClosed) {
+		t.Errorf("SendRealtimeInput() after Close() error = %v, want %v", err, ErrLiveSessionClosed)
+	}
+}
+
+func TestLiveConnectContext(t *testing.T) {
+	// A listener that accepts connections but never completes the handshake.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	client, err := NewClient(context.Background(), &ClientConfig{
+		Backend:     BackendGeminiAPI,
+		APIKey:      "test-api-key",
+		HTTPOptions: HTTPOptions{BaseURL: "ws://" + ln.Addr().String()},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = client.Live.Connect(ctx, "test-model", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Connect() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Connect() took %v, want it to honor the context deadline", elapsed)
+	}
+}
+
+func TestLiveSessionReceiveContext(t *testing.T) {
+	release := make(chan struct{})
+	var upgrader = websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Errorf("ReadMessage failed: %v", err)
+			return
+		}
+		<-release
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"setupComplete":{}}`)); err != nil {
+			t.Errorf("WriteMessage failed: %v", err)
+			return
+		}
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer ts.Close()
+
+	session, err := newTestLiveClient(t, ts).Live.Connect(context.Background(), "test-model", nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer session.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := session.ReceiveContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReceiveContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The session is still usable after a receive deadline.
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	message, err := session.ReceiveContext(ctx)
+	if err != nil {
+		t.Fatalf("ReceiveContext() failed: %v", err)
+	}
+	if message.SetupComplete == nil {
+		t.Errorf("ReceiveContext() = %+v, want setupComplete", message)
+	}
+}
+
+func TestLiveSessionKeepalive(t *testing.T) {
+	options := LiveOptions{
+		KeepaliveInterval: 20 * time.Millisecond,
+		KeepaliveTimeout:  20 * time.Millisecond,
+	}
+
+	t.Run("healthy connection", func(t *testing.T) {
+		var upgrader = websocket.Upgrader{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Errorf("Upgrade failed: %v", err)
+				return
+			}
+			defer conn.Close()
+			// Reading answers pings with pongs.
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}))
+		defer ts.Close()
+
+		session, err := newTestLiveClientWithOptions(t, ts, options).Live.Connect(context.Background(), "test-model", nil)
+		if err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer session.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*options.KeepaliveInterval)
+		defer cancel()
+		if _, err := session.ReceiveContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("ReceiveContext() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("half-open connection", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		var upgrader = websocket.Upgrader{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Errorf("Upgrade failed: %v", err)
+				return
+			}
+			defer conn.Close()
+			if _, _, err := conn.ReadMessage(); err != nil {
+				t.Errorf("ReadMessage failed: %v", err)
+				return
+			}
+			// Stop reading so pings are never answered.
+			<-release
+		}))
+		defer ts.Close()
+
+		session, err := newTestLiveClientWithOptions(t, ts, options).Live.Connect(context.Background(), "test-model", nil)
+		if err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer session.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := session.ReceiveContext(ctx); !errors.Is(err, ErrLiveKeepaliveTimeout) {
+			t.Errorf("ReceiveContext() error = %v, want %v", err, ErrLiveKeepaliveTimeout)
+		}
+	})
+}
+
+func TestLiveConnectRequestHTTPOptions(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		desc        string
+		config      *ClientConfig
+		wantPath    string
+		wantHeaders map[string]string
+	}{
+		{
+			desc:     "mldev",
+			config:   &ClientConfig{Backend: BackendGeminiAPI, APIKey: "test-api-key"},
+			wantPath: "/request-path/ws/google.ai.generativelanguage.v1alpha.GenerativeService.BidiGenerateContent",
+			wantHeaders: map[string]string{
+				"Client-Header":  "client-value",
+				"Request-Header": "request-value",
+				"X-Goog-Api-Key": "test-api-key",
+			},
+		},
+		{
+			desc: "vertex",
+			config: &ClientConfig{
+				Backend:  BackendVertexAI,
+				Project:  "test-project",
+				Location: "test-location",
+				Credentials: auth.NewCredentials(&auth.CredentialsOptions{
+					TokenProvider: mockCredentials{MockToken: &auth.Token{Value: "fake_access_token"}},
+				}),
+			},
+			wantPath: "/request-path/ws/google.cloud.aiplatform.v1alpha.LlmBidiService/BidiGenerateContent",
+			wantHeaders: map[string]string{
+				"Client-Header":  "client-value",
+				"Request-Header": "request-value",
+				"Authorization":  "Bearer fake_access_token",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var upgrader = websocket.Upgrader{}
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if diff := cmp.Diff(tt.wantPath, r.URL.Path); diff != "" {
+					t.Errorf("request path mismatch (-want +got):\n%s", diff)
+				}
+				for k, v := range tt.wantHeaders {
+					if got := r.Header.Get(k); got != v {
+						t.Errorf("request header %s = %q, want %q", k, got, v)
+					}
+				}
+				conn, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					t.Errorf("Upgrade failed: %v", err)
+					return
+				}
+				defer conn.Close()
+				_, message, err := conn.ReadMessage()
+				if err != nil {
+					t.Errorf("ReadMessage failed: %v", err)
+					return
+				}
+				if strings.Contains(string(message), "request-value") {
+					t.Errorf("setup message %s contains the request HTTP options", message)
+				}
+			}))
+			defer ts.Close()
+
+			tt.config.HTTPOptions = HTTPOptions{
+				BaseURL: "ws://client-level.invalid",
+				Headers: http.Header{"Client-Header": []string{"client-value"}},
+			}
+			client, err := NewClient(ctx, tt.config)
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+			session, err := client.Live.Connect(ctx, "test-model", &LiveConnectConfig{
+				HTTPOptions: &HTTPOptions{
+					BaseURL:    strings.Replace(ts.URL, "http", "ws", 1) + "/request-path",
+					APIVersion: "v1alpha",
+					Headers:    http.Header{"Request-Header": []string{"request-value"}},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Connect failed: %v", err)
+			}
+			session.Close()
+		})
+	}
+}
+
+func TestLiveConnectNetDialContext(t *testing.T) {
+	var upgrader = websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer ts.Close()
+
+	var dialedAddr string
+	client, err := NewClient(context.Background(), &ClientConfig{
+		Backend:     BackendGeminiAPI,
+		APIKey:      "test-api-key",
+		HTTPOptions: HTTPOptions{BaseURL: "ws://live.invalid"},
+		LiveOptions: LiveOptions{
+			NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dialedAddr = addr
+				var d net.Dialer
+				return d.DialContext(ctx, network, ts.Listener.Addr().String())
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	session, err := client.Live.Connect(context.Background(), "test-model", nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	session.Close()
+	if dialedAddr != "live.invalid:80" {
+		t.Errorf("NetDialContext called with %q, want %q", dialedAddr, "live.invalid:80")
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Preview. LiveToolHandler executes a function call received in a Live
+// session. args are the [FunctionCall.Args] of the call. The returned map is
+// sent as the "output" of the [FunctionResponse], and a non-nil error is sent
+// as its "error".
+//
+// ctx is canceled when the server cancels the call with a
+// [LiveServerToolCallCancellation] or when the context passed to
+// [LiveToolDispatcher.Dispatch] is done.
+type LiveToolHandler func(ctx context.Context, args map[string]any) (map[string]any, error)
+
+// Preview. LiveTool is a function that the model can call in a Live session.
+type LiveTool struct {
+	// Required. Declaration describes the function to the model. Set
+	// Declaration.Behavior to [BehaviorNonBlocking] to let the model continue
+	// while the function runs.
+	Declaration *FunctionDeclaration
+	// Required. Handler executes the function.
+	Handler LiveToolHandler
+	// Optional. Scheduling tells the model how to handle the response of a
+	// non-blocking function. It is ignored for blocking functions. If empty, the
+	// server default is used.
+	Scheduling FunctionResponseScheduling
+}
+
+// Preview. LiveToolResponder sends tool responses in a Live session. It is
+// implemented by [Session] and [ResumableSession].
+type LiveToolResponder interface {
+	SendToolResponse(input LiveToolResponseInput) error
+}
+
+// Preview. LiveToolDispatcher runs the handlers of [LiveTool] functions for
+// the tool calls received in a Live session and sends their responses.
+//
+// Pass the result of [LiveToolDispatcher.Tool] in [LiveConnectConfig.Tools]
+// and call [LiveToolDispatcher.Dispatch] for every received message:
+//
+//	for message, err := range session.Messages(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		if dispatcher.Dispatch(ctx, session, message) {
+//			continue
+//		}
+//		...
+//	}
+//
+// A LiveToolDispatcher is safe for concurrent use.
+type LiveToolDispatcher struct {
+	tools map[string]*LiveTool
+	// declarations keeps the registration order.
+	declarations []*FunctionDeclaration
+
+	wg sync.WaitGroup
+	// mu guards the fields below.
+	mu sync.Mutex
+	// running maps the ID of each running call to the function that cancels it.
+	running map[string]context.CancelFunc
+	errs    []error
+}
+
+// Preview. NewLiveToolDispatcher returns a dispatcher for tools. Each tool
+// must have a declaration with a unique name and a handler.
+func NewLiveToolDispatcher(tools ...*LiveTool) (*LiveToolDispatcher, error) {
+	d := &LiveToolDispatcher{
+		tools:   make(map[string]*LiveTool),
+		running: make(map[string]context.CancelFunc),
+	}
+	for _, tool := range tools {
+		if tool == nil || tool.Declaration == nil || tool.Declaration.Name == "" {
+			return nil, fmt.Errorf("live tool must have a declaration with a name")
+		}
+		name := tool.Declaration.Name
+		if tool.Handler == nil {
+			return nil, fmt.Errorf("live tool %q has no handler", name)
+		}
+		if _, ok := d.tools[name]; ok {
+			return nil, fmt.Errorf("live tool %q is registered more than once", name)
+		}
+		d.tools[name] = tool
+		d.declarations = append(d.declarations, tool.Declaration)
+	}
+	return d, nil
+}
+
+// Preview. Tool returns the declarations of the registered functions, to be
+// used in [LiveConnectConfig.Tools].
+func (d *LiveToolDispatcher) Tool() *Tool {
+	return &Tool{FunctionDeclarations: d.declarations}
+}
+
+// Preview. Dispatch handles the tool calls and cancellations in message. It
+// returns false if message has neither, so that the caller can handle it.
+//
+// Each function call runs its handler in a new goroutine, and its response is
+// sent with responder as soon as the handler returns. Calls canceled by the
+// server are not answered. Calls to unknown functions are answered with an
+// error.
+func (d *LiveToolDispatcher) Dispatch(ctx context.Context, responder LiveToolResponder, message *LiveServerMessage) bool {
+	if message == nil || (message.ToolCall == nil && message.ToolCallCancellation == nil) {
+		return false
+	}
+	if message.ToolCallCancellation != nil {
+		d.mu.Lock()
+		for _, id := range message.ToolCallCancellation.IDs {
+			if cancel, ok := d.running[id]; ok {
+				cancel()
+				delete(d.running, id)
+			}
+		}
+		d.mu.Unlock()
+	}
+	if message.ToolCall != nil {
+		for _, call := range message.ToolCall.FunctionCalls {
+			if call == nil {
+				continue
+			}
+			callCtx, cancel := context.WithCancel(ctx)
+			if call.ID != "" {
+				d.mu.Lock()
+				d.running[call.ID] = cancel
+				d.mu.Unlock()
+			}
+			d.wg.Add(1)
+			go d.run(callCtx, cancel, responder, call)
+		}
+	}
+	return true
+}
+
+// run executes the handler of call and sends its response unless the call was
+// canceled.
+func (d *LiveToolDispatcher) run(ctx context.Context, cancel context.CancelFunc, responder LiveToolResponder, call *FunctionCall) {
+	defer d.wg.Done()
+	defer cancel()
+
+	response := &FunctionResponse{ID: call.ID, Name: call.Name}
+	tool, ok := d.tools[call.Name]
+	if !ok {
+		response.Response = map[string]any{"error": fmt.Sprintf("function %q is not registered", call.Name)}
+	} else {
+		output, err := tool.Handler(ctx, call.Args)
+		if err != nil {
+			response.Response = map[string]any{"error": err.Error()}
+		} else {
+			response.Response = map[string]any{"output": output}
+		}
+		if tool.Declaration.Behavior == BehaviorNonBlocking {
+			response.Scheduling = tool.Scheduling
+		}
+	}
+
+	if call.ID != "" {
+		d.mu.Lock()
+		_, stillRunning := d.running[call.ID]
+		delete(d.running, call.ID)
+		d.mu.Unlock()
+		if !stillRunning {
+			// Canceled by the server.
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err := responder.SendToolResponse(LiveToolResponseInput{FunctionResponses: []*FunctionResponse{response}}); err != nil {
+		d.mu.Lock()
+		d.errs = append(d.errs, fmt.Errorf("failed to send response for function %q: %w", call.Name, err))
+		d.mu.Unlock()
+	}
+}
+
+// Preview. Wait waits for all running handlers to return. It returns the
+// errors that occurred while sending their responses.
+func (d *LiveToolDispatcher) Wait() error {
+	d.wg.Wait()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := errors.Join(d.errs...)
+	d.errs = nil
+	return err
+}
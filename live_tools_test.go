@@ -0,0 +1,176 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	_ LiveToolResponder = (*Session)(nil)
+	_ LiveToolResponder = (*ResumableSession)(nil)
+)
+
+type fakeToolResponder struct {
+	mu        sync.Mutex
+	responses []*FunctionResponse
+	err       error
+}
+
+func (r *fakeToolResponder) SendToolResponse(input LiveToolResponseInput) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, input.FunctionResponses...)
+	return r.err
+}
+
+func TestLiveToolDispatcher(t *testing.T) {
+	ctx := context.Background()
+	handlerCanceled := make(chan error, 1)
+	dispatcher, err := NewLiveToolDispatcher(
+		&LiveTool{
+			Declaration: &FunctionDeclaration{Name: "add"},
+			Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+				return map[string]any{"sum": args["a"].(float64) + args["b"].(float64)}, nil
+			},
+		},
+		&LiveTool{
+			Declaration: &FunctionDeclaration{Name: "fail"},
+			Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+				return nil, errors.New("failed")
+			},
+		},
+		&LiveTool{
+			Declaration: &FunctionDeclaration{Name: "notify", Behavior: BehaviorNonBlocking},
+			Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+				return map[string]any{"sent": true}, nil
+			},
+			Scheduling: FunctionResponseSchedulingSilent,
+		},
+		&LiveTool{
+			Declaration: &FunctionDeclaration{Name: "slow"},
+			Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+				<-ctx.Done()
+				handlerCanceled <- ctx.Err()
+				return nil, ctx.Err()
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewLiveToolDispatcher failed: %v", err)
+	}
+
+	wantTool := &Tool{FunctionDeclarations: []*FunctionDeclaration{
+		{Name: "add"},
+		{Name: "fail"},
+		{Name: "notify", Behavior: BehaviorNonBlocking},
+		{Name: "slow"},
+	}}
+	if diff := cmp.Diff(wantTool, dispatcher.Tool()); diff != "" {
+		t.Errorf("Tool() mismatch (-want +got):\n%s", diff)
+	}
+
+	responder := &fakeToolResponder{}
+	if dispatcher.Dispatch(ctx, responder, &LiveServerMessage{ServerContent: &LiveServerContent{TurnComplete: true}}) {
+		t.Errorf("Dispatch() = true for a message without tool calls, want false")
+	}
+	handled := dispatcher.Dispatch(ctx, responder, &LiveServerMessage{ToolCall: &LiveServerToolCall{
+		FunctionCalls: []*FunctionCall{
+			{ID: "1", Name: "add", Args: map[string]any{"a": 1.0, "b": 2.0}},
+			{ID: "2", Name: "fail"},
+			{ID: "3", Name: "notify"},
+			{ID: "4", Name: "slow"},
+			{ID: "5", Name: "unknown"},
+		},
+	}})
+	if !handled {
+		t.Errorf("Dispatch() = false for a tool call, want true")
+	}
+	if !dispatcher.Dispatch(ctx, responder, &LiveServerMessage{ToolCallCancellation: &LiveServerToolCallCancellation{IDs: []string{"4"}}}) {
+		t.Errorf("Dispatch() = false for a tool call cancellation, want true")
+	}
+
+	select {
+	case err := <-handlerCanceled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("handler context error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler context was not canceled")
+	}
+	if err := dispatcher.Wait(); err != nil {
+		t.Errorf("Wait() failed: %v", err)
+	}
+
+	sort.Slice(responder.responses, func(i, j int) bool { return responder.responses[i].ID < responder.responses[j].ID })
+	want := []*FunctionResponse{
+		{ID: "1", Name: "add", Response: map[string]any{"output": map[string]any{"sum": 3.0}}},
+		{ID: "2", Name: "fail", Response: map[string]any{"error": "failed"}},
+		{ID: "3", Name: "notify", Response: map[string]any{"output": map[string]any{"sent": true}}, Scheduling: FunctionResponseSchedulingSilent},
+		{ID: "5", Name: "unknown", Response: map[string]any{"error": `function "unknown" is not registered`}},
+	}
+	if diff := cmp.Diff(want, responder.responses); diff != "" {
+		t.Errorf("responses mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLiveToolDispatcherSendError(t *testing.T) {
+	dispatcher, err := NewLiveToolDispatcher(&LiveTool{
+		Declaration: &FunctionDeclaration{Name: "f"},
+		Handler: func(ctx context.Context, args map[string]any) (map[string]any, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewLiveToolDispatcher failed: %v", err)
+	}
+	responder := &fakeToolResponder{err: ErrLiveSessionClosed}
+	dispatcher.Dispatch(context.Background(), responder, &LiveServerMessage{ToolCall: &LiveServerToolCall{
+		FunctionCalls: []*FunctionCall{{ID: "1", Name: "f"}},
+	}})
+	if err := dispatcher.Wait(); !errors.Is(err, ErrLiveSessionClosed) {
+		t.Errorf("Wait() error = %v, want %v", err, ErrLiveSessionClosed)
+	}
+}
+
+func TestNewLiveToolDispatcherErrors(t *testing.T) {
+	handler := func(ctx context.Context, args map[string]any) (map[string]any, error) { return nil, nil }
+	tests := []struct {
+		desc  string
+		tools []*LiveTool
+	}{
+		{desc: "missing declaration", tools: []*LiveTool{{Handler: handler}}},
+		{desc: "missing name", tools: []*LiveTool{{Declaration: &FunctionDeclaration{}, Handler: handler}}},
+		{desc: "missing handler", tools: []*LiveTool{{Declaration: &FunctionDeclaration{Name: "f"}}}},
+		{desc: "duplicate name", tools: []*LiveTool{
+			{Declaration: &FunctionDeclaration{Name: "f"}, Handler: handler},
+			{Declaration: &FunctionDeclaration{Name: "f"}, Handler: handler},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if _, err := NewLiveToolDispatcher(tt.tools...); err == nil {
+				t.Errorf("NewLiveToolDispatcher() succeeded, want error")
+			}
+		})
+	}
+}
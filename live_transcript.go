@@ -0,0 +1,310 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai/internal/media"
+)
+
+// defaultLiveOutputSampleRate is the sample rate of Live model audio whose MIME
+// type doesn't specify one.
+const defaultLiveOutputSampleRate = 24000
+
+// Preview. LiveTurn is a turn of a Live conversation, as assembled by
+// [LiveTranscript].
+type LiveTurn struct {
+	// Role is [RoleUser] or [RoleModel].
+	Role string
+	// Transcript is the transcription of the turn's audio. For user turns it
+	// requires [LiveConnectConfig.InputAudioTranscription], and for model turns
+	// [LiveConnectConfig.OutputAudioTranscription].
+	Transcript string
+	// Text is the text of the turn. For model turns it is the text generated by
+	// the model, and for user turns the text sent with
+	// [Session.SendClientContent].
+	Text string
+	// AudioDuration is the duration of the audio generated by the model.
+	AudioDuration time.Duration
+	// ToolCalls are the functions called by the model.
+	ToolCalls []*FunctionCall
+	// ToolResponses are the responses to the tool calls of the previous model
+	// turn, added with [LiveTranscript.AddToolResponse].
+	ToolResponses []*FunctionResponse
+	// UsageMetadata is the last usage metadata received during the turn.
+	UsageMetadata *UsageMetadata
+	// StartTime is when the first message of the turn was handled.
+	StartTime time.Time
+	// EndTime is when the turn ended. It is zero while the turn is in progress.
+	EndTime time.Time
+	// Interrupted is true if the user interrupted the model's turn.
+	Interrupted bool
+}
+
+// Preview. LiveTranscript assembles the messages of a Live session into
+// conversation turns. Pass every received message to [LiveTranscript.Handle].
+//
+// A user turn ends when its transcription is finished or when the following
+// model turn ends. A model turn ends when it is complete or interrupted, or
+// when the responses to its tool calls are added.
+//
+// The transcription of the user's audio can arrive after the model started
+// answering it. The user turn is then placed before the model turn in
+// progress, unless the model turn is interrupted: the user turn is the speech
+// that interrupted it and is placed after it.
+//
+// A LiveTranscript is safe for concurrent use.
+type LiveTranscript struct {
+	mu    sync.Mutex
+	turns []*LiveTurn
+	// user and model are the turns in progress, if any.
+	user  *LiveTurn
+	model *LiveTurn
+	// early are the user turns placed before the model turn in progress,
+	// because their transcription arrived after the model started answering.
+	early []*LiveTurn
+	now   func() time.Time
+}
+
+// Preview. NewLiveTranscript returns an empty transcript.
+func NewLiveTranscript() *LiveTranscript {
+	return &LiveTranscript{now: time.Now}
+}
+
+// Preview. Handle adds the content of message to the transcript.
+func (t *LiveTranscript) Handle(message *LiveServerMessage) {
+	if message == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+
+	if message.ToolCall != nil {
+		turn := t.modelTurn(now)
+		turn.ToolCalls = append(turn.ToolCalls, message.ToolCall.FunctionCalls...)
+	}
+	if content := message.ServerContent; content != nil {
+		if content.InputTranscription != nil {
+			turn := t.userTurn(now)
+			turn.Transcript += content.InputTranscription.Text
+			if content.InputTranscription.Finished {
+				t.endUserTurn(now)
+			}
+		}
+		if content.ModelTurn != nil {
+			turn := t.modelTurn(now)
+			for _, part := range content.ModelTurn.Parts {
+				if part == nil {
+					continue
+				}
+				if part.Text != "" && !part.Thought {
+					turn.Text += part.Text
+				}
+				if part.InlineData != nil {
+					if rate, ok := media.ParsePCMRate(part.InlineData.MIMEType, defaultLiveOutputSampleRate); ok {
+						// The audio is 16-bit mono PCM.
+						turn.AudioDuration += time.Duration(len(part.InlineData.Data)/2) * time.Second / time.Duration(rate)
+					}
+				}
+			}
+		}
+		if content.OutputTranscription != nil {
+			turn := t.modelTurn(now)
+			turn.Transcript += content.OutputTranscription.Text
+		}
+		if content.Interrupted && t.model != nil {
+			t.interruptModelTurn(now)
+		}
+		if content.TurnComplete && t.model != nil {
+			t.endModelTurn(now)
+		}
+	}
+	if message.UsageMetadata != nil {
+		if turn := t.lastModelTurn(); turn != nil {
+			turn.UsageMetadata = message.UsageMetadata
+		}
+	}
+}
+
+// Preview. AddClientContent records the text of turns sent with
+// [Session.SendClientContent], which the server doesn't echo back.
+func (t *LiveTranscript) AddClientContent(input LiveClientContentInput) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	for _, content := range input.Turns {
+		if content == nil {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range content.Parts {
+			if part != nil {
+				text.WriteString(part.Text)
+			}
+		}
+		role := content.Role
+		if role == "" {
+			role = RoleUser
+		}
+		t.turns = append(t.turns, &LiveTurn{Role: role, Text: text.String(), StartTime: now, EndTime: now})
+	}
+}
+
+// Preview. AddToolResponse records the responses sent with
+// [Session.SendToolResponse], which the server doesn't echo back, as a user
+// turn. It ends the model turn that called the tools.
+func (t *LiveTranscript) AddToolResponse(input LiveToolResponseInput) {
+	if len(input.FunctionResponses) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	if t.model != nil {
+		t.endModelTurn(now)
+	}
+	t.turns = append(t.turns, &LiveTurn{
+		Role:          RoleUser,
+		ToolResponses: append([]*FunctionResponse(nil), input.FunctionResponses...),
+		StartTime:     now,
+		EndTime:       now,
+	})
+}
+
+// Preview. Turns returns a copy of the turns so far, in the order of the
+// conversation, including the turns in progress.
+func (t *LiveTranscript) Turns() []*LiveTurn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	turns := make([]*LiveTurn, len(t.turns))
+	for i, turn := range t.turns {
+		c := *turn
+		c.ToolCalls = append([]*FunctionCall(nil), turn.ToolCalls...)
+		c.ToolResponses = append([]*FunctionResponse(nil), turn.ToolResponses...)
+		turns[i] = &c
+	}
+	return turns
+}
+
+// Preview. History returns the finished turns as contents that can be used as
+// the history of a [Chat] created with [Chats.Create]. Each turn is converted
+// to a text part, using its text if set and its transcript otherwise, followed
+// by a part for each of its tool calls and tool responses. Turns without text,
+// tool calls and tool responses are omitted.
+func (t *LiveTranscript) History() []*Content {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var history []*Content
+	for _, turn := range t.turns {
+		if turn.EndTime.IsZero() {
+			continue
+		}
+		var parts []*Part
+		text := turn.Text
+		if text == "" {
+			text = turn.Transcript
+		}
+		if text != "" {
+			parts = append(parts, NewPartFromText(text))
+		}
+		for _, call := range turn.ToolCalls {
+			parts = append(parts, &Part{FunctionCall: call})
+		}
+		for _, response := range turn.ToolResponses {
+			parts = append(parts, &Part{FunctionResponse: response})
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		history = append(history, NewContentFromParts(parts, Role(turn.Role)))
+	}
+	return history
+}
+
+func (t *LiveTranscript) userTurn(now time.Time) *LiveTurn {
+	if t.user == nil {
+		t.user = &LiveTurn{Role: RoleUser, StartTime: now}
+		if t.model == nil {
+			t.turns = append(t.turns, t.user)
+		} else {
+			t.turns = slices.Insert(t.turns, slices.Index(t.turns, t.model), t.user)
+			t.early = append(t.early, t.user)
+		}
+	}
+	return t.user
+}
+
+func (t *LiveTranscript) modelTurn(now time.Time) *LiveTurn {
+	if t.model == nil {
+		t.model = &LiveTurn{Role: RoleModel, StartTime: now}
+		t.turns = append(t.turns, t.model)
+	}
+	return t.model
+}
+
+func (t *LiveTranscript) endUserTurn(now time.Time) {
+	if t.user != nil {
+		t.user.EndTime = now
+		t.user = nil
+	}
+}
+
+// endModelTurn ends the model turn and the user turn it answered.
+func (t *LiveTranscript) endModelTurn(now time.Time) {
+	t.endUserTurn(now)
+	t.model.EndTime = now
+	t.model = nil
+	t.early = nil
+}
+
+// interruptModelTurn ends the model turn as interrupted. The user turns placed
+// before it are the speech that interrupted it, so they are moved after it and
+// the one in progress, if any, goes on.
+func (t *LiveTranscript) interruptModelTurn(now time.Time) {
+	t.model.Interrupted = true
+	interrupting := t.early
+	t.turns = slices.DeleteFunc(t.turns, func(turn *LiveTurn) bool {
+		return slices.Contains(interrupting, turn)
+	})
+	user := t.user
+	inProgress := slices.Contains(interrupting, user)
+	if inProgress {
+		t.user = nil
+	}
+	t.endModelTurn(now)
+	t.turns = append(t.turns, interrupting...)
+	if inProgress {
+		t.user = user
+	}
+}
+
+// lastModelTurn returns the model turn in progress, or the last model turn if
+// none is in progress.
+func (t *LiveTranscript) lastModelTurn() *LiveTurn {
+	if t.model != nil {
+		return t.model
+	}
+	for i := len(t.turns) - 1; i >= 0; i-- {
+		if t.turns[i].Role == RoleModel {
+			return t.turns[i]
+		}
+	}
+	return nil
+}
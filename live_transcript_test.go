@@ -0,0 +1,165 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLiveTranscript(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tick := 0
+	transcript := NewLiveTranscript()
+	transcript.now = func() time.Time {
+		tick++
+		return start.Add(time.Duration(tick) * time.Second)
+	}
+
+	transcript.AddClientContent(LiveClientContentInput{Turns: []*Content{NewContentFromText("Hi there", RoleUser)}})
+	usage := &UsageMetadata{TotalTokenCount: 42}
+	for _, message := range []*LiveServerMessage{
+		{SetupComplete: &LiveServerSetupComplete{}},
+		// t=3: user speaks.
+		{ServerContent: &LiveServerContent{InputTranscription: &Transcription{Text: "What's the "}}},
+		{ServerContent: &LiveServerContent{InputTranscription: &Transcription{Text: "weather?"}}},
+		// t=5: model answers with audio and a tool call.
+		{ToolCall: &LiveServerToolCall{FunctionCalls: []*FunctionCall{{ID: "1", Name: "get_weather"}}}},
+		{ServerContent: &LiveServerContent{ModelTurn: &Content{Role: RoleModel, Parts: []*Part{
+			{InlineData: &Blob{MIMEType: "audio/pcm;rate=24000", Data: make([]byte, 48000)}},
+			{Text: "thinking", Thought: true},
+		}}}},
+		{ServerContent: &LiveServerContent{OutputTranscription: &Transcription{Text: "It's sunny."}}},
+		{ServerContent: &LiveServerContent{TurnComplete: true}},
+		// t=9: usage metadata arrives after the turn.
+		{UsageMetadata: usage},
+		// t=10: the model is interrupted.
+		{ServerContent: &LiveServerContent{ModelTurn: &Content{Role: RoleModel, Parts: []*Part{{Text: "Also, "}}}}},
+		{ServerContent: &LiveServerContent{InputTranscription: &Transcription{Text: "Stop.", Finished: true}}},
+		{ServerContent: &LiveServerContent{Interrupted: true}},
+		// t=13: a turn in progress.
+		{ServerContent: &LiveServerContent{OutputTranscription: &Transcription{Text: "OK"}}},
+	} {
+		transcript.Handle(message)
+	}
+
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+	want := []*LiveTurn{
+		{Role: RoleUser, Text: "Hi there", StartTime: at(1), EndTime: at(1)},
+		{Role: RoleUser, Transcript: "What's the weather?", StartTime: at(3), EndTime: at(8)},
+		{
+			Role:          RoleModel,
+			Transcript:    "It's sunny.",
+			AudioDuration: time.Second,
+			ToolCalls:     []*FunctionCall{{ID: "1", Name: "get_weather"}},
+			UsageMetadata: usage,
+			StartTime:     at(5),
+			EndTime:       at(8),
+		},
+		{Role: RoleModel, Text: "Also, ", StartTime: at(10), EndTime: at(12), Interrupted: true},
+		{Role: RoleUser, Transcript: "Stop.", StartTime: at(11), EndTime: at(11)},
+		{Role: RoleModel, Transcript: "OK", StartTime: at(13)},
+	}
+	if diff := cmp.Diff(want, transcript.Turns()); diff != "" {
+		t.Errorf("Turns() mismatch (-want +got):\n%s", diff)
+	}
+
+	wantHistory := []*Content{
+		NewContentFromText("Hi there", RoleUser),
+		NewContentFromText("What's the weather?", RoleUser),
+		NewContentFromParts([]*Part{NewPartFromText("It's sunny."), {FunctionCall: &FunctionCall{ID: "1", Name: "get_weather"}}}, RoleModel),
+		NewContentFromText("Also, ", RoleModel),
+		NewContentFromText("Stop.", RoleUser),
+	}
+	if diff := cmp.Diff(wantHistory, transcript.History()); diff != "" {
+		t.Errorf("History() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLiveTranscriptLateInputTranscription(t *testing.T) {
+	transcript := NewLiveTranscript()
+	for _, message := range []*LiveServerMessage{
+		// The model starts answering before the user's speech is transcribed.
+		{ServerContent: &LiveServerContent{ModelTurn: &Content{Role: RoleModel, Parts: []*Part{{Text: "It's "}}}}},
+		{ServerContent: &LiveServerContent{InputTranscription: &Transcription{Text: "What's the weather?"}}},
+		{ServerContent: &LiveServerContent{ModelTurn: &Content{Role: RoleModel, Parts: []*Part{{Text: "sunny."}}}}},
+		{ServerContent: &LiveServerContent{TurnComplete: true}},
+		// The user interrupts the next model turn and is still speaking.
+		{ServerContent: &LiveServerContent{ModelTurn: &Content{Role: RoleModel, Parts: []*Part{{Text: "Also, "}}}}},
+		{ServerContent: &LiveServerContent{InputTranscription: &Transcription{Text: "Wait, "}}},
+		{ServerContent: &LiveServerContent{Interrupted: true}},
+		{ServerContent: &LiveServerContent{InputTranscription: &Transcription{Text: "what about tomorrow?", Finished: true}}},
+	} {
+		transcript.Handle(message)
+	}
+
+	want := []*Content{
+		NewContentFromText("What's the weather?", RoleUser),
+		NewContentFromText("It's sunny.", RoleModel),
+		NewContentFromText("Also, ", RoleModel),
+		NewContentFromText("Wait, what about tomorrow?", RoleUser),
+	}
+	if diff := cmp.Diff(want, transcript.History()); diff != "" {
+		t.Errorf("History() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLiveTranscriptToolRoundTrip(t *testing.T) {
+	transcript := NewLiveTranscript()
+	call := &FunctionCall{ID: "1", Name: "get_weather", Args: map[string]any{"city": "Paris"}}
+	response := &FunctionResponse{ID: "1", Name: "get_weather", Response: map[string]any{"output": "sunny"}}
+	transcript.AddClientContent(LiveClientContentInput{Turns: Text("What's the weather in Paris?")})
+	transcript.Handle(&LiveServerMessage{ToolCall: &LiveServerToolCall{FunctionCalls: []*FunctionCall{call}}})
+	transcript.AddToolResponse(LiveToolResponseInput{FunctionResponses: []*FunctionResponse{response}})
+	transcript.Handle(&LiveServerMessage{ServerContent: &LiveServerContent{ModelTurn: &Content{Role: RoleModel, Parts: []*Part{{Text: "It's sunny."}}}}})
+	transcript.Handle(&LiveServerMessage{ServerContent: &LiveServerContent{TurnComplete: true}})
+
+	want := []*Content{
+		NewContentFromText("What's the weather in Paris?", RoleUser),
+		NewContentFromParts([]*Part{{FunctionCall: call}}, RoleModel),
+		NewContentFromParts([]*Part{{FunctionResponse: response}}, RoleUser),
+		NewContentFromText("It's sunny.", RoleModel),
+	}
+	history := transcript.History()
+	if diff := cmp.Diff(want, history); diff != "" {
+		t.Errorf("History() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The history continues in a chat.
+	client, err := NewClient(context.Background(), &ClientConfig{Backend: BackendGeminiAPI, APIKey: "test-api-key"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	chat, err := client.Chats.Create(context.Background(), "gemini-2.5-flash", nil, history)
+	if err != nil {
+		t.Fatalf("Chats.Create failed: %v", err)
+	}
+	if diff := cmp.Diff(want, chat.History(true)); diff != "" {
+		t.Errorf("curated chat history mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLiveTranscriptTurnsAreCopies(t *testing.T) {
+	transcript := NewLiveTranscript()
+	transcript.Handle(&LiveServerMessage{ServerContent: &LiveServerContent{OutputTranscription: &Transcription{Text: "a"}}})
+	turns := transcript.Turns()
+	turns[0].Transcript = "changed"
+	if got := transcript.Turns()[0].Transcript; got != "a" {
+		t.Errorf("Turns()[0].Transcript = %q after modifying a copy, want %q", got, "a")
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MetadataValue is the type of the values compared to custom metadata: strings
+// match [CustomMetadata.StringValue], numbers match
+// [CustomMetadata.NumericValue].
+type MetadataValue interface {
+	~string | MetadataNumber
+}
+
+// MetadataNumber is the type of the values compared to numeric custom
+// metadata.
+type MetadataNumber interface {
+	~int | ~int32 | ~int64 | ~float32 | ~float64
+}
+
+// MetadataFilter is a filter on the [CustomMetadata] of documents, built with
+// [MetadataEq], [MetadataIn], [MetadataContains], [MetadataLt] and the other
+// comparisons, and combined with [MetadataAnd], [MetadataOr] and
+// [MetadataNot].
+//
+// No config field takes a MetadataFilter: fields that take a metadata filter,
+// such as [FileSearch.MetadataFilter], are strings in the filter syntax of the
+// API (https://google.aip.dev/160). Render a filter for them with
+// [MetadataFilter.Render]. For example:
+//
+//	filter, err := genai.MetadataAnd(
+//		genai.MetadataEq("author", "Robert Graves"),
+//		genai.MetadataGe("year", 1930),
+//		genai.MetadataContains("tags", "poetry"),
+//	).Render()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	tool := &genai.Tool{FileSearch: &genai.FileSearch{
+//		FileSearchStoreNames: []string{store.Name},
+//		MetadataFilter:       filter,
+//	}}
+type MetadataFilter struct {
+	// op is the comparison operator, or AND, OR or NOT.
+	op    string
+	key   string
+	value string
+	// str or number is the value compared to the metadata, for Match.
+	str      *string
+	number   *float64
+	operands []*MetadataFilter
+	// err is the error of a value that can't be rendered.
+	err error
+}
+
+// metadataKeyPattern matches the keys that can be used in a filter without
+// quoting: identifiers, optionally separated by dots.
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// MetadataEq returns a filter that matches the documents whose metadata key is
+// equal to value.
+func MetadataEq[V MetadataValue](key string, value V) *MetadataFilter {
+	return newComparison("=", key, value)
+}
+
+// MetadataNe returns a filter that matches the documents whose metadata key is
+// not equal to value.
+func MetadataNe[V MetadataValue](key string, value V) *MetadataFilter {
+	return newComparison("!=", key, value)
+}
+
+// MetadataLt returns a filter that matches the documents whose numeric
+// metadata key is less than value.
+func MetadataLt[V MetadataNumber](key string, value V) *MetadataFilter {
+	return newComparison("<", key, value)
+}
+
+// MetadataLe returns a filter that matches the documents whose numeric
+// metadata key is less than or equal to value.
+func MetadataLe[V MetadataNumber](key string, value V) *MetadataFilter {
+	return newComparison("<=", key, value)
+}
+
+// MetadataGt returns a filter that matches the documents whose numeric
+// metadata key is greater than value.
+func MetadataGt[V MetadataNumber](key string, value V) *MetadataFilter {
+	return newComparison(">", key, value)
+}
+
+// MetadataGe returns a filter that matches the documents whose numeric
+// metadata key is greater than or equal to value.
+func MetadataGe[V MetadataNumber](key string, value V) *MetadataFilter {
+	return newComparison(">=", key, value)
+}
+
+// MetadataIn returns a filter that matches the documents whose metadata key is
+// equal to one of values. At least one value is required.
+func MetadataIn[V MetadataValue](key string, values ...V) *MetadataFilter {
+	if len(values) == 0 {
+		return &MetadataFilter{err: fmt.Errorf("MetadataIn(%q) requires at least one value", key)}
+	}
+	operands := make([]*MetadataFilter, len(values))
+	for i, value := range values {
+		operands[i] = MetadataEq(key, value)
+	}
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	return &MetadataFilter{op: "OR", operands: operands}
+}
+
+// MetadataContains returns a filter that matches the documents whose string
+// list metadata key, set with [CustomMetadata.StringListValue], contains value.
+func MetadataContains(key string, value string) *MetadataFilter {
+	return newComparison(":", key, value)
+}
+
+// MetadataAnd returns a filter that matches the documents matched by all
+// filters. At least one filter is required.
+func MetadataAnd(filters ...*MetadataFilter) *MetadataFilter {
+	return &MetadataFilter{op: "AND", operands: filters}
+}
+
+// MetadataOr returns a filter that matches the documents matched by any of
+// filters. At least one filter is required.
+func MetadataOr(filters ...*MetadataFilter) *MetadataFilter {
+	return &MetadataFilter{op: "OR", operands: filters}
+}
+
+// MetadataNot returns a filter that matches the documents not matched by
+// filter.
+func MetadataNot(filter *MetadataFilter) *MetadataFilter {
+	return &MetadataFilter{op: "NOT", operands: []*MetadataFilter{filter}}
+}
+
+func newComparison[V MetadataValue](op, key string, value V) *MetadataFilter {
+	f := &MetadataFilter{op: op, key: key}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		f.str = Ptr(v.String())
+		f.value = quoteMetadataString(v.String())
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		f.number = Ptr(float64(v.Int()))
+		f.value = strconv.FormatInt(v.Int(), 10)
+	default:
+		n := v.Float()
+		f.number = &n
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			f.err = fmt.Errorf("invalid value %v for metadata key %q", n, key)
+		}
+		f.value = strconv.FormatFloat(n, 'g', -1, v.Type().Bits())
+	}
+	return f
+}
+
+// quoteMetadataString returns s as a double-quoted string literal.
+func quoteMetadataString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// Validate reports whether the filter can be rendered: all keys are
+// identifiers optionally separated by dots, all numbers are finite, and all
+// combinations have operands.
+func (f *MetadataFilter) Validate() error {
+	if f == nil {
+		return errors.New("metadata filter is nil")
+	}
+	if f.err != nil {
+		return f.err
+	}
+	switch f.op {
+	case "AND", "OR", "NOT":
+		if len(f.operands) == 0 {
+			return fmt.Errorf("%s requires at least one filter", f.op)
+		}
+		for _, operand := range f.operands {
+			if err := operand.Validate(); err != nil {
+				return err
+			}
+		}
+	default:
+		if !metadataKeyPattern.MatchString(f.key) {
+			return fmt.Errorf("invalid metadata key %q, want an identifier optionally separated by dots", f.key)
+		}
+	}
+	return nil
+}
+
+// Match reports whether metadata, such as [Document.CustomMetadata], matches
+// the filter, to filter documents locally, for example those of
+// [Documents.All]. Comparisons don't match missing keys or values of another
+// type; numbers are compared with the precision of
+// [CustomMetadata.NumericValue].
+func (f *MetadataFilter) Match(metadata []*CustomMetadata) bool {
+	if f == nil {
+		return false
+	}
+	switch f.op {
+	case "AND":
+		for _, operand := range f.operands {
+			if !operand.Match(metadata) {
+				return false
+			}
+		}
+		return true
+	case "OR":
+		for _, operand := range f.operands {
+			if operand.Match(metadata) {
+				return true
+			}
+		}
+		return false
+	case "NOT":
+		return len(f.operands) > 0 && !f.operands[0].Match(metadata)
+	}
+	var m *CustomMetadata
+	for _, candidate := range metadata {
+		if candidate != nil && candidate.Key == f.key {
+			m = candidate
+			break
+		}
+	}
+	switch {
+	case m == nil:
+		return false
+	case f.op == ":":
+		return m.StringListValue != nil && slices.Contains(m.StringListValue.Values, *f.str)
+	case f.str != nil:
+		if m.NumericValue != nil || m.StringListValue != nil {
+			return false
+		}
+		return (f.op == "=") == (m.StringValue == *f.str)
+	case f.number != nil && m.NumericValue != nil:
+		value, want := *m.NumericValue, float32(*f.number)
+		switch f.op {
+		case "=":
+			return value == want
+		case "!=":
+			return value != want
+		case "<":
+			return value < want
+		case "<=":
+			return value <= want
+		case ">":
+			return value > want
+		case ">=":
+			return value >= want
+		}
+	}
+	return false
+}
+
+// Render validates the filter and returns it in the filter syntax of the API.
+func (f *MetadataFilter) Render() (string, error) {
+	if err := f.Validate(); err != nil {
+		return "", err
+	}
+	return f.String(), nil
+}
+
+// String returns the filter in the filter syntax of the API, without
+// validating it. Use [MetadataFilter.Render] to validate it too.
+func (f *MetadataFilter) String() string {
+	var b strings.Builder
+	f.render(&b, false)
+	return b.String()
+}
+
+// render writes the filter to b, in parentheses if nested is true and the
+// filter combines several filters.
+func (f *MetadataFilter) render(b *strings.Builder, nested bool) {
+	if f == nil {
+		return
+	}
+	switch f.op {
+	case "NOT":
+		b.WriteString("NOT ")
+		if len(f.operands) > 0 {
+			f.operands[0].render(b, true)
+		}
+	case "AND", "OR":
+		if len(f.operands) == 1 {
+			f.operands[0].render(b, nested)
+			return
+		}
+		if nested {
+			b.WriteByte('(')
+		}
+		for i, operand := range f.operands {
+			if i > 0 {
+				b.WriteString(" " + f.op + " ")
+			}
+			operand.render(b, true)
+		}
+		if nested {
+			b.WriteByte(')')
+		}
+	default:
+		b.WriteString(f.key)
+		if f.op == ":" {
+			b.WriteString(":")
+		} else {
+			b.WriteString(" " + f.op + " ")
+		}
+		b.WriteString(f.value)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMetadataFilterRender(t *testing.T) {
+	type genre string
+	tests := []struct {
+		name   string
+		filter *MetadataFilter
+		want   string
+	}{
+		{name: "String", filter: MetadataEq("author", "Robert Graves"), want: `author = "Robert Graves"`},
+		{name: "Quoted", filter: MetadataEq("title", `say "hi" \o/`), want: `title = "say \"hi\" \\o/"`},
+		{name: "NamedString", filter: MetadataNe("genre", genre("poetry")), want: `genre != "poetry"`},
+		{name: "Int", filter: MetadataGe("year", 1930), want: `year >= 1930`},
+		{name: "Float32", filter: MetadataLt("score", float32(0.1)), want: `score < 0.1`},
+		{name: "Float64", filter: MetadataGt("score", 1e21), want: `score > 1e+21`},
+		{name: "Contains", filter: MetadataContains("tags", "fiction"), want: `tags:"fiction"`},
+		{name: "In", filter: MetadataIn("lang", "en", "fr"), want: `lang = "en" OR lang = "fr"`},
+		{name: "InOne", filter: MetadataIn("year", 2024), want: `year = 2024`},
+		{name: "Nested", filter: MetadataAnd(MetadataEq("a", 1), MetadataOr(MetadataEq("b", 2), MetadataNot(MetadataContains("c", "x"))), MetadataIn("d", 3, 4)), want: `a = 1 AND (b = 2 OR NOT c:"x") AND (d = 3 OR d = 4)`},
+		{name: "NotCombination", filter: MetadataNot(MetadataAnd(MetadataEq("a", 1), MetadataLe("b", 2))), want: `NOT (a = 1 AND b <= 2)`},
+		{name: "DottedKey", filter: MetadataEq("chunk.custom_metadata.author", "x"), want: `chunk.custom_metadata.author = "x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filter.Render()
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataFilterValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter *MetadataFilter
+	}{
+		{name: "Nil", filter: nil},
+		{name: "EmptyKey", filter: MetadataEq("", "x")},
+		{name: "InvalidKey", filter: MetadataEq("author = x OR a", "x")},
+		{name: "NaN", filter: MetadataLt("score", math.NaN())},
+		{name: "Inf", filter: MetadataGt("score", math.Inf(1))},
+		{name: "EmptyIn", filter: MetadataIn[string]("lang")},
+		{name: "EmptyAnd", filter: MetadataAnd()},
+		{name: "NilOperand", filter: MetadataOr(MetadataEq("a", 1), nil)},
+		{name: "NestedError", filter: MetadataNot(MetadataAnd(MetadataEq("a", 1), MetadataEq("b c", 2)))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.filter.Render(); err == nil {
+				t.Errorf("Render() = %s, want an error", got)
+			}
+		})
+	}
+}
+
+func TestMetadataFilterMatch(t *testing.T) {
+	metadata := []*CustomMetadata{
+		{Key: "author", StringValue: "Robert Graves"},
+		{Key: "year", NumericValue: Ptr[float32](1934)},
+		{Key: "score", NumericValue: Ptr[float32](0.1)},
+		{Key: "tags", StringListValue: &StringList{Values: []string{"fiction", "history"}}},
+	}
+	tests := []struct {
+		filter *MetadataFilter
+		want   bool
+	}{
+		{filter: MetadataEq("author", "Robert Graves"), want: true},
+		{filter: MetadataEq("author", "Graves"), want: false},
+		{filter: MetadataNe("author", "Graves"), want: true},
+		{filter: MetadataNe("missing", "x"), want: false},
+		{filter: MetadataEq("year", 1934), want: true},
+		{filter: MetadataEq("year", "1934"), want: false},
+		{filter: MetadataEq("score", 0.1), want: true},
+		{filter: MetadataLt("year", 1934), want: false},
+		{filter: MetadataLe("year", 1934), want: true},
+		{filter: MetadataGt("year", 1930.5), want: true},
+		{filter: MetadataContains("tags", "history"), want: true},
+		{filter: MetadataContains("tags", "poetry"), want: false},
+		{filter: MetadataContains("author", "Robert Graves"), want: false},
+		{filter: MetadataIn("year", 1933, 1934), want: true},
+		{filter: MetadataAnd(MetadataEq("author", "Robert Graves"), MetadataGe("year", 1930)), want: true},
+		{filter: MetadataAnd(MetadataEq("author", "Robert Graves"), MetadataGe("year", 1940)), want: false},
+		{filter: MetadataOr(MetadataGe("year", 1940), MetadataContains("tags", "fiction")), want: true},
+		{filter: MetadataNot(MetadataContains("tags", "fiction")), want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(metadata); got != tt.want {
+			t.Errorf("%s: Match() = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
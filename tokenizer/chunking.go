@@ -0,0 +1,118 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"google.golang.org/genai"
+)
+
+// chunkWord is a whitespace-delimited word of a text, with the range of its
+// tokens.
+type chunkWord struct {
+	start, end int
+	// firstToken and lastToken are the range of the tokens of the word.
+	firstToken, lastToken int
+}
+
+func (w chunkWord) tokens() int { return w.lastToken - w.firstToken }
+
+// PreviewChunks splits text into chunks like the white space chunking of
+// [genai.FileSearchStores.UploadToFileSearchStore] with config, to evaluate a
+// [genai.ChunkingConfig] without uploading documents.
+//
+// Chunks are made of whole whitespace-delimited words, of at most
+// MaxTokensPerChunk tokens, and a chunk starts with the last words of the
+// previous one, of at most MaxOverlapTokens tokens. Words longer than
+// MaxTokensPerChunk are split between tokens. The Tokens of a chunk is the sum
+// of the tokens of its words, counted with the local tokenizer: the service
+// may count slightly differently.
+//
+// MaxTokensPerChunk is required, since the service default isn't part of the
+// API.
+func (tok *LocalTokenizer) PreviewChunks(text string, config *genai.ChunkingConfig) ([]Chunk, error) {
+	if config == nil || config.WhiteSpaceConfig == nil || config.WhiteSpaceConfig.MaxTokensPerChunk == nil {
+		return nil, fmt.Errorf("WhiteSpaceConfig.MaxTokensPerChunk is required")
+	}
+	maxTokens := int(*config.WhiteSpaceConfig.MaxTokensPerChunk)
+	overlap := 0
+	if config.WhiteSpaceConfig.MaxOverlapTokens != nil {
+		overlap = int(*config.WhiteSpaceConfig.MaxOverlapTokens)
+	}
+	if maxTokens <= 0 {
+		return nil, fmt.Errorf("max tokens per chunk must be positive, got %d", maxTokens)
+	}
+	if overlap < 0 || overlap >= maxTokens {
+		return nil, fmt.Errorf("max overlap tokens must be between 0 and max tokens per chunk - 1, got %d", overlap)
+	}
+
+	spans := tokenSpans(text, tok.processor.Encode(text))
+	words := splitWords(text, spans, maxTokens)
+	var chunks []Chunk
+	for first := 0; first < len(words); {
+		last, tokens := first, 0
+		for last < len(words) && tokens+words[last].tokens() <= maxTokens {
+			tokens += words[last].tokens()
+			last++
+		}
+		chunks = append(chunks, Chunk{
+			Text:   text[words[first].start:words[last-1].end],
+			Start:  words[first].start,
+			End:    words[last-1].end,
+			Tokens: tokens,
+		})
+		if last == len(words) {
+			break
+		}
+		// Start the next chunk with the last words that fit in the overlap.
+		next, overlapTokens := last, 0
+		for next-1 > first && overlapTokens+words[next-1].tokens() <= overlap {
+			next--
+			overlapTokens += words[next].tokens()
+		}
+		first = next
+	}
+	return chunks, nil
+}
+
+// splitWords returns the whitespace-delimited words of text. Each token is
+// attributed to the word of its first non-whitespace byte; whitespace tokens
+// aren't attributed to any word. Words of more than maxTokens tokens are split
+// between tokens.
+func splitWords(text string, spans []tokenSpan, maxTokens int) []chunkWord {
+	var words []chunkWord
+	inWord := false
+	for i, span := range spans {
+		s := text[span.start:span.end]
+		trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+		if trimmed == "" {
+			inWord = false
+			continue
+		}
+		start := span.start + len(s) - len(trimmed)
+		if !inWord || start > span.start || words[len(words)-1].tokens() == maxTokens {
+			words = append(words, chunkWord{start: start, firstToken: i})
+		}
+		w := &words[len(words)-1]
+		w.lastToken = i + 1
+		w.end = span.start + len(strings.TrimRightFunc(s, unicode.IsSpace))
+		// A token that ends with whitespace ends the word.
+		inWord = w.end == span.end
+	}
+	return words
+}
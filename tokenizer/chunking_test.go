@@ -0,0 +1,144 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+
+	sentencepiece "github.com/eliben/go-sentencepiece"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+)
+
+// pieceEncoder is an encoder that splits the tokens of wordEncoder into
+// pieces of at most 3 bytes.
+type pieceEncoder struct{}
+
+func (pieceEncoder) Encode(text string) []sentencepiece.Token {
+	var tokens []sentencepiece.Token
+	for _, word := range wordRegexp.FindAllString(text, -1) {
+		for len(word) > 0 {
+			n := min(3, len(word))
+			tokens = append(tokens, sentencepiece.Token{Text: strings.ReplaceAll(word[:n], " ", "▁")})
+			word = word[n:]
+		}
+	}
+	return tokens
+}
+
+func TestPreviewChunks(t *testing.T) {
+	chunking := func(maxTokens, overlap int32) *genai.ChunkingConfig {
+		return &genai.ChunkingConfig{WhiteSpaceConfig: &genai.WhiteSpaceConfig{
+			MaxTokensPerChunk: genai.Ptr(maxTokens),
+			MaxOverlapTokens:  genai.Ptr(overlap),
+		}}
+	}
+	chunks := func(text string, chunks ...string) []Chunk {
+		var want []Chunk
+		offset := 0
+		for _, s := range chunks {
+			tokens := len(strings.Fields(s))
+			if i := strings.Index(s, "|"); i >= 0 {
+				// "text|n" is a chunk of n tokens.
+				s, tokens = s[:i], int(s[i+1]-'0')
+			}
+			start := offset + strings.Index(text[offset:], s)
+			want = append(want, Chunk{Text: s, Start: start, End: start + len(s), Tokens: tokens})
+			offset = start + 1
+		}
+		return want
+	}
+	text := "One two three\n\nfour  five six seven."
+	tests := []struct {
+		name    string
+		encoder encoder
+		text    string
+		config  *genai.ChunkingConfig
+		want    []Chunk
+	}{
+		{
+			name:    "NoOverlap",
+			encoder: wordEncoder{},
+			text:    text,
+			config:  chunking(3, 0),
+			want:    chunks(text, "One two three", "four  five six", "seven."),
+		},
+		{
+			name:    "Overlap",
+			encoder: wordEncoder{},
+			text:    text,
+			config:  chunking(4, 2),
+			want:    chunks(text, "One two three\n\nfour", "three\n\nfour  five six", "five six seven."),
+		},
+		{
+			name:    "Fits",
+			encoder: wordEncoder{},
+			text:    "  short text \n",
+			config:  chunking(100, 10),
+			want:    chunks("  short text \n", "short text"),
+		},
+		{
+			name:    "LongWord",
+			encoder: pieceEncoder{},
+			text:    "go internationalization now",
+			config:  chunking(4, 0),
+			want:    chunks("go internationalization now", "go|1", "internation|4", "alization|3", "now|2"),
+		},
+		{
+			name:    "Empty",
+			encoder: wordEncoder{},
+			text:    " \n ",
+			config:  chunking(4, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := &LocalTokenizer{processor: tt.encoder}
+			got, err := tok.PreviewChunks(tt.text, tt.config)
+			if err != nil {
+				t.Fatalf("PreviewChunks failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("PreviewChunks mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPreviewChunksModel(t *testing.T) {
+	tok := newModelTokenizer(t)
+	for _, maxTokens := range []int32{1, 4, 16, 1000} {
+		config := &genai.ChunkingConfig{WhiteSpaceConfig: &genai.WhiteSpaceConfig{MaxTokensPerChunk: genai.Ptr(maxTokens)}}
+		chunks, err := tok.PreviewChunks(modelTestText, config)
+		if err != nil {
+			t.Fatalf("PreviewChunks failed: %v", err)
+		}
+		checkChunks(t, modelTestText, chunks, int(maxTokens))
+	}
+}
+
+func TestPreviewChunksInvalidConfig(t *testing.T) {
+	tok := &LocalTokenizer{processor: wordEncoder{}}
+	for _, config := range []*genai.ChunkingConfig{
+		nil,
+		{WhiteSpaceConfig: &genai.WhiteSpaceConfig{}},
+		{WhiteSpaceConfig: &genai.WhiteSpaceConfig{MaxTokensPerChunk: genai.Ptr[int32](0)}},
+		{WhiteSpaceConfig: &genai.WhiteSpaceConfig{MaxTokensPerChunk: genai.Ptr[int32](10), MaxOverlapTokens: genai.Ptr[int32](10)}},
+	} {
+		if _, err := tok.PreviewChunks("text", config); err == nil {
+			t.Errorf("PreviewChunks(%+v) succeeded, want an error", config)
+		}
+	}
+}
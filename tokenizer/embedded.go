@@ -0,0 +1,32 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build genai_tokenizer_embed
+
+package tokenizer
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// modelFiles holds the tokenizer models copied into the models directory,
+// named as returned by [ModelFileName].
+//
+//go:embed models/*.model
+var modelFiles embed.FS
+
+func init() {
+	embeddedModels, _ = fs.Sub(modelFiles, "models")
+}
@@ -0,0 +1,353 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	// Register the image formats whose dimensions can be decoded.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"google.golang.org/genai"
+)
+
+// Documented token rates of media inputs.
+const (
+	// tokensPerImageTile is the number of tokens of an image tile. Images with
+	// both dimensions of at most smallImageSize pixels are a single tile.
+	tokensPerImageTile = 258
+	smallImageSize     = 384
+	minImageTileSize   = 256
+	maxImageTileSize   = 768
+	// tokensPerVideoFrame is the number of tokens of a video frame, including
+	// its share of the audio track. At the default of one frame per second it
+	// is the documented rate of 263 tokens per second of video.
+	tokensPerVideoFrame = 263
+	defaultVideoFPS     = 1.0
+	// tokensPerAudioSecond is the number of tokens of a second of audio.
+	tokensPerAudioSecond = 32
+	// tokensPerDocumentPage is the number of tokens of a PDF page.
+	tokensPerDocumentPage = 258
+)
+
+// modalityOrder is the order of the modalities in [TokenEstimate].
+var modalityOrder = []genai.MediaModality{
+	genai.MediaModalityText,
+	genai.MediaModalityImage,
+	genai.MediaModalityVideo,
+	genai.MediaModalityAudio,
+	genai.MediaModalityDocument,
+}
+
+// MediaInfo describes media whose properties can't be decoded locally, such
+// as files referenced by [genai.FileData] or video containers.
+type MediaInfo struct {
+	// Width and Height are the dimensions of an image, in pixels.
+	Width  int
+	Height int
+	// Duration is the duration of audio or video.
+	Duration time.Duration
+	// Pages is the number of pages of a PDF document.
+	Pages int
+}
+
+// EstimateConfig configures [LocalTokenizer.EstimateTokens].
+type EstimateConfig struct {
+	// Optional. MediaInfo returns the properties of the media of a part, or nil
+	// if they are unknown. It is called for parts with [genai.FileData], and for
+	// parts with [genai.Blob] data whose properties can't be decoded: audio
+	// other than WAV and PCM, and video.
+	MediaInfo func(part *genai.Part) *MediaInfo
+}
+
+// TokenEstimate is the estimated token count of contents with media.
+type TokenEstimate struct {
+	// TotalTokens is the estimated total number of tokens.
+	TotalTokens int32
+	// PromptTokensDetails is the breakdown of TotalTokens by modality, in the
+	// same shape as [genai.GenerateContentResponseUsageMetadata]. Modalities
+	// without tokens are omitted.
+	PromptTokensDetails []*genai.ModalityTokenCount
+	// UnestimatedParts is the number of media parts whose tokens couldn't be
+	// estimated, for example audio of unknown duration. They are not counted in
+	// TotalTokens.
+	UnestimatedParts int
+}
+
+// EstimateTokens estimates the tokens of contents, including their media.
+// Text is counted like [LocalTokenizer.CountTokens]. Media is estimated with
+// the documented rates:
+//
+//   - images: 258 tokens if both dimensions are at most 384 pixels, otherwise
+//     258 tokens per tile, with tiles sized after the smaller dimension;
+//   - video: 263 tokens per second at the default of 1 frame per second,
+//     scaled by [genai.VideoMetadata.FPS] and clipped to its offsets;
+//   - audio: 32 tokens per second;
+//   - PDF documents: 258 tokens per page.
+//
+// Image dimensions are decoded from PNG, JPEG and GIF data, audio durations
+// from WAV and PCM data, and PDF page counts from the document. Other
+// properties are requested from [EstimateConfig.MediaInfo]. Images of unknown
+// dimensions count as a single tile.
+//
+// The estimates are approximations and may differ from the counts returned by
+// the API.
+func (tok *LocalTokenizer) EstimateTokens(contents []*genai.Content, config *genai.CountTokensConfig, estimateConfig *EstimateConfig) (*TokenEstimate, error) {
+	if estimateConfig == nil {
+		estimateConfig = &EstimateConfig{}
+	}
+	counts := map[genai.MediaModality]int{}
+	estimate := &TokenEstimate{}
+
+	textContents := make([]*genai.Content, 0, len(contents))
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+		text := &genai.Content{Role: content.Role}
+		for _, part := range content.Parts {
+			if part == nil {
+				continue
+			}
+			if part.InlineData == nil && part.FileData == nil {
+				text.Parts = append(text.Parts, part)
+				continue
+			}
+			modality, tokens, ok := estimatePart(part, estimateConfig)
+			if modality == genai.MediaModalityText {
+				// Inline text documents are tokenized.
+				text.Parts = append(text.Parts, &genai.Part{Text: string(part.InlineData.Data)})
+				continue
+			}
+			if !ok {
+				estimate.UnestimatedParts++
+				continue
+			}
+			counts[modality] += tokens
+		}
+		textContents = append(textContents, text)
+	}
+
+	textCount, err := tok.CountTokens(textContents, config)
+	if err != nil {
+		return nil, err
+	}
+	counts[genai.MediaModalityText] += int(textCount.TotalTokens)
+
+	for _, modality := range modalityOrder {
+		if counts[modality] == 0 {
+			continue
+		}
+		estimate.TotalTokens += int32(counts[modality])
+		estimate.PromptTokensDetails = append(estimate.PromptTokensDetails, &genai.ModalityTokenCount{
+			Modality:   modality,
+			TokenCount: int32(counts[modality]),
+		})
+	}
+	return estimate, nil
+}
+
+// estimatePart estimates the tokens of a part with inline or file data. It
+// returns false if the tokens can't be estimated. Inline text returns
+// [genai.MediaModalityText] and is left to the caller.
+func estimatePart(part *genai.Part, config *EstimateConfig) (genai.MediaModality, int, bool) {
+	var mimeType string
+	var data []byte
+	if part.InlineData != nil {
+		mimeType, data = part.InlineData.MIMEType, part.InlineData.Data
+	} else {
+		mimeType = part.FileData.MIMEType
+	}
+	mediaType, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(mimeType)), ";")
+	info := func() *MediaInfo {
+		if config.MediaInfo == nil {
+			return nil
+		}
+		return config.MediaInfo(part)
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/") && data != nil:
+		return genai.MediaModalityText, 0, true
+	case strings.HasPrefix(mediaType, "image/"):
+		width, height, ok := imageSize(data)
+		if !ok {
+			if i := info(); i != nil {
+				width, height = i.Width, i.Height
+			}
+		}
+		return genai.MediaModalityImage, imageTokens(width, height), true
+	case strings.HasPrefix(mediaType, "audio/"):
+		duration, ok := audioDuration(mimeType, data)
+		if !ok {
+			i := info()
+			if i == nil || i.Duration <= 0 {
+				return genai.MediaModalityAudio, 0, false
+			}
+			duration = i.Duration
+		}
+		return genai.MediaModalityAudio, int(math.Ceil(duration.Seconds() * tokensPerAudioSecond)), true
+	case strings.HasPrefix(mediaType, "video/"):
+		var duration time.Duration
+		if i := info(); i != nil {
+			duration = i.Duration
+		}
+		seconds, fps, ok := videoSampling(duration, part.VideoMetadata)
+		if !ok {
+			return genai.MediaModalityVideo, 0, false
+		}
+		return genai.MediaModalityVideo, int(math.Ceil(seconds * fps * tokensPerVideoFrame)), true
+	case mediaType == "application/pdf":
+		pages := pdfPageCount(data)
+		if pages == 0 {
+			if i := info(); i != nil {
+				pages = i.Pages
+			}
+		}
+		if pages <= 0 {
+			return genai.MediaModalityDocument, 0, false
+		}
+		return genai.MediaModalityDocument, pages * tokensPerDocumentPage, true
+	default:
+		return genai.MediaModalityUnspecified, 0, false
+	}
+}
+
+// imageSize decodes the dimensions of an image.
+func imageSize(data []byte) (int, int, bool) {
+	if data == nil {
+		return 0, 0, false
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
+
+// imageTokens returns the tokens of an image of the given dimensions. Unknown
+// dimensions count as a single tile.
+func imageTokens(width, height int) int {
+	if width <= smallImageSize && height <= smallImageSize {
+		return tokensPerImageTile
+	}
+	tile := int(float64(min(width, height)) / 1.5)
+	tile = max(minImageTileSize, min(tile, maxImageTileSize))
+	tiles := ((width + tile - 1) / tile) * ((height + tile - 1) / tile)
+	return tiles * tokensPerImageTile
+}
+
+// videoSampling returns the sampled duration of a video in seconds and its
+// sampling rate, given its full duration (zero if unknown) and metadata.
+func videoSampling(duration time.Duration, metadata *genai.VideoMetadata) (float64, float64, bool) {
+	fps := defaultVideoFPS
+	start, end := time.Duration(0), duration
+	if metadata != nil {
+		if metadata.FPS != nil && *metadata.FPS > 0 {
+			fps = *metadata.FPS
+		}
+		start = metadata.StartOffset
+		if metadata.EndOffset > 0 && (end == 0 || metadata.EndOffset < end) {
+			end = metadata.EndOffset
+		}
+	}
+	if end <= 0 {
+		return 0, 0, false
+	}
+	if start >= end {
+		return 0, fps, true
+	}
+	return (end - start).Seconds(), fps, true
+}
+
+// audioDuration decodes the duration of WAV or raw PCM audio.
+func audioDuration(mimeType string, data []byte) (time.Duration, bool) {
+	if data == nil {
+		return 0, false
+	}
+	parts := strings.Split(mimeType, ";")
+	switch strings.TrimSpace(strings.ToLower(parts[0])) {
+	case "audio/pcm", "audio/l16":
+		rate := 16000
+		for _, p := range parts[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.ToLower(key) == "rate" {
+				r, err := strconv.Atoi(value)
+				if err != nil || r <= 0 {
+					return 0, false
+				}
+				rate = r
+			}
+		}
+		return time.Duration(len(data)/2) * time.Second / time.Duration(rate), true
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return wavDuration(data)
+	}
+	return 0, false
+}
+
+// wavDuration decodes the duration of a WAV file from its fmt and data
+// chunks.
+func wavDuration(data []byte) (time.Duration, bool) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return 0, false
+	}
+	var byteRate uint32
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := binary.LittleEndian.Uint32(data[offset+4:])
+		body := offset + 8
+		switch id {
+		case "fmt ":
+			if body+12 > len(data) {
+				return 0, false
+			}
+			byteRate = binary.LittleEndian.Uint32(data[body+8:])
+		case "data":
+			if byteRate == 0 {
+				return 0, false
+			}
+			// Streamed WAV files may have a placeholder data size.
+			dataSize := min(int64(size), int64(len(data)-body))
+			return time.Duration(float64(dataSize) / float64(byteRate) * float64(time.Second)), true
+		}
+		// Chunks are padded to an even size.
+		offset = body + int(size) + int(size%2)
+	}
+	return 0, false
+}
+
+// pdfPageRegexp matches the page objects of a PDF document, but not the page
+// tree nodes ("/Type /Pages").
+var pdfPageRegexp = regexp.MustCompile(`/Type\s*/Page\b`)
+
+// pdfPageCount returns the number of pages of a PDF document, or 0 if it
+// can't be determined. It counts the uncompressed page objects, which misses
+// pages stored in compressed object streams.
+func pdfPageCount(data []byte) int {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return 0
+	}
+	return len(pdfPageRegexp.FindAll(data, -1))
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+)
+
+func pngData(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// wavData returns a WAV file with a LIST chunk before the audio, as written
+// by many encoders, and d of 16kHz mono audio.
+func wavData(d time.Duration) []byte {
+	const byteRate = 16000 * 2
+	dataSize := int(d.Seconds() * byteRate)
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, []uint32{16})
+	binary.Write(&b, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&b, binary.LittleEndian, []uint32{16000, byteRate})
+	binary.Write(&b, binary.LittleEndian, []uint16{2, 16})
+	b.WriteString("LIST")
+	binary.Write(&b, binary.LittleEndian, uint32(3))
+	b.WriteString("abc\x00")
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(dataSize))
+	b.Write(make([]byte, dataSize))
+	return b.Bytes()
+}
+
+func TestEstimateTokens(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n1 0 obj << /Type /Pages /Kids [2 0 R 3 0 R] >>\n2 0 obj << /Type /Page >>\n3 0 obj <</Type/Page>>\n")
+	fps := 2.0
+	tests := []struct {
+		name   string
+		parts  []*genai.Part
+		info   *MediaInfo
+		want   []*genai.ModalityTokenCount
+		wantUn int
+	}{
+		{
+			name:  "SmallImage",
+			parts: []*genai.Part{genai.NewPartFromBytes(pngData(t, 300, 384), "image/png")},
+			want:  []*genai.ModalityTokenCount{{Modality: genai.MediaModalityImage, TokenCount: 258}},
+		},
+		{
+			// 1000/1.5 = 666px tiles: 2x2 tiles.
+			name:  "TiledImage",
+			parts: []*genai.Part{genai.NewPartFromBytes(pngData(t, 1000, 1000), "image/png")},
+			want:  []*genai.ModalityTokenCount{{Modality: genai.MediaModalityImage, TokenCount: 4 * 258}},
+		},
+		{
+			// Tiles are at most 768px: 3x2 tiles.
+			name:  "FileImage",
+			parts: []*genai.Part{genai.NewPartFromURI("files/image", "image/jpeg")},
+			info:  &MediaInfo{Width: 2000, Height: 1200},
+			want:  []*genai.ModalityTokenCount{{Modality: genai.MediaModalityImage, TokenCount: 6 * 258}},
+		},
+		{
+			name:  "UnknownImage",
+			parts: []*genai.Part{genai.NewPartFromURI("files/image", "image/webp")},
+			want:  []*genai.ModalityTokenCount{{Modality: genai.MediaModalityImage, TokenCount: 258}},
+		},
+		{
+			name: "Audio",
+			parts: []*genai.Part{
+				genai.NewPartFromBytes(wavData(2*time.Second), "audio/wav"),
+				genai.NewPartFromBytes(make([]byte, 48000), "audio/pcm;rate=24000"),
+			},
+			want: []*genai.ModalityTokenCount{{Modality: genai.MediaModalityAudio, TokenCount: 64 + 32}},
+		},
+		{
+			name:   "UnknownAudio",
+			parts:  []*genai.Part{genai.NewPartFromURI("files/audio", "audio/mp3")},
+			wantUn: 1,
+		},
+		{
+			name: "ClippedVideo",
+			parts: []*genai.Part{{
+				FileData:      &genai.FileData{FileURI: "files/video", MIMEType: "video/mp4"},
+				VideoMetadata: &genai.VideoMetadata{StartOffset: 10 * time.Second, EndOffset: 70 * time.Second, FPS: &fps},
+			}},
+			info: &MediaInfo{Duration: 5 * time.Minute},
+			want: []*genai.ModalityTokenCount{{Modality: genai.MediaModalityVideo, TokenCount: 60 * 2 * 263}},
+		},
+		{
+			name: "VideoEndOffsetPastDuration",
+			parts: []*genai.Part{{
+				FileData:      &genai.FileData{FileURI: "files/video", MIMEType: "video/mp4"},
+				VideoMetadata: &genai.VideoMetadata{EndOffset: time.Hour},
+			}},
+			info: &MediaInfo{Duration: 10 * time.Second},
+			want: []*genai.ModalityTokenCount{{Modality: genai.MediaModalityVideo, TokenCount: 10 * 263}},
+		},
+		{
+			name:   "UnknownVideo",
+			parts:  []*genai.Part{genai.NewPartFromURI("files/video", "video/mp4")},
+			wantUn: 1,
+		},
+		{
+			name:  "PDF",
+			parts: []*genai.Part{genai.NewPartFromBytes(pdf, "application/pdf")},
+			want:  []*genai.ModalityTokenCount{{Modality: genai.MediaModalityDocument, TokenCount: 2 * 258}},
+		},
+		{
+			name:  "FilePDF",
+			parts: []*genai.Part{genai.NewPartFromURI("files/doc", "application/pdf")},
+			info:  &MediaInfo{Pages: 10},
+			want:  []*genai.ModalityTokenCount{{Modality: genai.MediaModalityDocument, TokenCount: 10 * 258}},
+		},
+		{
+			name: "Mixed",
+			parts: []*genai.Part{
+				genai.NewPartFromBytes(pngData(t, 10, 10), "image/png"),
+				genai.NewPartFromBytes(make([]byte, 32000), "audio/pcm"),
+				genai.NewPartFromBytes(pdf, "application/pdf"),
+				genai.NewPartFromURI("files/archive", "application/zip"),
+			},
+			want: []*genai.ModalityTokenCount{
+				{Modality: genai.MediaModalityImage, TokenCount: 258},
+				{Modality: genai.MediaModalityAudio, TokenCount: 32},
+				{Modality: genai.MediaModalityDocument, TokenCount: 2 * 258},
+			},
+			wantUn: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Media is estimated without the tokenizer model.
+			tok := &LocalTokenizer{}
+			config := &EstimateConfig{MediaInfo: func(*genai.Part) *MediaInfo { return tt.info }}
+			got, err := tok.EstimateTokens([]*genai.Content{{Role: "user", Parts: tt.parts}}, nil, config)
+			if err != nil {
+				t.Fatalf("EstimateTokens failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got.PromptTokensDetails); diff != "" {
+				t.Errorf("PromptTokensDetails mismatch (-want +got):\n%s", diff)
+			}
+			var total int32
+			for _, count := range tt.want {
+				total += count.TokenCount
+			}
+			if got.TotalTokens != total {
+				t.Errorf("TotalTokens = %d, want %d", got.TotalTokens, total)
+			}
+			if got.UnestimatedParts != tt.wantUn {
+				t.Errorf("UnestimatedParts = %d, want %d", got.UnestimatedParts, tt.wantUn)
+			}
+		})
+	}
+}
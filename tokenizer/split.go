@@ -0,0 +1,305 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	sentencepiece "github.com/eliben/go-sentencepiece"
+	"google.golang.org/genai"
+)
+
+// ErrContentsTooLarge is returned by [LocalTokenizer.TruncateContents] when
+// the contents can't fit the token budget, even after dropping and shortening
+// everything that can be.
+var ErrContentsTooLarge = errors.New("contents don't fit the token budget")
+
+// Break levels of the positions where a text can be split, from the least to
+// the most preferred.
+const (
+	breakToken = iota
+	breakSentence
+	breakParagraph
+)
+
+// SplitConfig configures [LocalTokenizer.SplitText].
+type SplitConfig struct {
+	// Required. MaxTokens is the maximum number of tokens of a chunk.
+	MaxTokens int
+	// Optional. OverlapTokens is the number of tokens at the end of a chunk
+	// that are repeated at the start of the next one. It must be less than
+	// MaxTokens.
+	OverlapTokens int
+}
+
+// Chunk is a part of a text split by [LocalTokenizer.SplitText] or
+// [LocalTokenizer.PreviewChunks].
+type Chunk struct {
+	// Text is the text of the chunk, without leading and trailing whitespace.
+	Text string
+	// Start and End are the byte offsets of Text in the source text.
+	Start int
+	End   int
+	// Tokens is the number of tokens of the chunk.
+	Tokens int
+}
+
+// SplitText splits text into chunks of at most [SplitConfig.MaxTokens]
+// tokens. Chunks end at paragraph boundaries when possible, then at sentence
+// boundaries, and only split in the middle of a sentence when a sentence
+// doesn't fit in a chunk.
+func (tok *LocalTokenizer) SplitText(text string, config *SplitConfig) ([]Chunk, error) {
+	if config == nil || config.MaxTokens <= 0 {
+		return nil, fmt.Errorf("max tokens must be positive")
+	}
+	if config.OverlapTokens < 0 || config.OverlapTokens >= config.MaxTokens {
+		return nil, fmt.Errorf("overlap tokens must be between 0 and max tokens - 1, got %d", config.OverlapTokens)
+	}
+	spans := tokenSpans(text, tok.processor.Encode(text))
+	n := len(spans)
+
+	var chunks []Chunk
+	for start := 0; start < n; {
+		end := start + config.MaxTokens
+		if end >= n {
+			end = n
+		} else {
+			end = bestBreak(text, spans, start, end)
+		}
+		chunk := newChunk(text, spans[start].start, spans[end-1].end, end-start)
+		if chunk.Text != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == n {
+			break
+		}
+		start = max(end-config.OverlapTokens, start+1)
+	}
+	return chunks, nil
+}
+
+// bestBreak returns where to end a chunk that starts at token start and can't
+// extend beyond token limit: the last break of the highest level in the second
+// half of the chunk, or limit if there is none.
+func bestBreak(text string, spans []tokenSpan, start, limit int) int {
+	best, bestLevel := limit, breakToken
+	for i := limit; i > start && i >= start+(limit-start+1)/2; i-- {
+		if level := breakLevel(text, spans[i].start); level > bestLevel {
+			best, bestLevel = i, level
+		}
+	}
+	return best
+}
+
+// breakLevel returns the level of a break before the byte at offset.
+func breakLevel(text string, offset int) int {
+	before := strings.TrimRight(text[:offset], " \t")
+	switch {
+	case strings.HasSuffix(before, "\n\n"), strings.HasSuffix(before, "\r\n\r\n"):
+		return breakParagraph
+	case strings.HasSuffix(before, "\n"):
+		return breakSentence
+	}
+	r, _ := utf8.DecodeLastRuneInString(before)
+	if !strings.ContainsRune(".!?。！？", r) {
+		return breakToken
+	}
+	// A sentence ends at a punctuation mark followed by whitespace, or by the
+	// end of a CJK sentence.
+	if len(before) < offset || r > unicode.MaxASCII {
+		return breakSentence
+	}
+	if next, _ := utf8.DecodeRuneInString(text[offset:]); unicode.IsSpace(next) {
+		return breakSentence
+	}
+	return breakToken
+}
+
+// newChunk returns the chunk of text between the byte offsets start and end,
+// trimmed of whitespace.
+func newChunk(text string, start, end, tokens int) Chunk {
+	s := text[start:end]
+	trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+	start += len(s) - len(trimmed)
+	trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	return Chunk{Text: trimmed, Start: start, End: start + len(trimmed), Tokens: tokens}
+}
+
+// tokenSpan is the byte range of a token in the source text.
+type tokenSpan struct {
+	start, end int
+}
+
+// byteTokenRegexp matches the byte fallback tokens of sentencepiece models.
+var byteTokenRegexp = regexp.MustCompile(`^<0x[0-9A-Fa-f]{2}>$`)
+
+// tokenSpans returns the byte ranges of tokens in text. Sentencepiece encodes
+// spaces as "▁" and unknown characters as one token per byte.
+func tokenSpans(text string, tokens []sentencepiece.Token) []tokenSpan {
+	spans := make([]tokenSpan, len(tokens))
+	offset := 0
+	for i, token := range tokens {
+		n := len(strings.ReplaceAll(token.Text, "▁", " "))
+		if byteTokenRegexp.MatchString(token.Text) {
+			n = 1
+		}
+		end := min(offset+n, len(text))
+		spans[i] = tokenSpan{start: offset, end: end}
+		offset = end
+	}
+	return spans
+}
+
+// TruncateContents returns the most recent contents that fit in maxTokens,
+// including the tokens of config as counted by [LocalTokenizer.CountTokens].
+//
+// The oldest contents are dropped first. A content with function calls and
+// the following content with their responses are dropped together, so that
+// the result never starts with a function response or has calls without
+// responses. The last content is never dropped; if the contents still don't
+// fit, the oldest text parts are shortened from their start. If that isn't
+// enough, [ErrContentsTooLarge] is returned.
+//
+// The contents are not modified; shortened contents are copies.
+func (tok *LocalTokenizer) TruncateContents(contents []*genai.Content, maxTokens int, config *genai.CountTokensConfig) ([]*genai.Content, error) {
+	count := func(contents []*genai.Content, config *genai.CountTokensConfig) (int, error) {
+		result, err := tok.CountTokens(contents, config)
+		if err != nil {
+			return 0, err
+		}
+		return int(result.TotalTokens), nil
+	}
+	if maxTokens <= 0 {
+		return nil, fmt.Errorf("max tokens must be positive")
+	}
+	budget := maxTokens
+	if config != nil {
+		configTokens, err := count(nil, config)
+		if err != nil {
+			return nil, err
+		}
+		budget -= configTokens
+	}
+
+	var kept []*genai.Content
+	for _, content := range contents {
+		if content != nil {
+			kept = append(kept, content)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, nil
+	}
+	units := functionCallUnits(kept)
+	tokens := make([]int, len(units))
+	total := 0
+	for i, unit := range units {
+		n, err := count(unit, nil)
+		if err != nil {
+			return nil, err
+		}
+		tokens[i] = n
+		total += n
+	}
+
+	// Drop the oldest units, keeping at least the last one.
+	first := 0
+	for total > budget && first < len(units)-1 {
+		total -= tokens[first]
+		first++
+	}
+	var result []*genai.Content
+	for _, unit := range units[first:] {
+		result = append(result, unit...)
+	}
+
+	// Shorten the oldest text parts.
+	for i := 0; total > budget && i < len(result); i++ {
+		content := result[i]
+		shortened := &genai.Content{Role: content.Role}
+		for _, part := range content.Parts {
+			if total <= budget || part == nil || part.Text == "" {
+				shortened.Parts = append(shortened.Parts, part)
+				continue
+			}
+			spans := tokenSpans(part.Text, tok.processor.Encode(part.Text))
+			excess := total - budget
+			if excess >= len(spans) {
+				// Drop the whole part.
+				total -= len(spans)
+				continue
+			}
+			p := *part
+			p.Text = strings.TrimLeftFunc(part.Text[spans[excess].start:], unicode.IsSpace)
+			total -= excess
+			shortened.Parts = append(shortened.Parts, &p)
+		}
+		result[i] = shortened
+	}
+	if total > budget {
+		return nil, ErrContentsTooLarge
+	}
+	// Counting the shortened texts again can differ slightly from the estimate
+	// above, as tokens at the cut may merge differently.
+	n, err := count(result, config)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxTokens {
+		return nil, ErrContentsTooLarge
+	}
+	return result, nil
+}
+
+// functionCallUnits groups contents into units that must be kept or dropped
+// together: a content with function calls and the contents with their
+// responses that follow it.
+func functionCallUnits(contents []*genai.Content) [][]*genai.Content {
+	var units [][]*genai.Content
+	for _, content := range contents {
+		if len(units) > 0 && hasFunctionResponse(content) {
+			last := units[len(units)-1]
+			if hasFunctionCall(last[0]) || hasFunctionResponse(last[0]) {
+				units[len(units)-1] = append(last, content)
+				continue
+			}
+		}
+		units = append(units, []*genai.Content{content})
+	}
+	return units
+}
+
+func hasFunctionCall(content *genai.Content) bool {
+	for _, part := range content.Parts {
+		if part != nil && part.FunctionCall != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFunctionResponse(content *genai.Content) bool {
+	for _, part := range content.Parts {
+		if part != nil && part.FunctionResponse != nil {
+			return true
+		}
+	}
+	return false
+}
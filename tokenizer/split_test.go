@@ -0,0 +1,243 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tokenizer
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	sentencepiece "github.com/eliben/go-sentencepiece"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+)
+
+// wordEncoder is an encoder with a token per word, including its leading
+// space, and per newline.
+type wordEncoder struct{}
+
+var wordRegexp = regexp.MustCompile(` ?[^\s]+|\n| `)
+
+func (wordEncoder) Encode(text string) []sentencepiece.Token {
+	var tokens []sentencepiece.Token
+	for _, word := range wordRegexp.FindAllString(text, -1) {
+		tokens = append(tokens, sentencepiece.Token{Text: strings.ReplaceAll(word, " ", "▁")})
+	}
+	return tokens
+}
+
+func TestSplitText(t *testing.T) {
+	tok := &LocalTokenizer{processor: wordEncoder{}}
+	text := "One two three. Four five six.\n\nSeven eight nine ten."
+	chunk := func(s string, tokens int) Chunk {
+		start := strings.Index(text, s)
+		return Chunk{Text: s, Start: start, End: start + len(s), Tokens: tokens}
+	}
+	tests := []struct {
+		name   string
+		text   string
+		config *SplitConfig
+		want   []Chunk
+	}{
+		{
+			name:   "Paragraphs",
+			text:   text,
+			config: &SplitConfig{MaxTokens: 8},
+			want: []Chunk{
+				chunk("One two three. Four five six.", 8),
+				chunk("Seven eight nine ten.", 4),
+			},
+		},
+		{
+			name:   "Sentences",
+			text:   text,
+			config: &SplitConfig{MaxTokens: 4},
+			want: []Chunk{
+				chunk("One two three.", 3),
+				chunk("Four five six.", 4),
+				chunk("Seven eight nine", 4),
+				chunk("ten.", 1),
+			},
+		},
+		{
+			name:   "Fits",
+			text:   text,
+			config: &SplitConfig{MaxTokens: 100},
+			want:   []Chunk{chunk(text, 12)},
+		},
+		{
+			name:   "Overlap",
+			text:   "a b c d e f",
+			config: &SplitConfig{MaxTokens: 3, OverlapTokens: 1},
+			want: []Chunk{
+				{Text: "a b c", Start: 0, End: 5, Tokens: 3},
+				{Text: "c d e", Start: 4, End: 9, Tokens: 3},
+				{Text: "e f", Start: 8, End: 11, Tokens: 2},
+			},
+		},
+		{
+			name:   "Empty",
+			text:   "",
+			config: &SplitConfig{MaxTokens: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tok.SplitText(tt.text, tt.config)
+			if err != nil {
+				t.Fatalf("SplitText failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("SplitText mismatch (-want +got):\n%s", diff)
+			}
+			for _, c := range got {
+				if tt.text[c.Start:c.End] != c.Text {
+					t.Errorf("text[%d:%d] = %q, want %q", c.Start, c.End, tt.text[c.Start:c.End], c.Text)
+				}
+			}
+		})
+	}
+
+	for _, config := range []*SplitConfig{nil, {MaxTokens: 0}, {MaxTokens: 3, OverlapTokens: 3}} {
+		if _, err := tok.SplitText(text, config); err == nil {
+			t.Errorf("SplitText with config %+v succeeded, want an error", config)
+		}
+	}
+}
+
+func TestTokenSpans(t *testing.T) {
+	// "日" isn't in the vocabulary and falls back to its UTF-8 bytes.
+	text := "日 ab"
+	tokens := []sentencepiece.Token{{Text: "<0xE6>"}, {Text: "<0x97>"}, {Text: "<0xA5>"}, {Text: "▁ab"}}
+	want := []tokenSpan{{0, 1}, {1, 2}, {2, 3}, {3, 6}}
+	if diff := cmp.Diff(want, tokenSpans(text, tokens), cmp.AllowUnexported(tokenSpan{})); diff != "" {
+		t.Errorf("tokenSpans mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// modelTestText is a text with multibyte characters and leading, repeated and
+// trailing whitespace, to split with a real model.
+var modelTestText = strings.Repeat("  Leading spaces.   Repeated   spaces, then a longer sentence that goes on and on.\n\n"+
+	"日本語の文です。これは二番目の文です。Ünïcödé wörds and an emoji 🎉 here!\n\n\n   ", 5)
+
+// newModelTokenizer returns a tokenizer of a real model, read from the file at
+// $GENAI_TOKENIZER_MODEL_PATH if set, or else embedded, cached or downloaded.
+// The test is skipped if the model is unavailable.
+func newModelTokenizer(t *testing.T) *LocalTokenizer {
+	t.Helper()
+	tok, err := NewLocalTokenizerWithConfig("gemini-2.5-flash", &LocalTokenizerConfig{ModelPath: os.Getenv("GENAI_TOKENIZER_MODEL_PATH")})
+	if err != nil {
+		t.Skipf("tokenizer model is unavailable: %v", err)
+	}
+	return tok
+}
+
+// checkChunks checks that chunks of at most maxTokens tokens, without overlap,
+// are slices of text in order that concatenate back to text, but for the
+// whitespace between them.
+func checkChunks(t *testing.T, text string, chunks []Chunk, maxTokens int) {
+	t.Helper()
+	var concatenated strings.Builder
+	offset := 0
+	for i, c := range chunks {
+		if c.Start < offset || c.End > len(text) || text[c.Start:c.End] != c.Text {
+			t.Fatalf("chunk %d = %+v isn't the slice of text after offset %d", i, c, offset)
+		}
+		if c.Text == "" || c.Tokens <= 0 || c.Tokens > maxTokens {
+			t.Errorf("chunk %d = %+v, want text and 1 to %d tokens", i, c, maxTokens)
+		}
+		concatenated.WriteString(c.Text)
+		offset = c.End
+	}
+	withoutSpace := func(s string) string { return strings.Join(strings.Fields(s), "") }
+	if got, want := withoutSpace(concatenated.String()), withoutSpace(text); got != want {
+		t.Errorf("concatenated chunks = %q, want %q without whitespace", got, want)
+	}
+}
+
+func TestSplitTextModel(t *testing.T) {
+	tok := newModelTokenizer(t)
+	for _, maxTokens := range []int{1, 4, 16, 1000} {
+		chunks, err := tok.SplitText(modelTestText, &SplitConfig{MaxTokens: maxTokens})
+		if err != nil {
+			t.Fatalf("SplitText failed: %v", err)
+		}
+		checkChunks(t, modelTestText, chunks, maxTokens)
+	}
+}
+
+func TestTruncateContents(t *testing.T) {
+	tok := &LocalTokenizer{processor: wordEncoder{}}
+	contents := []*genai.Content{
+		genai.NewContentFromText("a b c", genai.RoleUser),
+		genai.NewContentFromFunctionCall("f", nil, genai.RoleModel),
+		genai.NewContentFromFunctionResponse("f", nil, genai.RoleUser),
+		genai.NewContentFromText("d e", genai.RoleModel),
+		genai.NewContentFromText("g h i j", genai.RoleUser),
+	}
+	original := cloneContents(contents)
+	tests := []struct {
+		name      string
+		maxTokens int
+		config    *genai.CountTokensConfig
+		want      []*genai.Content
+	}{
+		{name: "Fits", maxTokens: 11, want: contents},
+		{name: "DropOldest", maxTokens: 10, want: contents[1:]},
+		{name: "DropFunctionCallPair", maxTokens: 7, want: contents[3:]},
+		{
+			name:      "Config",
+			maxTokens: 8,
+			config:    &genai.CountTokensConfig{SystemInstruction: genai.NewContentFromText("s t", genai.RoleUser)},
+			want:      contents[3:],
+		},
+		{name: "KeepLast", maxTokens: 4, want: contents[4:]},
+		{name: "Shorten", maxTokens: 2, want: []*genai.Content{genai.NewContentFromText("i j", genai.RoleUser)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tok.TruncateContents(contents, tt.maxTokens, tt.config)
+			if err != nil {
+				t.Fatalf("TruncateContents failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("TruncateContents mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(original, contents); diff != "" {
+				t.Errorf("TruncateContents modified its input (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// Function calls can't be shortened.
+	calls := contents[1:3]
+	if _, err := tok.TruncateContents(calls, 1, nil); !errors.Is(err, ErrContentsTooLarge) {
+		t.Errorf("TruncateContents error = %v, want %v", err, ErrContentsTooLarge)
+	}
+}
+
+func cloneContents(contents []*genai.Content) []*genai.Content {
+	var clones []*genai.Content
+	for _, content := range contents {
+		clone := &genai.Content{Role: content.Role}
+		for _, part := range content.Parts {
+			p := *part
+			clone.Parts = append(clone.Parts, &p)
+		}
+		clones = append(clones, clone)
+	}
+	return clones
+}
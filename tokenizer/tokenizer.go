@@ -12,9 +12,11 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package tokenizer provides local token counting for Gemini models. This
-// tokenizer downloads its model from the web, but otherwise doesn't require
-// an API call for every [CountTokens] invocation.
+// Package tokenizer provides local token counting for Gemini models. By
+// default this tokenizer downloads its model from the web, but otherwise
+// doesn't require an API call for every [CountTokens] invocation. Use
+// [LocalTokenizerConfig] to load the model from a local source instead, for
+// example in air-gapped environments.
 package tokenizer
 
 import (
@@ -23,8 +25,10 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"sync"
 
@@ -105,29 +109,74 @@ func getLocalTokenizerName(modelName string) (string, error) {
 
 // LocalTokenizer is a local tokenizer for text.
 type LocalTokenizer struct {
-	processor *sentencepiece.Processor
+	processor encoder
+}
+
+// encoder encodes text into tokens. It is implemented by
+// [sentencepiece.Processor].
+type encoder interface {
+	Encode(text string) []sentencepiece.Token
 }
 
 var experimentalWarningLocalTokenizer sync.Once
 
+// embeddedModels holds the models embedded with the genai_tokenizer_embed
+// build tag, named as returned by [ModelFileName]. It is nil without the tag.
+var embeddedModels fs.FS
+
+// LocalTokenizerConfig configures how [NewLocalTokenizerWithConfig] loads the
+// tokenizer model. At most one of ModelData, ModelPath and ModelFS can be
+// set. If none is set, the model embedded in the binary is used if there is
+// one, and otherwise the model is downloaded and cached.
+//
+// Models are embedded when building with the genai_tokenizer_embed build tag,
+// from the files of the tokenizer/models directory of the module, named as
+// returned by [ModelFileName]. The models are not distributed with the module:
+// copy them from [ModelURL] into a checkout or vendored copy of the module
+// before building with the tag.
+//
+// Whatever its source, the model is verified against the SHA-256 hash known
+// for the model name.
+type LocalTokenizerConfig struct {
+	// Optional. ModelData is the content of the tokenizer model.
+	ModelData []byte
+	// Optional. ModelPath is the path of the tokenizer model file.
+	ModelPath string
+	// Optional. ModelFS is a file system that contains the tokenizer model, such
+	// as an [embed.FS] with a copy of the model embedded with a go:embed
+	// directive.
+	ModelFS fs.FS
+	// Optional. ModelFSPath is the path of the model in ModelFS. If empty, the
+	// file name returned by [ModelFileName] is used.
+	ModelFSPath string
+	// Optional. CacheDir is the directory where downloaded models are cached.
+	// If empty, a directory under [os.TempDir] is used.
+	CacheDir string
+	// Optional. HTTPClient is used to download the model. If nil,
+	// [http.DefaultClient] is used.
+	HTTPClient *http.Client
+}
+
 // NewLocalTokenizer creates a new [LocalTokenizer] from a model name; the model name is the same
 // as you would pass to a [genai.Client.GenerativeModel].
 func NewLocalTokenizer(modelName string) (*LocalTokenizer, error) {
+	return NewLocalTokenizerWithConfig(modelName, nil)
+}
+
+// NewLocalTokenizerWithConfig creates a new [LocalTokenizer] from a model
+// name, loading the tokenizer model as configured by config. A nil config
+// behaves like [NewLocalTokenizer].
+func NewLocalTokenizerWithConfig(modelName string, config *LocalTokenizerConfig) (*LocalTokenizer, error) {
 	experimentalWarningLocalTokenizer.Do(func() {
-		fmt.Println("Warning: The SDK's local tokenizer implementation is experimental and may change in the future. It only supports text based tokenization.")
+		fmt.Println("Warning: The SDK's local tokenizer implementation is experimental and may change in the future. It tokenizes text only; media tokens are estimated by EstimateTokens.")
 	})
 
-	tokenizerName, err := getLocalTokenizerName(modelName)
+	tc, err := tokenizerConfigFor(modelName)
 	if err != nil {
 		return nil, fmt.Errorf("model %s is not supported", modelName)
 	}
 
-	config, ok := tokenizers[tokenizerName]
-	if !ok {
-		return nil, fmt.Errorf("model %s is not supported", modelName)
-	}
-
-	data, err := loadModelData(config.modelURL, config.modelHash)
+	data, err := loadModel(tc, config)
 	if err != nil {
 		return nil, fmt.Errorf("loading model: %w", err)
 	}
@@ -140,6 +189,41 @@ func NewLocalTokenizer(modelName string) (*LocalTokenizer, error) {
 	return &LocalTokenizer{processor: processor}, nil
 }
 
+// ModelFileName returns the file name of the tokenizer model used for the
+// given model name, which is the default path looked up in
+// [LocalTokenizerConfig.ModelFS].
+func ModelFileName(modelName string) (string, error) {
+	tc, err := tokenizerConfigFor(modelName)
+	if err != nil {
+		return "", err
+	}
+	return path.Base(tc.modelURL), nil
+}
+
+// ModelURL returns the URL the tokenizer model used for the given model name
+// is downloaded from, for example to fetch it ahead of time.
+func ModelURL(modelName string) (string, error) {
+	tc, err := tokenizerConfigFor(modelName)
+	if err != nil {
+		return "", err
+	}
+	return tc.modelURL, nil
+}
+
+// tokenizerConfigFor returns the tokenizer configuration for the given model
+// name.
+func tokenizerConfigFor(modelName string) (tokenizerConfig, error) {
+	tokenizerName, err := getLocalTokenizerName(modelName)
+	if err != nil {
+		return tokenizerConfig{}, err
+	}
+	tc, ok := tokenizers[tokenizerName]
+	if !ok {
+		return tokenizerConfig{}, fmt.Errorf("model %s is not supported", modelName)
+	}
+	return tc, nil
+}
+
 // CountTokens counts tokens in the given contents with optional configuration,
 // similar to the Python LocalLocalTokenizer.count_tokens method.
 func (tok *LocalTokenizer) CountTokens(contents []*genai.Content, config *genai.CountTokensConfig) (*genai.CountTokensResult, error) {
@@ -361,11 +445,19 @@ func (ta *textsAccumulator) traverseAny(value any) {
 
 // downloadModelFile downloads a file from the given URL.
 func downloadModelFile(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+	return downloadModelFileWithClient(http.DefaultClient, url)
+}
+
+// downloadModelFileWithClient downloads a file from the given URL with client.
+func downloadModelFileWithClient(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
 
 	return io.ReadAll(resp.Body)
 }
@@ -390,13 +482,24 @@ func hashString(data []byte) string {
 // wantHash, downloads data from the URL and writes it into the cache. If the
 // URL's data doesn't match the hash, an error is returned.
 func loadModelData(url string, wantHash string) ([]byte, error) {
+	return loadCachedModelData(url, wantHash, defaultCacheDir(), http.DefaultClient)
+}
+
+// defaultCacheDir returns the directory where downloaded models are cached by
+// default.
+func defaultCacheDir() string {
+	return filepath.Join(os.TempDir(), "vertexai_tokenizer_model")
+}
+
+// loadCachedModelData is like [loadModelData], with a custom cache directory
+// and HTTP client.
+func loadCachedModelData(url, wantHash, cacheDir string, client *http.Client) ([]byte, error) {
 	urlhash := hashString([]byte(url))
-	cacheDir := filepath.Join(os.TempDir(), "vertexai_tokenizer_model")
 	cachePath := filepath.Join(cacheDir, urlhash)
 
 	cacheData, err := os.ReadFile(cachePath)
 	if err != nil || hashString(cacheData) != wantHash {
-		cacheData, err = downloadModelFile(url)
+		cacheData, err = downloadModelFileWithClient(client, url)
 		if err != nil {
 			return nil, fmt.Errorf("loading cache and downloading model: %w", err)
 		}
@@ -417,3 +520,60 @@ func loadModelData(url string, wantHash string) ([]byte, error) {
 
 	return cacheData, nil
 }
+
+// loadModel loads the model data of tc from the source configured by config,
+// and verifies its hash.
+func loadModel(tc tokenizerConfig, config *LocalTokenizerConfig) ([]byte, error) {
+	if config == nil {
+		config = &LocalTokenizerConfig{}
+	}
+	sources := 0
+	for _, set := range []bool{config.ModelData != nil, config.ModelPath != "", config.ModelFS != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, fmt.Errorf("only one of ModelData, ModelPath and ModelFS can be set")
+	}
+
+	var data []byte
+	var err error
+	switch {
+	case config.ModelData != nil:
+		data = config.ModelData
+	case config.ModelPath != "":
+		data, err = os.ReadFile(config.ModelPath)
+	case config.ModelFS != nil:
+		name := config.ModelFSPath
+		if name == "" {
+			name = path.Base(tc.modelURL)
+		}
+		data, err = fs.ReadFile(config.ModelFS, name)
+	case embeddedModels != nil && fileExists(embeddedModels, path.Base(tc.modelURL)):
+		data, err = fs.ReadFile(embeddedModels, path.Base(tc.modelURL))
+	default:
+		cacheDir := config.CacheDir
+		if cacheDir == "" {
+			cacheDir = defaultCacheDir()
+		}
+		client := config.HTTPClient
+		if client == nil {
+			client = http.DefaultClient
+		}
+		return loadCachedModelData(tc.modelURL, tc.modelHash, cacheDir, client)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading model: %w", err)
+	}
+	if gotHash := hashString(data); gotHash != tc.modelHash {
+		return nil, fmt.Errorf("model hash mismatch: got %s, want %s", gotHash, tc.modelHash)
+	}
+	return data, nil
+}
+
+// fileExists reports whether name exists in fsys.
+func fileExists(fsys fs.FS, name string) bool {
+	_, err := fs.Stat(fsys, name)
+	return err == nil
+}
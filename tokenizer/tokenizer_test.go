@@ -15,9 +15,14 @@ package tokenizer
 
 import (
 	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"google.golang.org/genai"
 )
@@ -65,6 +70,124 @@ func TestLoadModelData(t *testing.T) {
 	checkDataAndErr(data, err)
 }
 
+func TestLoadModel(t *testing.T) {
+	modelData := []byte("test tokenizer model")
+	tc := tokenizerConfig{
+		modelURL:  "https://example.com/models/test.model",
+		modelHash: hashString(modelData),
+	}
+	modelPath := filepath.Join(t.TempDir(), "test.model")
+	if err := os.WriteFile(modelPath, modelData, 0660); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  *LocalTokenizerConfig
+		wantErr string
+	}{
+		{name: "ModelData", config: &LocalTokenizerConfig{ModelData: modelData}},
+		{name: "ModelPath", config: &LocalTokenizerConfig{ModelPath: modelPath}},
+		{name: "ModelFS", config: &LocalTokenizerConfig{ModelFS: fstest.MapFS{"test.model": {Data: modelData}}}},
+		{
+			name:   "ModelFSPath",
+			config: &LocalTokenizerConfig{ModelFS: fstest.MapFS{"models/custom.model": {Data: modelData}}, ModelFSPath: "models/custom.model"},
+		},
+		{name: "HashMismatch", config: &LocalTokenizerConfig{ModelData: []byte("tampered")}, wantErr: "hash mismatch"},
+		{name: "MissingFile", config: &LocalTokenizerConfig{ModelFS: fstest.MapFS{}}, wantErr: "reading model"},
+		{name: "SeveralSources", config: &LocalTokenizerConfig{ModelData: modelData, ModelPath: modelPath}, wantErr: "only one of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := loadModel(tc, tt.config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadModel error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadModel failed: %v", err)
+			}
+			if string(data) != string(modelData) {
+				t.Errorf("loadModel = %q, want %q", data, modelData)
+			}
+		})
+	}
+}
+
+func TestLoadModelDownload(t *testing.T) {
+	modelData := []byte("test tokenizer model")
+	var downloads int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		if r.URL.Path != "/test.model" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(modelData)
+	}))
+	defer ts.Close()
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	config := &LocalTokenizerConfig{CacheDir: cacheDir, HTTPClient: ts.Client()}
+	tc := tokenizerConfig{modelURL: ts.URL + "/test.model", modelHash: hashString(modelData)}
+	for range 2 {
+		data, err := loadModel(tc, config)
+		if err != nil {
+			t.Fatalf("loadModel failed: %v", err)
+		}
+		if string(data) != string(modelData) {
+			t.Errorf("loadModel = %q, want %q", data, modelData)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("downloaded the model %d times, want 1 and then the cached copy", downloads)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, hashString([]byte(tc.modelURL)))); err != nil {
+		t.Errorf("model not cached in CacheDir: %v", err)
+	}
+
+	tc.modelURL = ts.URL + "/missing.model"
+	if _, err := loadModel(tc, config); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("loadModel error = %v, want a 404 error", err)
+	}
+}
+
+func TestLoadModelEmbedded(t *testing.T) {
+	modelData := []byte("test tokenizer model")
+	defer func(models fs.FS) { embeddedModels = models }(embeddedModels)
+	embeddedModels = fstest.MapFS{"test.model": {Data: modelData}}
+
+	// The embedded model is used without downloading it.
+	tc := tokenizerConfig{modelURL: "http://127.0.0.1:0/test.model", modelHash: hashString(modelData)}
+	data, err := loadModel(tc, &LocalTokenizerConfig{CacheDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("loadModel failed: %v", err)
+	}
+	if string(data) != string(modelData) {
+		t.Errorf("loadModel = %q, want %q", data, modelData)
+	}
+
+	tc.modelHash = hashString([]byte("another model"))
+	if _, err := loadModel(tc, nil); err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+		t.Errorf("loadModel error = %v, want a hash mismatch", err)
+	}
+}
+
+func TestModelFileName(t *testing.T) {
+	name, err := ModelFileName("gemini-2.5-flash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "gemma3_cleaned_262144_v2.spiece.model"; name != want {
+		t.Errorf("ModelFileName = %q, want %q", name, want)
+	}
+	if _, err := ModelFileName("gemini-0.92"); err == nil {
+		t.Errorf("ModelFileName for an unsupported model succeeded, want an error")
+	}
+}
+
 func TestCreateLocalTokenizer(t *testing.T) {
 	// Create a tokenizer successfully with gemma2 model
 	_, err := NewLocalTokenizer("gemini-1.5-flash")
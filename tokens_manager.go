@@ -0,0 +1,316 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAuthTokenLifetime           = 30 * time.Minute
+	defaultAuthTokenNewSessionLifetime = time.Minute
+	defaultAuthTokenRefreshMargin      = 10 * time.Second
+	defaultAuthTokenMintTimeout        = 30 * time.Second
+)
+
+// Preview. ManagedAuthToken is an ephemeral token handed out by an [AuthTokenManager].
+type ManagedAuthToken struct {
+	// Name is the token, to be used as the API key of a Live connection.
+	Name string `json:"name"`
+	// ExpireTime is when messages in sessions opened with the token start being
+	// rejected.
+	ExpireTime time.Time `json:"expireTime"`
+	// NewSessionExpireTime is when new sessions opened with the token start
+	// being rejected.
+	NewSessionExpireTime time.Time `json:"newSessionExpireTime"`
+}
+
+// Preview. AuthTokenManagerConfig configures an [AuthTokenManager].
+type AuthTokenManagerConfig struct {
+	// Optional. Policy returns the configuration of the tokens minted for key,
+	// typically a user ID. Use it to choose [LiveConnectConstraints] and
+	// [CreateAuthTokenConfig.LockAdditionalFields] per user; they are converted
+	// to the token's setup and field mask as in [Tokens.Create]. Zero
+	// ExpireTime, NewSessionExpireTime and Uses are set from the fields below.
+	// If nil, unconstrained tokens are minted.
+	Policy func(ctx context.Context, key string) (*CreateAuthTokenConfig, error)
+	// Optional. Lifetime sets [CreateAuthTokenConfig.ExpireTime] relative to the
+	// time the token is minted. If zero, 30 minutes is used.
+	Lifetime time.Duration
+	// Optional. NewSessionLifetime sets
+	// [CreateAuthTokenConfig.NewSessionExpireTime] relative to the time the
+	// token is minted. It must be greater than RefreshMargin. If zero, 1 minute
+	// is used.
+	NewSessionLifetime time.Duration
+	// Optional. Uses is the number of sessions each token can open. If zero, 1
+	// is used. If negative, the number of uses is not limited.
+	Uses int32
+	// Optional. RefreshMargin is how long before NewSessionExpireTime a token
+	// stops being handed out, so that clients have time to open their session.
+	// A replacement is minted in the background when a token gets within twice
+	// this margin. If zero, 10 seconds is used.
+	RefreshMargin time.Duration
+	// Optional. MintTimeout bounds each call to [Tokens.Create]. A mint is
+	// shared by the concurrent callers of [AuthTokenManager.Token] for the same
+	// key, so it isn't bound by their contexts. If zero, 30 seconds is used.
+	MintTimeout time.Duration
+}
+
+// Preview. AuthTokenManager caches the ephemeral tokens minted with [Tokens.Create] per
+// key, hands each token out as many times as it can be used, and refreshes
+// tokens before they expire.
+//
+// An AuthTokenManager is safe for concurrent use.
+type AuthTokenManager struct {
+	tokens Tokens
+	config AuthTokenManagerConfig
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*authTokenEntry
+}
+
+// authTokenEntry is the cached token of a key.
+type authTokenEntry struct {
+	token *ManagedAuthToken
+	// remaining is the number of uses left, or -1 if not limited.
+	remaining int32
+	// pending is the mint in progress, if any.
+	pending *authTokenMint
+}
+
+// authTokenMint is a token being minted. done is closed when it finishes.
+type authTokenMint struct {
+	done chan struct{}
+	err  error
+}
+
+// Preview. NewManager returns a token manager that mints tokens with m. It
+// returns an error if the new session lifetime of the tokens isn't greater than
+// the refresh margin, since such tokens could never be handed out.
+func (m Tokens) NewManager(config *AuthTokenManagerConfig) (*AuthTokenManager, error) {
+	tm := &AuthTokenManager{tokens: m, now: time.Now, entries: make(map[string]*authTokenEntry)}
+	if config != nil {
+		tm.config = *config
+	}
+	if tm.config.Lifetime <= 0 {
+		tm.config.Lifetime = defaultAuthTokenLifetime
+	}
+	if tm.config.NewSessionLifetime <= 0 {
+		tm.config.NewSessionLifetime = defaultAuthTokenNewSessionLifetime
+	}
+	if tm.config.Uses == 0 {
+		tm.config.Uses = 1
+	}
+	if tm.config.RefreshMargin <= 0 {
+		tm.config.RefreshMargin = defaultAuthTokenRefreshMargin
+	}
+	if tm.config.MintTimeout <= 0 {
+		tm.config.MintTimeout = defaultAuthTokenMintTimeout
+	}
+	if min(tm.config.Lifetime, tm.config.NewSessionLifetime) <= tm.config.RefreshMargin {
+		return nil, fmt.Errorf("auth token lifetimes must be greater than the refresh margin %v", tm.config.RefreshMargin)
+	}
+	return tm, nil
+}
+
+// Preview. Token returns a token for key that can open a new session, minting
+// one if the cached token is used up or about to expire. Each call counts as
+// one use of the returned token.
+//
+// A freshly minted token is handed out even if it is within the refresh
+// margin, for example if [AuthTokenManagerConfig.Policy] set a short
+// NewSessionExpireTime, as long as it can still open a session.
+func (m *AuthTokenManager) Token(ctx context.Context, key string) (*ManagedAuthToken, error) {
+	for {
+		m.mu.Lock()
+		e := m.entries[key]
+		if e == nil {
+			e = &authTokenEntry{}
+			m.entries[key] = e
+		}
+		if m.usable(e, m.now()) {
+			token := m.take(ctx, key, e)
+			m.mu.Unlock()
+			return token, nil
+		}
+		mint := m.startMint(ctx, key, e)
+		m.mu.Unlock()
+
+		select {
+		case <-mint.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if mint.err != nil {
+			return nil, mint.err
+		}
+
+		m.mu.Lock()
+		// Concurrent callers may have used up the minted token, in which case
+		// another one is minted.
+		if e.token != nil && e.remaining != 0 {
+			if !e.token.NewSessionExpireTime.After(m.now()) {
+				m.mu.Unlock()
+				return nil, fmt.Errorf("auth token minted for %q can no longer open a session", key)
+			}
+			token := m.take(ctx, key, e)
+			m.mu.Unlock()
+			return token, nil
+		}
+		m.mu.Unlock()
+	}
+}
+
+// take uses the cached token of e once and returns a copy of it, starting to
+// mint a replacement if it is used up or close to expiry. It must be called
+// with m.mu held.
+func (m *AuthTokenManager) take(ctx context.Context, key string, e *authTokenEntry) *ManagedAuthToken {
+	token := *e.token
+	if e.remaining > 0 {
+		e.remaining--
+	}
+	if e.remaining == 0 || e.token.NewSessionExpireTime.Sub(m.now()) < 2*m.config.RefreshMargin {
+		m.startMint(ctx, key, e)
+	}
+	return &token
+}
+
+// Preview. Invalidate drops the cached token of key, for example after a client
+// reported that it was rejected.
+func (m *AuthTokenManager) Invalidate(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e := m.entries[key]; e != nil {
+		e.token = nil
+	}
+}
+
+// usable reports whether the cached token of e can be handed out at now.
+func (m *AuthTokenManager) usable(e *authTokenEntry, now time.Time) bool {
+	return e.token != nil && e.remaining != 0 && e.token.NewSessionExpireTime.Sub(now) >= m.config.RefreshMargin
+}
+
+// startMint starts minting a token for key unless a mint is already in
+// progress, and returns the mint. It must be called with m.mu held.
+func (m *AuthTokenManager) startMint(ctx context.Context, key string, e *authTokenEntry) *authTokenMint {
+	if e.pending != nil {
+		return e.pending
+	}
+	mint := &authTokenMint{done: make(chan struct{})}
+	e.pending = mint
+	// The mint is shared by concurrent callers, so it isn't cancelled with the
+	// context of the caller that started it.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.config.MintTimeout)
+	go func() {
+		defer cancel()
+		token, uses, err := m.mint(ctx, key)
+		m.mu.Lock()
+		if err == nil {
+			e.token = token
+			e.remaining = uses
+		}
+		mint.err = err
+		e.pending = nil
+		m.mu.Unlock()
+		close(mint.done)
+	}()
+	return mint
+}
+
+// mint creates a token for key and returns it with its number of uses, or -1
+// if the uses are not limited.
+func (m *AuthTokenManager) mint(ctx context.Context, key string) (*ManagedAuthToken, int32, error) {
+	config := &CreateAuthTokenConfig{}
+	if m.config.Policy != nil {
+		policy, err := m.config.Policy(ctx, key)
+		if err != nil {
+			return nil, 0, err
+		}
+		if policy != nil {
+			c := *policy
+			config = &c
+		}
+	}
+	now := m.now()
+	if config.ExpireTime.IsZero() {
+		config.ExpireTime = now.Add(m.config.Lifetime)
+	}
+	if config.NewSessionExpireTime.IsZero() {
+		config.NewSessionExpireTime = now.Add(m.config.NewSessionLifetime)
+	}
+	if config.NewSessionExpireTime.After(config.ExpireTime) {
+		config.NewSessionExpireTime = config.ExpireTime
+	}
+	if config.Uses == nil {
+		uses := max(m.config.Uses, 0)
+		config.Uses = &uses
+	}
+	token, err := m.tokens.Create(ctx, config)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create auth token: %w", err)
+	}
+	uses := *config.Uses
+	if uses == 0 {
+		uses = -1
+	}
+	return &ManagedAuthToken{
+		Name:                 token.Name,
+		ExpireTime:           config.ExpireTime,
+		NewSessionExpireTime: config.NewSessionExpireTime,
+	}, uses, nil
+}
+
+// Preview. Handler returns an [http.Handler] that hands out tokens to browser clients.
+// key authenticates the request and returns the key of the user, typically a
+// user ID that selects the user's policy. If key returns an error, the request
+// is rejected with 403 Forbidden.
+//
+// The handler responds with the JSON encoding of a [ManagedAuthToken].
+func (m *AuthTokenManager) Handler(key func(r *http.Request) (string, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		k, err := key(r)
+		if err != nil {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		token, err := m.Token(r.Context(), k)
+		if err != nil {
+			status := http.StatusInternalServerError
+			var apiErr APIError
+			if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+				status = http.StatusTooManyRequests
+			}
+			http.Error(w, "failed to create token", status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(token); err != nil {
+			http.Error(w, "failed to encode token", http.StatusInternalServerError)
+		}
+	})
+}
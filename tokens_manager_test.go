@@ -0,0 +1,296 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestAuthTokenServer returns a server that mints tokens named
+// "auth_tokens/1", "auth_tokens/2", ... and records the request bodies.
+func newTestAuthTokenServer(t *testing.T) (*httptest.Server, func() []map[string]any) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("ReadAll failed: %v", err)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("Unmarshal failed: %v", err)
+		}
+		mu.Lock()
+		bodies = append(bodies, body)
+		n := len(bodies)
+		mu.Unlock()
+		fmt.Fprintf(w, `{"name":"auth_tokens/%d"}`, n)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, func() []map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]map[string]any(nil), bodies...)
+	}
+}
+
+func newTestAuthTokenManager(t *testing.T, ts *httptest.Server, config *AuthTokenManagerConfig) (*AuthTokenManager, *time.Time) {
+	t.Helper()
+	client, err := NewClient(context.Background(), &ClientConfig{
+		Backend:     BackendGeminiAPI,
+		APIKey:      "test-api-key",
+		HTTPOptions: HTTPOptions{BaseURL: ts.URL, APIVersion: "v1alpha"},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	manager, err := client.AuthTokens.NewManager(config)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+	return manager, &now
+}
+
+func TestAuthTokenManager(t *testing.T) {
+	ctx := context.Background()
+	ts, bodies := newTestAuthTokenServer(t)
+	manager, now := newTestAuthTokenManager(t, ts, &AuthTokenManagerConfig{
+		Uses:               2,
+		NewSessionLifetime: time.Minute,
+		RefreshMargin:      10 * time.Second,
+	})
+
+	token := func() string {
+		t.Helper()
+		tok, err := manager.Token(ctx, "alice")
+		if err != nil {
+			t.Fatalf("Token failed: %v", err)
+		}
+		return tok.Name
+	}
+	// waitMints waits for the background mints to finish.
+	waitMints := func() {
+		manager.mu.Lock()
+		var pending []*authTokenMint
+		for _, e := range manager.entries {
+			if e.pending != nil {
+				pending = append(pending, e.pending)
+			}
+		}
+		manager.mu.Unlock()
+		for _, mint := range pending {
+			<-mint.done
+		}
+	}
+
+	// The first token is used twice, and a replacement is minted in the
+	// background when it is used up.
+	got := []string{token(), token()}
+	waitMints()
+	got = append(got, token())
+	want := []string{"auth_tokens/1", "auth_tokens/1", "auth_tokens/2"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("tokens = %v, want %v", got, want)
+	}
+
+	// A token close to its new session expiry triggers a background refresh,
+	// and one within the refresh margin is not handed out.
+	*now = now.Add(45 * time.Second)
+	if got := token(); got != "auth_tokens/2" {
+		t.Errorf("token close to expiry = %s, want auth_tokens/2", got)
+	}
+	waitMints()
+	*now = now.Add(10 * time.Second)
+	if got := token(); got != "auth_tokens/3" {
+		t.Errorf("token after refresh = %s, want auth_tokens/3", got)
+	}
+
+	if got := len(bodies()); got != 3 {
+		t.Errorf("minted %d tokens, want 3", got)
+	}
+	for _, body := range bodies() {
+		if body["uses"] != 2.0 {
+			t.Errorf("uses = %v, want 2", body["uses"])
+		}
+		if body["expireTime"] == nil || body["newSessionExpireTime"] == nil {
+			t.Errorf("token request %v has no expiry, want expireTime and newSessionExpireTime", body)
+		}
+	}
+
+	manager.Invalidate("alice")
+	if got := token(); got != "auth_tokens/4" {
+		t.Errorf("token after Invalidate = %s, want auth_tokens/4", got)
+	}
+}
+
+func TestAuthTokenManagerPolicy(t *testing.T) {
+	ctx := context.Background()
+	ts, bodies := newTestAuthTokenServer(t)
+	manager, _ := newTestAuthTokenManager(t, ts, &AuthTokenManagerConfig{
+		Policy: func(ctx context.Context, key string) (*CreateAuthTokenConfig, error) {
+			if key == "mallory" {
+				return nil, errors.New("banned")
+			}
+			return &CreateAuthTokenConfig{
+				LiveConnectConstraints: &LiveConnectConstraints{
+					Model:  "gemini-live",
+					Config: &LiveConnectConfig{Temperature: Ptr[float32](0.5)},
+				},
+				LockAdditionalFields: []string{},
+			}, nil
+		},
+	})
+
+	if _, err := manager.Token(ctx, "mallory"); err == nil {
+		t.Errorf("Token for a rejected key succeeded, want an error")
+	}
+	if _, err := manager.Token(ctx, "alice"); err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	got := bodies()
+	if len(got) != 1 {
+		t.Fatalf("minted %d tokens, want 1", len(got))
+	}
+	setup, ok := got[0]["bidiGenerateContentSetup"].(map[string]any)
+	if !ok || !strings.HasSuffix(setup["model"].(string), "gemini-live") {
+		t.Errorf("bidiGenerateContentSetup = %v, want the constrained model", got[0]["bidiGenerateContentSetup"])
+	}
+	if mask, _ := got[0]["fieldMask"].(string); !slices.Contains(strings.Split(mask, ","), "generationConfig.temperature") {
+		t.Errorf("fieldMask = %v, want it to lock generationConfig.temperature", got[0]["fieldMask"])
+	}
+}
+
+func TestAuthTokenManagerShortLifetime(t *testing.T) {
+	ctx := context.Background()
+	client, err := NewClient(ctx, &ClientConfig{Backend: BackendGeminiAPI, APIKey: "test-api-key"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if _, err := client.AuthTokens.NewManager(&AuthTokenManagerConfig{NewSessionLifetime: 5 * time.Second}); err == nil {
+		t.Errorf("NewManager with a lifetime within the refresh margin succeeded, want an error")
+	}
+
+	// The policy sets a lifetime within the refresh margin.
+	ts, bodies := newTestAuthTokenServer(t)
+	lifetimes := map[string]time.Duration{"alice": 5 * time.Second, "bob": 0}
+	var now *time.Time
+	manager, now := newTestAuthTokenManager(t, ts, &AuthTokenManagerConfig{
+		Policy: func(ctx context.Context, key string) (*CreateAuthTokenConfig, error) {
+			return &CreateAuthTokenConfig{NewSessionExpireTime: now.Add(lifetimes[key])}, nil
+		},
+	})
+	token, err := manager.Token(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if token.Name != "auth_tokens/1" {
+		t.Errorf("token = %s, want auth_tokens/1", token.Name)
+	}
+
+	// A minted token that can't open a session anymore is an error.
+	if _, err := manager.Token(ctx, "bob"); err == nil {
+		t.Errorf("Token with an expired minted token succeeded, want an error")
+	}
+	if got := len(bodies()); got > 3 {
+		t.Errorf("minted %d tokens, want at most 3", got)
+	}
+}
+
+func TestAuthTokenManagerMintTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The mint hangs until the client gives up.
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(ts.Close)
+	manager, _ := newTestAuthTokenManager(t, ts, &AuthTokenManagerConfig{MintTimeout: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := manager.Token(ctx, "alice"); err == nil || ctx.Err() != nil {
+		t.Errorf("Token with a hung mint returned %v, want an error before the context deadline", err)
+	}
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	if e := manager.entries["alice"]; e.pending != nil {
+		t.Errorf("mint still pending after its timeout")
+	}
+}
+
+func TestAuthTokenManagerHandler(t *testing.T) {
+	ts, _ := newTestAuthTokenServer(t)
+	manager, now := newTestAuthTokenManager(t, ts, nil)
+	handler := manager.Handler(func(r *http.Request) (string, error) {
+		user := r.Header.Get("X-User")
+		if user == "" {
+			return "", errors.New("not signed in")
+		}
+		return user, nil
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		user       string
+		wantStatus int
+	}{
+		{name: "SignedIn", method: http.MethodPost, user: "alice", wantStatus: http.StatusOK},
+		{name: "NotSignedIn", method: http.MethodPost, wantStatus: http.StatusForbidden},
+		{name: "WrongMethod", method: http.MethodDelete, user: "alice", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/token", nil)
+			if tt.user != "" {
+				req.Header.Set("X-User", tt.user)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var token ManagedAuthToken
+			if err := json.Unmarshal(rec.Body.Bytes(), &token); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if token.Name != "auth_tokens/1" {
+				t.Errorf("Name = %q, want auth_tokens/1", token.Name)
+			}
+			if want := now.Add(defaultAuthTokenNewSessionLifetime); !token.NewSessionExpireTime.Equal(want) {
+				t.Errorf("NewSessionExpireTime = %v, want %v", token.NewSessionExpireTime, want)
+			}
+			if rec.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", rec.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
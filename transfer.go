@@ -0,0 +1,152 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// uploadChunkGranularity is the granularity of the chunk sizes accepted by the
+// resumable upload protocol.
+const uploadChunkGranularity = 256 * 1024
+
+// TransferProgress reports the progress of an upload or a download.
+type TransferProgress struct {
+	// Bytes is the number of bytes transferred so far. For uploads, it is the
+	// number of bytes committed by the server.
+	Bytes int64
+	// Total is the size of the transfer in bytes, or -1 if it is unknown.
+	Total int64
+}
+
+// uploadOptions configures how [apiClient.uploadFrom] uploads the data of a
+// file.
+type uploadOptions struct {
+	chunkSize    int
+	chunkTimeout time.Duration
+	timeout      time.Duration
+	onProgress   func(progress TransferProgress)
+	// onCommit, if not nil, is called with the offset committed by the server
+	// after each chunk that doesn't finalize the upload.
+	onCommit func(offset int64)
+}
+
+// newUploadOptions validates the fields of an [UploadTransferConfig] and
+// returns them with the defaults applied.
+func newUploadOptions(chunkSize int, chunkTimeout, timeout time.Duration, onProgress func(TransferProgress)) (*uploadOptions, error) {
+	if chunkSize < 0 || chunkSize%uploadChunkGranularity != 0 {
+		return nil, fmt.Errorf("upload chunk size must be a positive multiple of %d bytes, got %d", uploadChunkGranularity, chunkSize)
+	}
+	if chunkSize == 0 {
+		chunkSize = maxChunkSize
+	}
+	if chunkTimeout < 0 || timeout < 0 {
+		return nil, fmt.Errorf("upload timeouts must not be negative")
+	}
+	return &uploadOptions{chunkSize: chunkSize, chunkTimeout: chunkTimeout, timeout: timeout, onProgress: onProgress}, nil
+}
+
+// UploadTransferConfig configures the transfer of the data of an upload.
+type UploadTransferConfig struct {
+	// Optional. ChunkSize is the size of the chunks of the upload, in bytes. It
+	// must be a multiple of 256 KiB. If zero, 8 MiB is used.
+	ChunkSize int
+	// Optional. ChunkTimeout bounds each upload request, so that a stalled chunk
+	// fails without waiting for the whole upload to time out. If zero,
+	// HTTPOptions.Timeout is used.
+	ChunkTimeout time.Duration
+	// Optional. Timeout bounds the whole upload, including retries. If zero, the
+	// upload is only bounded by the context.
+	Timeout time.Duration
+	// Optional. OnProgress is called after each chunk of the upload is committed
+	// by the server. It is called from the goroutine of the upload.
+	OnProgress func(progress TransferProgress)
+}
+
+// uploadOptions returns the upload options of c, which may be nil.
+func (c *UploadTransferConfig) uploadOptions() (*uploadOptions, error) {
+	if c == nil {
+		return newUploadOptions(0, 0, 0, nil)
+	}
+	return newUploadOptions(c.ChunkSize, c.ChunkTimeout, c.Timeout, c.OnProgress)
+}
+
+// reportProgress calls the progress callback of o, if any.
+func (o *uploadOptions) reportProgress(bytes, total int64) {
+	if o.onProgress != nil {
+		o.onProgress(TransferProgress{Bytes: bytes, Total: total})
+	}
+}
+
+// uploadSize returns the size of the data uploaded from r, from its current
+// position, or -1 if it is unknown. It is read from the
+// X-Goog-Upload-Header-Content-Length header set by the callers that know it,
+// or from r if it reports its length or can seek.
+func uploadSize(r io.Reader, httpOptions *HTTPOptions) int64 {
+	if httpOptions != nil && httpOptions.Headers != nil {
+		if size, err := strconv.ParseInt(httpOptions.Headers.Get("X-Goog-Upload-Header-Content-Length"), 10, 64); err == nil {
+			return size
+		}
+	}
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		// bytes.Reader, strings.Reader and bytes.Buffer report their unread bytes.
+		return int64(r.Len())
+	case io.Seeker:
+		if f, ok := r.(*os.File); ok {
+			// Pipes and terminals may seek without having a size.
+			if info, err := f.Stat(); err != nil || !info.Mode().IsRegular() {
+				return -1
+			}
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - offset
+	}
+	return -1
+}
+
+// progressReader reports the progress of the data read from r to onProgress,
+// if not nil.
+type progressReader struct {
+	r          io.Reader
+	onProgress func(progress TransferProgress)
+	bytes      int64
+	total      int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.bytes += int64(n)
+		if p.onProgress != nil {
+			p.onProgress(TransferProgress{Bytes: p.bytes, Total: p.total})
+		}
+	}
+	return n, err
+}
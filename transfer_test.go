@@ -0,0 +1,289 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUploadTransferConfig(t *testing.T) {
+	s := newTestFilesAPI(t)
+	client := s.client()
+	data := bytes.Repeat([]byte("x"), 2*uploadChunkGranularity+100)
+
+	ctx := context.Background()
+	var progress []TransferProgress
+	config := &UploadWithTransferConfig{
+		UploadFileConfig: UploadFileConfig{MIMEType: "text/plain"},
+		UploadTransferConfig: UploadTransferConfig{
+			ChunkSize:  uploadChunkGranularity,
+			OnProgress: func(p TransferProgress) { progress = append(progress, p) },
+		},
+	}
+	if _, err := client.Files.UploadWithTransfer(ctx, bytes.NewReader(data), config); err != nil {
+		t.Fatalf("UploadWithTransfer failed: %v", err)
+	}
+	total := int64(len(data))
+	want := []TransferProgress{
+		{Bytes: uploadChunkGranularity, Total: total},
+		{Bytes: 2 * uploadChunkGranularity, Total: total},
+		{Bytes: total, Total: total},
+	}
+	if diff := cmp.Diff(want, progress); diff != "" {
+		t.Errorf("progress mismatch (-want +got):\n%s", diff)
+	}
+	if s.uploads != 3 || !bytes.Equal(s.data["files/1"], data) {
+		t.Errorf("server received %d bytes in %d chunks, want %d bytes in 3 chunks", len(s.data["files/1"]), s.uploads, len(data))
+	}
+
+	config.ChunkSize = 1000
+	if _, err := client.Files.UploadWithTransfer(ctx, bytes.NewReader(data), config); err == nil {
+		t.Errorf("UploadWithTransfer with a chunk size of 1000 bytes succeeded, want an error")
+	}
+}
+
+func TestUploadToFileSearchStoreTransferConfig(t *testing.T) {
+	s := &testStoreServer{documents: map[string]*Document{}}
+	client := newTestStoreClient(t, s)
+	data := bytes.Repeat([]byte("x"), uploadChunkGranularity+100)
+
+	var progress []TransferProgress
+	config := &UploadToFileSearchStoreWithTransferConfig{
+		UploadToFileSearchStoreConfig: UploadToFileSearchStoreConfig{MIMEType: "text/plain", DisplayName: "doc"},
+		UploadTransferConfig: UploadTransferConfig{
+			ChunkSize:  uploadChunkGranularity,
+			OnProgress: func(p TransferProgress) { progress = append(progress, p) },
+		},
+	}
+	operation, err := client.FileSearchStores.UploadToFileSearchStoreWithTransfer(context.Background(), bytes.NewReader(data), "fileSearchStores/store", config)
+	if err != nil {
+		t.Fatalf("UploadToFileSearchStoreWithTransfer failed: %v", err)
+	}
+	if want := "fileSearchStores/store/upload/operations/1"; operation.Name != want {
+		t.Errorf("operation name = %q, want %q", operation.Name, want)
+	}
+	total := int64(len(data))
+	want := []TransferProgress{{Bytes: uploadChunkGranularity, Total: total}, {Bytes: total, Total: total}}
+	if diff := cmp.Diff(want, progress); diff != "" {
+		t.Errorf("progress mismatch (-want +got):\n%s", diff)
+	}
+	if size := s.sessions["1"].header.Get("X-Goog-Upload-Header-Content-Length"); size != strconv.Itoa(len(data)) {
+		t.Errorf("X-Goog-Upload-Header-Content-Length = %q, want %d", size, len(data))
+	}
+}
+
+func TestUploadSize(t *testing.T) {
+	partlyRead := func(r io.Reader) io.Reader {
+		io.CopyN(io.Discard, r, 3)
+		return r
+	}
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	tests := []struct {
+		name        string
+		r           io.Reader
+		httpOptions *HTTPOptions
+		want        int64
+	}{
+		{name: "BytesReader", r: bytes.NewReader([]byte("0123456789")), want: 10},
+		{name: "PartlyReadBytesReader", r: partlyRead(bytes.NewReader([]byte("0123456789"))), want: 7},
+		{name: "PartlyReadStringsReader", r: partlyRead(strings.NewReader("0123456789")), want: 7},
+		{name: "PartlyReadFile", r: partlyRead(file), want: 7},
+		{name: "PartlyReadSeeker", r: partlyRead(struct{ io.ReadSeeker }{bytes.NewReader([]byte("0123456789"))}), want: 7},
+		{name: "Reader", r: struct{ io.Reader }{strings.NewReader("0123456789")}, want: -1},
+		{
+			name:        "Header",
+			r:           struct{ io.Reader }{strings.NewReader("0123456789")},
+			httpOptions: &HTTPOptions{Headers: http.Header{"X-Goog-Upload-Header-Content-Length": []string{"10"}}},
+			want:        10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := uploadSize(tt.r, tt.httpOptions); got != tt.want {
+				t.Errorf("uploadSize() = %d, want %d", got, tt.want)
+			}
+			// The position of the reader is kept.
+			if rest, _ := io.ReadAll(tt.r); tt.want >= 0 && int64(len(rest)) != tt.want {
+				t.Errorf("%d bytes left to read after uploadSize, want %d", len(rest), tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadPartlyReadReader(t *testing.T) {
+	s := newTestFilesAPI(t)
+	client := s.client()
+	ctx := context.Background()
+
+	r := bytes.NewReader([]byte("headerdata"))
+	io.CopyN(io.Discard, r, int64(len("header")))
+	file, err := client.Files.UploadWithTransfer(ctx, r, &UploadWithTransferConfig{UploadFileConfig: UploadFileConfig{MIMEType: "text/plain"}})
+	if err != nil {
+		t.Fatalf("UploadWithTransfer failed: %v", err)
+	}
+	if size := s.sessions["1"].header.Get("X-Goog-Upload-Header-Content-Length"); size != "4" {
+		t.Errorf("X-Goog-Upload-Header-Content-Length = %q, want 4", size)
+	}
+	if got := string(s.data[file.Name]); got != "data" {
+		t.Errorf("uploaded %q, want %q", got, "data")
+	}
+
+	r = bytes.NewReader([]byte("headerdata"))
+	io.CopyN(io.Discard, r, int64(len("header")))
+	if file, err = client.Files.Upload(ctx, r, &UploadFileConfig{MIMEType: "text/plain"}); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if got := string(s.data[file.Name]); got != "data" || *file.SizeBytes != 4 {
+		t.Errorf("uploaded %q, want %q", got, "data")
+	}
+}
+
+func TestUploadTimeouts(t *testing.T) {
+	// stalled answers the upload start and never answers chunks.
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Goog-Upload-Command") == "start" {
+			w.Header().Set("X-Goog-Upload-Url", "http://"+r.Host+"/upload/session")
+			w.Write([]byte(`{}`))
+			return
+		}
+		// The body is read so that the server notices when the client gives up.
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(stalled.Close)
+	client, err := NewClient(context.Background(), &ClientConfig{
+		Backend:     BackendGeminiAPI,
+		APIKey:      "test-api-key",
+		HTTPOptions: HTTPOptions{BaseURL: stalled.URL},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	timeout := 50 * time.Millisecond
+	tests := []struct {
+		name   string
+		config UploadWithTransferConfig
+		want   string
+	}{
+		{
+			name:   "ChunkTimeout",
+			config: UploadWithTransferConfig{UploadTransferConfig: UploadTransferConfig{ChunkTimeout: timeout}},
+			want:   "timed out",
+		},
+		{
+			name:   "HTTPOptionsTimeout",
+			config: UploadWithTransferConfig{UploadFileConfig: UploadFileConfig{HTTPOptions: &HTTPOptions{Timeout: &timeout}}},
+			want:   "timed out",
+		},
+		{
+			name:   "Timeout",
+			config: UploadWithTransferConfig{UploadTransferConfig: UploadTransferConfig{Timeout: timeout}},
+			want:   context.DeadlineExceeded.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.MIMEType = "text/plain"
+			start := time.Now()
+			_, err := client.Files.UploadWithTransfer(context.Background(), strings.NewReader("data"), &tt.config)
+			var uploadErr *UploadError
+			if !errors.As(err, &uploadErr) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("UploadWithTransfer error = %v, want an *UploadError containing %q", err, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("UploadWithTransfer took %v, want it to time out", elapsed)
+			}
+		})
+	}
+}
+
+func TestDownloadTransferConfig(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 100_000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1beta/files/slow:download" {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+	}))
+	t.Cleanup(ts.Close)
+	client, err := NewClient(context.Background(), &ClientConfig{
+		Backend:     BackendGeminiAPI,
+		APIKey:      "test-api-key",
+		HTTPOptions: HTTPOptions{BaseURL: ts.URL},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	ctx := context.Background()
+	var last TransferProgress
+	calls := 0
+	config := &DownloadToConfig{
+		OnProgress: func(p TransferProgress) {
+			if p.Bytes < last.Bytes {
+				t.Errorf("progress went back from %d to %d bytes", last.Bytes, p.Bytes)
+			}
+			last = p
+			calls++
+		},
+	}
+	var got bytes.Buffer
+	if _, err := client.Files.DownloadTo(ctx, NewDownloadURIFromFile(&File{DownloadURI: "files/abc"}), &got, config); err != nil {
+		t.Fatalf("DownloadTo failed: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Errorf("DownloadTo wrote %d bytes, want %d", got.Len(), len(data))
+	}
+	want := TransferProgress{Bytes: int64(len(data)), Total: int64(len(data))}
+	if calls == 0 || last != want {
+		t.Errorf("last progress = %+v after %d calls, want %+v", last, calls, want)
+	}
+
+	// The timeout of the request HTTP options bounds the whole download.
+	timeout := 50 * time.Millisecond
+	slow := NewDownloadURIFromFile(&File{DownloadURI: "files/slow"})
+	timeoutConfig := &DownloadFileConfig{HTTPOptions: &HTTPOptions{Timeout: &timeout}}
+	if _, err := client.Files.Download(ctx, slow, timeoutConfig); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Download error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := client.Files.DownloadTo(ctx, slow, io.Discard, &DownloadToConfig{DownloadFileConfig: *timeoutConfig}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DownloadTo error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
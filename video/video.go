@@ -0,0 +1,282 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package video provides helpers to stream camera or screen frames into Live
+// sessions.
+//
+// Frames are encoded as JPEG and sent as [genai.LiveRealtimeInput.Video]
+// blobs. Sending is safe to run concurrently with an audio stream on the same
+// session, such as the one of the audio package.
+package video
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"time"
+
+	"google.golang.org/genai"
+	"google.golang.org/genai/internal/media"
+)
+
+const (
+	// MIMEType is the MIME type of the frames sent by [Stream].
+	MIMEType = "image/jpeg"
+
+	defaultFPS       = 1
+	defaultQuality   = 75
+	defaultMaxWidth  = 1024
+	defaultMaxHeight = 1024
+)
+
+// RealtimeSender sends realtime input in a Live session. It is implemented by
+// [genai.Session] and [genai.ResumableSession].
+type RealtimeSender interface {
+	SendRealtimeInput(input genai.LiveRealtimeInput) error
+}
+
+// StreamConfig configures [Stream].
+type StreamConfig struct {
+	// Optional. FPS is the maximum number of frames sent per second. If zero,
+	// 1 frame per second is used, which is the rate at which Live models
+	// process video.
+	FPS float64
+	// Optional. Quality is the JPEG quality, from 1 to 100. If zero, 75 is
+	// used.
+	Quality int
+	// Optional. MaxWidth and MaxHeight bound the size of the sent frames.
+	// Larger frames are scaled down, keeping their aspect ratio. If zero, 1024
+	// is used. If negative, the dimension is not bounded.
+	MaxWidth  int
+	MaxHeight int
+}
+
+// Stats reports what happened to the frames received by [Stream].
+type Stats struct {
+	// Sent is the number of frames sent to the session.
+	Sent int
+	// Dropped is the number of frames replaced by a newer frame before they
+	// could be sent.
+	Dropped int
+}
+
+// Stream sends the frames received from frames to sender until frames is
+// closed or ctx is done.
+//
+// Frames are sent no faster than [StreamConfig.FPS]. Stream always keeps
+// receiving from frames, so producers don't block: when a frame arrives before
+// the previous one was sent, because of the frame rate or because sending is
+// slow, the previous frame is dropped. Stale frames are never queued.
+//
+// Stream returns nil when frames is closed, after sending the last pending
+// frame.
+func Stream(ctx context.Context, sender RealtimeSender, frames <-chan image.Image, config *StreamConfig) (Stats, error) {
+	var stats Stats
+	if config == nil {
+		config = &StreamConfig{}
+	}
+	c := *config
+	if c.FPS < 0 {
+		return stats, fmt.Errorf("video FPS must not be negative, got %v", c.FPS)
+	}
+	if c.FPS == 0 {
+		c.FPS = defaultFPS
+	}
+	if c.Quality < 0 || c.Quality > 100 {
+		return stats, fmt.Errorf("video quality must be between 1 and 100, got %d", c.Quality)
+	}
+	if c.Quality == 0 {
+		c.Quality = defaultQuality
+	}
+	if c.MaxWidth == 0 {
+		c.MaxWidth = defaultMaxWidth
+	}
+	if c.MaxHeight == 0 {
+		c.MaxHeight = defaultMaxHeight
+	}
+	interval := time.Duration(float64(time.Second) / c.FPS)
+
+	// pending is the latest frame that hasn't been sent yet.
+	var pending image.Image
+	// sending is true while a frame is being encoded and sent.
+	var sending bool
+	var nextSend time.Time
+	sendDone := make(chan error, 1)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	send := func() {
+		frame := pending
+		pending = nil
+		sending = true
+		stats.Sent++
+		nextSend = time.Now().Add(interval)
+		go func() {
+			data, err := Encode(frame, &c)
+			if err == nil {
+				err = sender.SendRealtimeInput(genai.LiveRealtimeInput{Video: &genai.Blob{MIMEType: MIMEType, Data: data}})
+			}
+			sendDone <- err
+		}()
+	}
+	// trySend sends the pending frame if possible, or arms the timer for when
+	// the frame rate allows it.
+	trySend := func() {
+		if pending == nil || sending {
+			return
+		}
+		if d := time.Until(nextSend); d > 0 {
+			timer.Reset(d)
+			return
+		}
+		send()
+	}
+	// wait waits for the frame being sent, if any.
+	wait := func() error {
+		if !sending {
+			return nil
+		}
+		sending = false
+		return <-sendDone
+	}
+
+	for {
+		select {
+		case frame, ok := <-frames:
+			if !ok {
+				if err := wait(); err != nil {
+					return stats, err
+				}
+				if pending == nil {
+					return stats, nil
+				}
+				if err := media.SleepUntil(ctx, nextSend); err != nil {
+					return stats, err
+				}
+				send()
+				return stats, wait()
+			}
+			if frame == nil {
+				continue
+			}
+			if pending != nil {
+				stats.Dropped++
+			}
+			pending = frame
+			trySend()
+		case err := <-sendDone:
+			sending = false
+			if err != nil {
+				return stats, err
+			}
+			trySend()
+		case <-timer.C:
+			trySend()
+		case <-ctx.Done():
+			wait()
+			return stats, ctx.Err()
+		}
+	}
+}
+
+// Encode scales img down to the bounds of config and encodes it as JPEG. A
+// nil config uses the defaults of [StreamConfig].
+func Encode(img image.Image, config *StreamConfig) ([]byte, error) {
+	quality, maxWidth, maxHeight := defaultQuality, defaultMaxWidth, defaultMaxHeight
+	if config != nil {
+		if config.Quality != 0 {
+			quality = config.Quality
+		}
+		if config.MaxWidth != 0 {
+			maxWidth = config.MaxWidth
+		}
+		if config.MaxHeight != 0 {
+			maxHeight = config.MaxHeight
+		}
+	}
+	width, height := fit(img.Bounds().Dx(), img.Bounds().Dy(), maxWidth, maxHeight)
+	if width != img.Bounds().Dx() || height != img.Bounds().Dy() {
+		img = scale(img, width, height)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode video frame: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RGBA returns an image backed by a raw RGBA buffer of width×height pixels,
+// such as a frame captured from a camera or a screen. The buffer is copied, so
+// it can be reused for the next frame.
+func RGBA(pix []byte, width, height int) (image.Image, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("video frame size must be positive, got %dx%d", width, height)
+	}
+	if len(pix) != 4*width*height {
+		return nil, fmt.Errorf("RGBA buffer of a %dx%d frame must be %d bytes, got %d", width, height, 4*width*height, len(pix))
+	}
+	return &image.RGBA{Pix: bytes.Clone(pix), Stride: 4 * width, Rect: image.Rect(0, 0, width, height)}, nil
+}
+
+// fit returns the size of a width×height frame scaled down to fit within
+// maxWidth×maxHeight, keeping its aspect ratio. Negative bounds are ignored.
+func fit(width, height, maxWidth, maxHeight int) (int, int) {
+	ratio := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		ratio = min(ratio, float64(maxWidth)/float64(width))
+	}
+	if maxHeight > 0 && height > maxHeight {
+		ratio = min(ratio, float64(maxHeight)/float64(height))
+	}
+	if ratio == 1 {
+		return width, height
+	}
+	return max(int(float64(width)*ratio), 1), max(int(float64(height)*ratio), 1)
+}
+
+// scale resizes img to width×height by averaging the source pixels covered by
+// each destination pixel.
+func scale(img image.Image, width, height int) *image.RGBA {
+	src, ok := img.(*image.RGBA)
+	if !ok || src.Rect.Min != (image.Point{}) {
+		src = image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(src, src.Rect, img, img.Bounds().Min, draw.Src)
+	}
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0, y1 := y*srcHeight/height, max((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := range width {
+			x0, x1 := x*srcWidth/width, max((x+1)*srcWidth/width, x*srcWidth/width+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for i := range sum {
+						sum[i] += int(row[4*sx+i])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			d := dst.Pix[y*dst.Stride+4*x:]
+			for i := range sum {
+				d[i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,210 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package video
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+type fakeSender struct {
+	mu     sync.Mutex
+	inputs []genai.LiveRealtimeInput
+	times  []time.Time
+	// delay is how long each send takes.
+	delay time.Duration
+	err   error
+}
+
+func (s *fakeSender) SendRealtimeInput(input genai.LiveRealtimeInput) error {
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inputs = append(s.inputs, input)
+	s.times = append(s.times, time.Now())
+	return s.err
+}
+
+// solid returns a width×height frame filled with gray level v.
+func solid(width, height int, v uint8) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = v
+	}
+	return img
+}
+
+// decode decodes a sent frame and returns its size and the gray level of its
+// center pixel.
+func decode(t *testing.T, input genai.LiveRealtimeInput) (image.Point, uint8) {
+	t.Helper()
+	if input.Video == nil || input.Video.MIMEType != MIMEType {
+		t.Fatalf("input = %+v, want a JPEG video blob", input)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(input.Video.Data))
+	if err != nil {
+		t.Fatalf("jpeg.Decode failed: %v", err)
+	}
+	b := img.Bounds()
+	gray := color.GrayModel.Convert(img.At(b.Dx()/2, b.Dy()/2)).(color.Gray)
+	return b.Size(), gray.Y
+}
+
+func TestStream(t *testing.T) {
+	ctx := context.Background()
+	sender := &fakeSender{}
+	frames := make(chan image.Image)
+	done := make(chan struct{})
+	var stats Stats
+	var err error
+	go func() {
+		defer close(done)
+		stats, err = Stream(ctx, sender, frames, &StreamConfig{FPS: 10, MaxWidth: 64, MaxHeight: 64})
+	}()
+
+	// Frames produced faster than the frame rate don't block the producer; the
+	// stale ones are dropped.
+	start := time.Now()
+	for i := range 10 {
+		frames <- solid(128, 96, uint8(10*i+10))
+	}
+	close(frames)
+	<-done
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if stats.Sent+stats.Dropped != 10 || stats.Dropped == 0 {
+		t.Errorf("stats = %+v, want 10 frames with some dropped", stats)
+	}
+	if len(sender.inputs) != stats.Sent {
+		t.Fatalf("sent %d frames, stats report %d", len(sender.inputs), stats.Sent)
+	}
+	for i := 1; i < len(sender.times); i++ {
+		if gap := sender.times[i].Sub(sender.times[i-1]); gap < 80*time.Millisecond {
+			t.Errorf("gap between frames %d and %d = %v, want about 100ms", i-1, i, gap)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stream took %v, want the stale frames to be dropped", elapsed)
+	}
+
+	// The last frame is always sent, scaled down to the bounds.
+	size, gray := decode(t, sender.inputs[len(sender.inputs)-1])
+	if size != (image.Point{X: 64, Y: 48}) {
+		t.Errorf("frame size = %v, want 64x48", size)
+	}
+	if gray < 98 || gray > 102 {
+		t.Errorf("last frame gray level = %d, want about 100", gray)
+	}
+}
+
+func TestStreamSlowSender(t *testing.T) {
+	sender := &fakeSender{delay: 50 * time.Millisecond}
+	frames := make(chan image.Image)
+	done := make(chan struct{})
+	var stats Stats
+	go func() {
+		defer close(done)
+		stats, _ = Stream(context.Background(), sender, frames, &StreamConfig{FPS: 1000})
+	}()
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for range 40 {
+		<-ticker.C
+		frames <- solid(8, 8, 0)
+	}
+	close(frames)
+	<-done
+	if stats.Sent > 10 || stats.Dropped < 30 {
+		t.Errorf("stats = %+v, want at most one frame in flight and the others dropped", stats)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	wantErr := errors.New("session closed")
+	frames := make(chan image.Image, 1)
+	frames <- solid(8, 8, 0)
+	_, err := Stream(context.Background(), &fakeSender{err: wantErr}, frames, nil)
+	if !errors.Is(err, wantErr) {
+		t.Errorf("Stream error = %v, want %v", err, wantErr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Stream(ctx, &fakeSender{}, make(chan image.Image), nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream error = %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := Stream(context.Background(), &fakeSender{}, nil, &StreamConfig{Quality: 101}); err == nil {
+		t.Errorf("Stream with quality 101 succeeded, want an error")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		desc     string
+		img      image.Image
+		config   *StreamConfig
+		wantSize image.Point
+	}{
+		{desc: "default bounds", img: solid(2048, 1024, 50), wantSize: image.Point{X: 1024, Y: 512}},
+		{desc: "small frame", img: solid(320, 240, 50), wantSize: image.Point{X: 320, Y: 240}},
+		{desc: "height bound", img: solid(300, 600, 50), config: &StreamConfig{MaxHeight: 300}, wantSize: image.Point{X: 150, Y: 300}},
+		{desc: "unbounded", img: solid(2048, 16, 50), config: &StreamConfig{MaxWidth: -1}, wantSize: image.Point{X: 2048, Y: 16}},
+		{desc: "subimage", img: solid(200, 200, 50).(*image.RGBA).SubImage(image.Rect(50, 50, 150, 100)), config: &StreamConfig{MaxWidth: 50}, wantSize: image.Point{X: 50, Y: 25}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			data, err := Encode(tt.img, tt.config)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			size, gray := decode(t, genai.LiveRealtimeInput{Video: &genai.Blob{MIMEType: MIMEType, Data: data}})
+			if size != tt.wantSize {
+				t.Errorf("size = %v, want %v", size, tt.wantSize)
+			}
+			if gray < 48 || gray > 52 {
+				t.Errorf("gray level = %d, want about 50", gray)
+			}
+		})
+	}
+}
+
+func TestRGBA(t *testing.T) {
+	pix := make([]byte, 4*4*2)
+	for i := range pix {
+		pix[i] = 200
+	}
+	img, err := RGBA(pix, 4, 2)
+	if err != nil {
+		t.Fatalf("RGBA failed: %v", err)
+	}
+	pix[0] = 0
+	if r, _, _, _ := img.At(0, 0).RGBA(); r>>8 != 200 {
+		t.Errorf("pixel red = %d after reusing the buffer, want 200", r>>8)
+	}
+	if _, err := RGBA(pix, 4, 3); err == nil {
+		t.Errorf("RGBA with a short buffer succeeded, want an error")
+	}
+}